@@ -5,35 +5,52 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"net/http/cookiejar"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
 	"github.com/ahmethakanbesel/finance-api/internal/config"
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
 	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
 	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	archiverepo "github.com/ahmethakanbesel/finance-api/internal/repository/archive"
+	fundrepo "github.com/ahmethakanbesel/finance-api/internal/repository/fund"
 	jobrepo "github.com/ahmethakanbesel/finance-api/internal/repository/job"
 	pricerepo "github.com/ahmethakanbesel/finance-api/internal/repository/price"
+	qualityrepo "github.com/ahmethakanbesel/finance-api/internal/repository/quality"
 	raterepo "github.com/ahmethakanbesel/finance-api/internal/repository/rate"
+	watchlistrepo "github.com/ahmethakanbesel/finance-api/internal/repository/watchlist"
+	"github.com/ahmethakanbesel/finance-api/internal/schedule"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/cassette"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/isyatirim"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/ratelimit"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/tefas"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/yahoo"
 	"github.com/ahmethakanbesel/finance-api/internal/server"
+	"github.com/ahmethakanbesel/finance-api/internal/watchlist"
 )
 
 func main() {
 	cfg := config.Load()
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(cfg, os.Args[2:]))
+	}
+
 	// Root context: cancelled on SIGINT/SIGTERM so in-flight scraper workers
 	// stop promptly during graceful shutdown.
 	rootCtx, rootCancel := context.WithCancel(context.Background())
 	defer rootCancel()
 
-	// Open database
+	// Open database (applies pending migrations; refuses a newer schema)
 	db, err := sqlite.Open(cfg.DBPath)
 	if err != nil {
 		slog.Error("failed to open database", "error", err)
@@ -45,21 +62,127 @@ func main() {
 	priceRepo := pricerepo.NewRepository(db.DB)
 	jobRepo := jobrepo.NewRepository(db.DB)
 	rateRepo := raterepo.NewRepository(db.DB)
+	fundRepo := fundrepo.NewRepository(db.DB)
+	watchlistRepo := watchlistrepo.NewRepository(db.DB)
+	archiveRepo := archiverepo.NewRepository(db.DB)
+	qualityRepo := qualityrepo.NewRepository(db.DB)
+
+	// Response archive: fetchers record raw responses only when enabled, but
+	// reparse jobs can always use what was recorded before.
+	var recorder archive.Recorder
+	if cfg.ArchiveResponses {
+		recorder = archiveRepo
+	}
+
+	// HTTP clients: every client of a source waits for the request budget of
+	// the hosts it talks to, shared across jobs and chunk workers. In record
+	// mode the responses are also saved as fixtures; in replay mode they are
+	// served from the fixtures and nothing goes upstream.
+	mode, err := cassette.ParseMode(cfg.ScraperMode)
+	if err != nil {
+		slog.Error("invalid scraper mode", "error", err)
+		os.Exit(1)
+	}
+	if mode != cassette.Live {
+		slog.Info("scraper fixtures", "mode", mode, "dir", cfg.FixtureDir)
+	}
+	limiter := ratelimit.New()
+	transport := func(source string) http.RoundTripper {
+		if mode == cassette.Replay {
+			return cassette.New(mode, cfg.FixtureDir, nil)
+		}
+		rt := limiter.Transport(nil, ratelimit.PerMinute(cfg.Limits[source].RatePerMinute))
+		if mode == cassette.Record {
+			rt = cassette.New(mode, cfg.FixtureDir, rt)
+		}
+		return rt
+	}
+	yahooJar, _ := cookiejar.New(nil)
+
+	// Retries and circuit breakers: all clients share one breaker per host, so
+	// an upstream that is down is left alone by every source using it.
+	breakers := httpx.NewBreakers(5, 30*time.Second)
+	httpOpts := []httpx.Option{
+		httpx.WithTimeout(time.Duration(cfg.HTTPTimeout) * time.Second),
+		httpx.WithAttempts(cfg.HTTPAttempts),
+		httpx.WithBreakers(breakers),
+	}
 
 	// Scraper registry
+	tefasScraper := tefas.New(
+		tefas.WithWorkers(cfg.Workers),
+		tefas.WithClient(&http.Client{Transport: transport("tefas")}),
+		tefas.WithHTTPOptions(httpOpts...),
+		tefas.WithArchive(recorder),
+	)
 	registry := scraper.NewRegistry()
-	registry.Register(tefas.New(tefas.WithWorkers(cfg.Workers)))
-	registry.Register(yahoo.New(yahoo.WithWorkers(cfg.Workers)))
-	registry.Register(isyatirim.New(isyatirim.WithWorkers(cfg.Workers)))
+	registry.Register(tefasScraper)
+	registry.Register(yahoo.New(
+		yahoo.WithWorkers(cfg.Workers),
+		yahoo.WithClient(&http.Client{Jar: yahooJar, Transport: transport("yahoo")}),
+		yahoo.WithHTTPOptions(httpOpts...),
+		yahoo.WithArchive(recorder),
+	))
+	registry.Register(isyatirim.New(
+		isyatirim.WithWorkers(cfg.Workers),
+		isyatirim.WithClient(&http.Client{Transport: transport("isyatirim")}),
+		isyatirim.WithHTTPOptions(httpOpts...),
+		isyatirim.WithArchive(recorder),
+	))
 
 	// Services
-	rateSvc := rate.NewService(rateRepo)
-	jobSvc := job.NewService(jobRepo)
-	priceSvc := price.NewService(priceRepo, jobRepo, registry, rateSvc)
+	tcmb := rate.NewTCMB(
+		rate.WithTCMBClient(&http.Client{Transport: transport("tcmb")}),
+		rate.WithTCMBHTTPOptions(httpOpts...),
+		rate.WithTCMBArchive(recorder),
+	)
+	rateSvc := rate.NewService(rateRepo,
+		rate.WithClient(&http.Client{Transport: transport("yahoo")}),
+		rate.WithHTTPOptions(httpOpts...),
+		rate.WithArchive(recorder),
+		rate.WithProvider(rate.SourceTCMB, tcmb),
+	)
+	// Quality checks: scraped prices that fail one are quarantined instead of
+	// stored.
+	rules := quality.Rules{
+		RejectInvalid: cfg.Quality.RejectInvalid,
+		RejectFuture:  cfg.Quality.RejectFuture,
+		MaxMove:       make(map[quality.Class]float64),
+		ConfirmRun:    cfg.Quality.ConfirmRun,
+		StaleRun:      cfg.Quality.StaleRun,
+	}
+	for _, class := range quality.Classes {
+		rules.MaxMove[class] = float64(cfg.Quality.MaxMove[string(class)]) / 100
+	}
+	checker := quality.NewChecker(rules)
 
-	// Worker pool: picks up pending jobs in the background
-	pool := job.NewWorkerPool(jobRepo, priceSvc, cfg.Workers)
+	jobSvc := job.NewService(jobRepo)
+	priceSvc := price.NewService(priceRepo, jobRepo, registry, rateSvc,
+		price.WithFundRepository(fundRepo),
+		price.WithArchive(archiveRepo),
+		price.WithQuality(checker, qualityRepo),
+	)
+	fundSvc := fund.NewService(fundRepo, jobRepo, priceSvc, tefasScraper)
+	watchlistSvc := watchlist.NewService(watchlistRepo)
+	qualitySvc := quality.NewService(qualityRepo, checker)
+
+	// Worker pool: picks up pending jobs in the background and hands each to
+	// the service owning its dataset.
+	router := job.NewRouter(jobRepo)
+	router.Handle(job.KindPrices, priceSvc)
+	router.Handle(job.KindRefresh, priceSvc)
+	router.Handle(job.KindAllocation, fundSvc)
+	router.Handle(job.KindReparse, priceSvc)
+	var poolOpts []job.PoolOption
+	for source, limit := range cfg.Limits {
+		poolOpts = append(poolOpts, job.WithSourceLimit(source, limit.Jobs))
+	}
+	pool := job.NewWorkerPool(jobRepo, router, cfg.Workers, poolOpts...)
 	priceSvc.SetNotify(pool.Notify)
+	fundSvc.SetNotify(pool.Notify)
+	jobSvc.SetNotify(pool.Notify)
+	jobSvc.SetCancel(pool.Cancel)
+	jobSvc.SetRunning(pool.Running)
 	poolDone := make(chan struct{})
 	go func() {
 		pool.Run(rootCtx)
@@ -72,9 +195,31 @@ func main() {
 	}
 	pool.Notify()
 
+	// Scheduler: refreshes the watchlist after each source publishes, so that
+	// tracked symbols are up to date before anyone asks. It stops with the
+	// pool on rootCtx.
+	schedules := make(map[string]*schedule.Schedule)
+	for source, expr := range cfg.Schedules {
+		if expr == "off" {
+			continue
+		}
+		sched, err := schedule.Parse(expr)
+		if err != nil {
+			slog.Error("invalid refresh schedule", "source", source, "error", err)
+			os.Exit(1)
+		}
+		schedules[source] = sched
+	}
+	scheduler := watchlist.NewScheduler(watchlistRepo, priceSvc, schedules)
+	schedulerDone := make(chan struct{})
+	go func() {
+		scheduler.Run(rootCtx)
+		close(schedulerDone)
+	}()
+
 	// HTTP server — rootCtx is used as BaseContext so every request context
 	// inherits from it and is cancelled on shutdown.
-	srv := server.New(rootCtx, cfg.Port, priceSvc, jobSvc)
+	srv := server.New(rootCtx, cfg.Port, priceSvc, jobSvc, fundSvc, rateSvc, watchlistSvc, qualitySvc)
 
 	// Graceful shutdown
 	done := make(chan os.Signal, 1)
@@ -94,7 +239,9 @@ func main() {
 	// workers) begin winding down immediately.
 	rootCancel()
 
-	// Wait for worker pool to drain before shutting down HTTP.
+	// Wait for the scheduler and the worker pool to drain before shutting
+	// down HTTP.
+	<-schedulerDone
 	<-poolDone
 
 	// Then drain connections with a deadline.
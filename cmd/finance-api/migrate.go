@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/config"
+	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
+)
+
+const migrateUsage = `usage: finance-api migrate [status|up]
+
+  status  list migrations and whether they are applied (default)
+  up      apply pending migrations, then print status
+`
+
+// runMigrate implements the "migrate" subcommand and returns the exit code.
+func runMigrate(cfg config.Config, args []string) int {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	var opts []sqlite.Option
+	switch cmd {
+	case "status":
+		opts = append(opts, sqlite.WithoutMigrations())
+	case "up":
+	default:
+		_, _ = fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	db, err := sqlite.Open(cfg.DBPath, opts...)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	status, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "migrate: %v\n", err)
+		return 1
+	}
+
+	printMigrationStatus(os.Stdout, status)
+
+	for _, st := range status {
+		if !st.Known {
+			_, _ = fmt.Fprintf(os.Stderr, "migrate: %v\n", sqlite.ErrSchemaTooNew)
+			return 1
+		}
+	}
+	return 0
+}
+
+func printMigrationStatus(w io.Writer, status []sqlite.MigrationStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range status {
+		state := "pending"
+		switch {
+		case !st.Known:
+			state = "unknown"
+		case st.Applied:
+			state = "applied"
+		}
+		appliedAt := ""
+		if !st.AppliedAt.IsZero() {
+			appliedAt = st.AppliedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,68 @@
+// Package archive keeps the raw upstream responses that data was parsed
+// from, so that prices can be rebuilt after a parser fix without fetching
+// again, and so that a disputed number can be traced to what the upstream
+// actually returned.
+package archive
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
+)
+
+// Datasets an archived response belongs to.
+const (
+	DatasetPrices     = "prices"
+	DatasetAllocation = "allocation"
+	DatasetRates      = "rates"
+)
+
+// Entry is one upstream response.
+type Entry struct {
+	ID          int64
+	Source      string
+	Symbol      string // symbol or pair the request was for; empty if none
+	Dataset     string
+	Method      string
+	URL         string // with secrets removed
+	RequestBody []byte
+	StatusCode  int
+	Body        []byte
+	BodyHash    string // hex SHA-256 of Body, set when saved
+	FetchedAt   time.Time
+}
+
+// Record saves e with rec, which may be nil when archiving is off. A failure
+// is logged rather than returned: losing an archive entry must not fail the
+// fetch it came from.
+func Record(ctx context.Context, rec Recorder, e *Entry) {
+	if rec == nil {
+		return
+	}
+	if e.FetchedAt.IsZero() {
+		e.FetchedAt = time.Now().UTC()
+	}
+	e.URL = redact(e.URL)
+	if err := rec.Save(ctx, e); err != nil {
+		slog.Warn("archive: save response", "source", e.Source, "symbol", e.Symbol, "url", e.URL, "error", err)
+	}
+}
+
+// redact removes the query parameters in httpx.SecretParams from raw.
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	q := u.Query()
+	for name := range q {
+		if httpx.IsSecretParam(name) {
+			q.Del(name)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
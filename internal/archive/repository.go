@@ -0,0 +1,15 @@
+package archive
+
+import "context"
+
+// Recorder stores responses as they are fetched.
+type Recorder interface {
+	Save(ctx context.Context, e *Entry) error
+}
+
+type Repository interface {
+	Recorder
+	// List returns the successful responses of a dataset for a symbol,
+	// oldest first, with their bodies.
+	List(ctx context.Context, source, symbol, dataset string) ([]Entry, error)
+}
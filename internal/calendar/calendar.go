@@ -0,0 +1,244 @@
+// Package calendar knows which days markets trade on, so that coverage
+// checks can tell a missing price from a holiday.
+package calendar
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
+
+// Holiday is a day a market is closed or closes early.
+type Holiday struct {
+	Date    time.Time `json:"date"`
+	Name    string    `json:"name"`
+	HalfDay bool      `json:"halfDay,omitempty"` // early close rather than a full closure
+}
+
+// Calendar is the set of days a market trades on. Its holidays come from
+// rules (fixed dates, "third Monday of January") and, for those no rule can
+// compute, an embedded list.
+type Calendar struct {
+	name      string
+	weekends  bool                     // trades on Saturdays and Sundays
+	closeHalf bool                     // half days are closed rather than short sessions
+	rules     func(year int) []Holiday // rule-based holidays of a year
+	list      string                   // key of the embedded holiday list, "" for none
+
+	mu    sync.Mutex
+	years map[int]map[time.Time]Holiday
+}
+
+var (
+	// Weekdays trades Monday to Friday without holidays, e.g. currency
+	// markets.
+	Weekdays = &Calendar{name: "weekdays"}
+
+	// Continuous trades every day, e.g. cryptocurrencies.
+	Continuous = &Calendar{name: "continuous", weekends: true}
+
+	// NYSE is the New York Stock Exchange.
+	NYSE = &Calendar{name: "nyse", rules: nyseHolidays, list: "NYSE"}
+
+	// BIST is Borsa Istanbul. It trades a short session on the eve of the
+	// bayram holidays.
+	BIST = &Calendar{name: "bist", rules: turkishHolidays, list: "TR"}
+
+	// TEFAS is the Turkish fund trading platform. Funds are not priced on
+	// half days, so those count as closures.
+	TEFAS = &Calendar{name: "tefas", closeHalf: true, rules: turkishHolidays, list: "TR"}
+
+	// TCMB is the business calendar of the Central Bank of the Republic of
+	// Türkiye, which publishes no exchange rate bulletin on public holidays.
+	TCMB = &Calendar{name: "tcmb", rules: turkishHolidays, list: "TR"}
+)
+
+var calendars = []*Calendar{BIST, Continuous, NYSE, TCMB, TEFAS, Weekdays}
+
+// Get returns the calendar of the given name.
+func Get(name string) (*Calendar, bool) {
+	for _, c := range calendars {
+		if c.name == strings.ToLower(name) {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// Names lists the available calendars.
+func Names() []string {
+	names := make([]string, len(calendars))
+	for i, c := range calendars {
+		names[i] = c.name
+	}
+	return names
+}
+
+// For returns the calendar a data source's symbol trades on. Yahoo Finance
+// symbols are told apart by their suffix: ".IS" is Borsa Istanbul, "=X"
+// currencies, "-USD" and similar cryptocurrencies, other exchange suffixes
+// fall back to weekdays, and plain tickers are NYSE.
+func For(source, symbol string) *Calendar {
+	switch source {
+	case "tefas":
+		return TEFAS
+	case "isyatirim":
+		return BIST
+	case "yahoo":
+		return forYahoo(strings.ToUpper(symbol))
+	default:
+		return Weekdays
+	}
+}
+
+func forYahoo(symbol string) *Calendar {
+	switch {
+	case strings.HasSuffix(symbol, ".IS"), strings.HasPrefix(symbol, "^XU"):
+		return BIST
+	case strings.HasSuffix(symbol, "=X"), strings.HasSuffix(symbol, "=F"):
+		return Weekdays
+	}
+	for _, quote := range []string{"-USD", "-USDT", "-EUR", "-TRY", "-BTC"} {
+		if strings.HasSuffix(symbol, quote) {
+			return Continuous
+		}
+	}
+	if strings.Contains(symbol, ".") {
+		return Weekdays
+	}
+	return NYSE
+}
+
+// GetDays lists the trading days and holidays of a calendar over a range.
+// An empty EndDate means today.
+func GetDays(req GetDaysRequest) (*GetDaysResponse, *apperror.AppError) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	c, _ := Get(req.Calendar)
+
+	end := req.EndDate
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	resp := &GetDaysResponse{
+		Calendar: c.Name(),
+		Days:     c.TradingDays(req.StartDate, end),
+		Holidays: c.Holidays(req.StartDate, end),
+	}
+	if resp.Days == nil {
+		resp.Days = []time.Time{}
+	}
+	if resp.Holidays == nil {
+		resp.Holidays = []Holiday{}
+	}
+	return resp, nil
+}
+
+// Name is the calendar's identifier, as accepted by Get.
+func (c *Calendar) Name() string { return c.name }
+
+// IsTradingDay reports whether the market trades on the date of d.
+func (c *Calendar) IsTradingDay(d time.Time) bool {
+	d = day(d)
+	if !c.weekends {
+		if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			return false
+		}
+	}
+	h, ok := c.holidays(d.Year())[d]
+	if !ok {
+		return true
+	}
+	return h.HalfDay && !c.closeHalf
+}
+
+// TradingDays lists the trading days in [from, to].
+func (c *Calendar) TradingDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	for d := day(from); !d.After(day(to)); d = d.AddDate(0, 0, 1) {
+		if c.IsTradingDay(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// Count returns the number of trading days in [from, to].
+func (c *Calendar) Count(from, to time.Time) int {
+	n := 0
+	for d := day(from); !d.After(day(to)); d = d.AddDate(0, 0, 1) {
+		if c.IsTradingDay(d) {
+			n++
+		}
+	}
+	return n
+}
+
+// Holidays lists the closures and half days in [from, to] that fall on days
+// the market would otherwise trade.
+func (c *Calendar) Holidays(from, to time.Time) []Holiday {
+	from, to = day(from), day(to)
+	var out []Holiday
+	for y := from.Year(); y <= to.Year(); y++ {
+		for _, h := range c.holidays(y) {
+			if h.Date.Before(from) || h.Date.After(to) {
+				continue
+			}
+			if wd := h.Date.Weekday(); !c.weekends && (wd == time.Saturday || wd == time.Sunday) {
+				continue
+			}
+			out = append(out, h)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out
+}
+
+// holidays returns the holidays of a year by date, computing them once.
+func (c *Calendar) holidays(year int) map[time.Time]Holiday {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if hs, ok := c.years[year]; ok {
+		return hs
+	}
+
+	hs := make(map[time.Time]Holiday)
+	add := func(h Holiday) {
+		// A full closure wins over a half day on the same date.
+		if prev, ok := hs[h.Date]; !ok || prev.HalfDay {
+			hs[h.Date] = h
+		}
+	}
+	if c.rules != nil {
+		for _, h := range c.rules(year) {
+			add(h)
+		}
+	}
+	n := 0
+	for _, h := range listed[c.list] {
+		if h.Date.Year() == year {
+			add(h)
+			n++
+		}
+	}
+	if n == 0 && yearly[c.list] {
+		slog.Warn("no holidays listed for year, its lunar holidays count as trading days",
+			"calendar", c.name, "year", year)
+	}
+
+	if c.years == nil {
+		c.years = make(map[int]map[time.Time]Holiday)
+	}
+	c.years[year] = hs
+	return hs
+}
+
+// day truncates t to midnight UTC of its calendar date.
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
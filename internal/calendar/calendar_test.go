@@ -0,0 +1,110 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTradingDay(t *testing.T) {
+	tests := []struct {
+		name    string
+		cal     *Calendar
+		date    time.Time
+		trading bool
+	}{
+		{"nyse good friday", NYSE, date(2024, time.March, 29), false},
+		{"nyse easter monday", NYSE, date(2024, time.April, 1), true},
+		{"nyse juneteenth", NYSE, date(2024, time.June, 19), false},
+		{"nyse thanksgiving", NYSE, date(2024, time.November, 28), false},
+		{"nyse early close", NYSE, date(2024, time.November, 29), true},
+		{"nyse independence day observed", NYSE, date(2026, time.July, 3), false},
+		{"nyse new year on saturday not made up", NYSE, date(2021, time.December, 31), true},
+		{"nyse new year on sunday observed", NYSE, date(2023, time.January, 2), false},
+		{"nyse listed closure", NYSE, date(2025, time.January, 9), false},
+		{"nyse weekend", NYSE, date(2024, time.March, 30), false},
+		{"bist republic day", BIST, date(2024, time.October, 29), false},
+		{"bist bayram", BIST, date(2024, time.April, 10), false},
+		{"bist arife half day", BIST, date(2024, time.April, 9), true},
+		{"tefas arife half day", TEFAS, date(2024, time.April, 9), false},
+		{"tcmb bayram", TCMB, date(2024, time.June, 17), false},
+		{"bist bayram 2010", BIST, date(2010, time.September, 9), false},
+		{"tefas bayram 2028", TEFAS, date(2028, time.May, 5), false},
+		{"weekdays new year", Weekdays, date(2024, time.January, 1), true},
+		{"weekdays weekend", Weekdays, date(2024, time.January, 6), false},
+		{"continuous weekend", Continuous, date(2024, time.January, 6), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cal.IsTradingDay(tt.date); got != tt.trading {
+				t.Errorf("IsTradingDay(%s) = %v, want %v", tt.date.Format("2006-01-02"), got, tt.trading)
+			}
+		})
+	}
+}
+
+func TestListed_TurkishYears(t *testing.T) {
+	for year := 2005; year <= 2029; year++ {
+		var ramazan, kurban bool
+		for _, h := range listed["TR"] {
+			if h.Date.Year() != year || h.HalfDay {
+				continue
+			}
+			ramazan = ramazan || h.Name == "Ramazan Bayramı"
+			kurban = kurban || h.Name == "Kurban Bayramı"
+		}
+		if !ramazan || !kurban {
+			t.Errorf("%d: Ramazan Bayramı listed %v, Kurban Bayramı listed %v", year, ramazan, kurban)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	// April 2024 has 22 weekdays; the Ramazan Bayramı closes 10–12 April and
+	// its eve on the 9th is a half day, and 23 April is a public holiday.
+	from, to := date(2024, time.April, 1), date(2024, time.April, 30)
+	if got := Weekdays.Count(from, to); got != 22 {
+		t.Errorf("Weekdays.Count = %d, want 22", got)
+	}
+	if got := BIST.Count(from, to); got != 18 {
+		t.Errorf("BIST.Count = %d, want 18", got)
+	}
+	if got := TEFAS.Count(from, to); got != 17 {
+		t.Errorf("TEFAS.Count = %d, want 17", got)
+	}
+}
+
+func TestHolidays_SkipsWeekends(t *testing.T) {
+	// Kurban Bayramı 2024 runs from Saturday 15 June to Wednesday 19 June.
+	hs := BIST.Holidays(date(2024, time.June, 1), date(2024, time.June, 30))
+	want := []time.Time{date(2024, time.June, 17), date(2024, time.June, 18), date(2024, time.June, 19)}
+	if len(hs) != len(want) {
+		t.Fatalf("got %d holidays %v, want %d", len(hs), hs, len(want))
+	}
+	for i, w := range want {
+		if !hs[i].Date.Equal(w) {
+			t.Errorf("holiday %d = %s, want %s", i, hs[i].Date.Format("2006-01-02"), w.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		source, symbol string
+		want           *Calendar
+	}{
+		{"tefas", "AEA", TEFAS},
+		{"isyatirim", "THYAO", BIST},
+		{"yahoo", "THYAO.IS", BIST},
+		{"yahoo", "^XU100", BIST},
+		{"yahoo", "AAPL", NYSE},
+		{"yahoo", "EURUSD=X", Weekdays},
+		{"yahoo", "BTC-USD", Continuous},
+		{"yahoo", "VOD.L", Weekdays},
+		{"unknown", "X", Weekdays},
+	}
+	for _, tt := range tests {
+		if got := For(tt.source, tt.symbol); got != tt.want {
+			t.Errorf("For(%q, %q) = %s, want %s", tt.source, tt.symbol, got.Name(), tt.want.Name())
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package calendar
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//go:embed holidays.csv
+var holidaysCSV string
+
+// listed holds the embedded holidays by list key ("TR", "NYSE").
+var listed = mustParseHolidays(holidaysCSV)
+
+// yearly marks the lists with holidays in every year, so that a year without
+// any is missing from the list rather than free of closures.
+var yearly = map[string]bool{"TR": true}
+
+// mustParseHolidays parses lines of "list,date,kind,name". Blank lines and
+// lines starting with # are ignored.
+func mustParseHolidays(data string) map[string][]Holiday {
+	out := make(map[string][]Holiday)
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, ",", 4)
+		if len(fields) != 4 {
+			panic(fmt.Sprintf("calendar: holidays.csv line %d: want 4 fields, got %d", n, len(fields)))
+		}
+		d, err := time.Parse("2006-01-02", fields[1])
+		if err != nil {
+			panic(fmt.Sprintf("calendar: holidays.csv line %d: %v", n, err))
+		}
+		var half bool
+		switch fields[2] {
+		case "closed":
+		case "half":
+			half = true
+		default:
+			panic(fmt.Sprintf("calendar: holidays.csv line %d: unknown kind %q", n, fields[2]))
+		}
+		out[fields[0]] = append(out[fields[0]], Holiday{Date: d, Name: fields[3], HalfDay: half})
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package calendar
+
+import "time"
+
+// turkishHolidays returns the fixed-date public holidays of Türkiye. The
+// bayram holidays move with the lunar calendar and are listed in
+// holidays.csv.
+func turkishHolidays(year int) []Holiday {
+	hs := []Holiday{
+		{Date: date(year, time.January, 1), Name: "Yılbaşı"},
+		{Date: date(year, time.April, 23), Name: "Ulusal Egemenlik ve Çocuk Bayramı"},
+		{Date: date(year, time.May, 19), Name: "Atatürk'ü Anma, Gençlik ve Spor Bayramı"},
+		{Date: date(year, time.August, 30), Name: "Zafer Bayramı"},
+		{Date: date(year, time.October, 28), Name: "Cumhuriyet Bayramı Arifesi", HalfDay: true},
+		{Date: date(year, time.October, 29), Name: "Cumhuriyet Bayramı"},
+	}
+	if year >= 2009 {
+		hs = append(hs, Holiday{Date: date(year, time.May, 1), Name: "Emek ve Dayanışma Günü"})
+	}
+	if year >= 2017 {
+		hs = append(hs, Holiday{Date: date(year, time.July, 15), Name: "Demokrasi ve Millî Birlik Günü"})
+	}
+	return hs
+}
+
+// nyseHolidays returns the regular holidays and early closes of the New York
+// Stock Exchange. Holidays on a Saturday are observed on the Friday before
+// and those on a Sunday on the Monday after, except New Year's Day, which is
+// not made up when it falls on a Saturday.
+func nyseHolidays(year int) []Holiday {
+	easter := easterSunday(year)
+	hs := []Holiday{
+		{Date: nthWeekday(year, time.January, time.Monday, 3), Name: "Martin Luther King Jr. Day"},
+		{Date: nthWeekday(year, time.February, time.Monday, 3), Name: "Washington's Birthday"},
+		{Date: easter.AddDate(0, 0, -2), Name: "Good Friday"},
+		{Date: lastWeekday(year, time.May, time.Monday), Name: "Memorial Day"},
+		{Date: observed(date(year, time.July, 4)), Name: "Independence Day"},
+		{Date: nthWeekday(year, time.September, time.Monday, 1), Name: "Labor Day"},
+		{Date: nthWeekday(year, time.November, time.Thursday, 4), Name: "Thanksgiving Day"},
+		{Date: observed(date(year, time.December, 25)), Name: "Christmas Day"},
+	}
+	if ny := date(year, time.January, 1); ny.Weekday() != time.Saturday {
+		hs = append(hs, Holiday{Date: observed(ny), Name: "New Year's Day"})
+	}
+	if year >= 2022 {
+		hs = append(hs, Holiday{Date: observed(date(year, time.June, 19)), Name: "Juneteenth National Independence Day"})
+	}
+
+	// Early closes at 1 p.m.
+	thanksgiving := nthWeekday(year, time.November, time.Thursday, 4)
+	hs = append(hs, Holiday{Date: thanksgiving.AddDate(0, 0, 1), Name: "Day after Thanksgiving", HalfDay: true})
+	if d := date(year, time.July, 3); d.Weekday() != time.Friday && isWeekday(d) {
+		hs = append(hs, Holiday{Date: d, Name: "Independence Day Eve", HalfDay: true})
+	}
+	if d := date(year, time.December, 24); isWeekday(d) && d.Weekday() != time.Friday {
+		hs = append(hs, Holiday{Date: d, Name: "Christmas Eve", HalfDay: true})
+	}
+	return hs
+}
+
+func date(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func isWeekday(d time.Time) bool {
+	return d.Weekday() != time.Saturday && d.Weekday() != time.Sunday
+}
+
+// observed moves a holiday on a weekend to the nearest weekday.
+func observed(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	default:
+		return d
+	}
+}
+
+// nthWeekday returns the n-th given weekday of a month.
+func nthWeekday(year int, month time.Month, wd time.Weekday, n int) time.Time {
+	d := date(year, month, 1)
+	offset := (int(wd) - int(d.Weekday()) + 7) % 7
+	return d.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last given weekday of a month.
+func lastWeekday(year int, month time.Month, wd time.Weekday) time.Time {
+	d := date(year, month+1, 1).AddDate(0, 0, -1)
+	offset := (int(d.Weekday()) - int(wd) + 7) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// easterSunday computes the date of Western Easter with the anonymous
+// Gregorian algorithm.
+func easterSunday(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	dd := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), dd)
+}
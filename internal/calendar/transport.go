@@ -0,0 +1,34 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
+
+type GetDaysRequest struct {
+	Calendar  string
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func (r GetDaysRequest) Validate() *apperror.AppError {
+	if _, ok := Get(r.Calendar); !ok {
+		return apperror.New(apperror.NotFound, fmt.Sprintf("unknown calendar %q; available: %s", r.Calendar, strings.Join(Names(), ", ")))
+	}
+	if r.StartDate.IsZero() {
+		return apperror.New(apperror.BadRequest, "startDate is required")
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
+	}
+	return nil
+}
+
+type GetDaysResponse struct {
+	Calendar string      `json:"calendar"`
+	Days     []time.Time `json:"days"`     // trading days, including half days
+	Holidays []Holiday   `json:"holidays"` // closures and half days on weekdays in the range
+}
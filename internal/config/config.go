@@ -6,6 +6,42 @@ type Config struct {
 	Port    string
 	DBPath  string
 	Workers int
+	// Schedules holds the cron expression (UTC) of the watchlist refresh of
+	// each source, set after the source publishes the day's data. "off"
+	// disables a source's refresh.
+	Schedules map[string]string
+	// Limits caps the load put on each upstream.
+	Limits map[string]SourceLimit
+	// HTTPTimeout limits each attempt of an upstream request, in seconds.
+	HTTPTimeout int
+	// HTTPAttempts is how many times an upstream request is sent before it
+	// fails.
+	HTTPAttempts int
+	// ScraperMode is "live", "record" (save upstream responses to
+	// FixtureDir) or "replay" (serve them from FixtureDir, offline).
+	ScraperMode string
+	FixtureDir  string
+	// ArchiveResponses keeps every raw upstream response in the database so
+	// that prices can be reparsed later.
+	ArchiveResponses bool
+	// Quality configures the checks scraped prices must pass to be stored.
+	Quality QualityRules
+}
+
+// QualityRules are the price checks. Zero disables a limit.
+type QualityRules struct {
+	RejectInvalid bool           // zero, negative, NaN or infinite values
+	RejectFuture  bool           // dates after today
+	MaxMove       map[string]int // largest daily close move per asset class, in percent
+	ConfirmRun    int            // closes in a row at a new level that accept a larger move
+	StaleRun      int            // equal closes in a row that flag the last
+}
+
+// SourceLimit is the share of the workers and the request rate a source may
+// use. Zero means no limit.
+type SourceLimit struct {
+	Jobs          int // jobs of the source processed at once
+	RatePerMinute int // requests per minute to each of the source's hosts
 }
 
 func Load() Config {
@@ -13,6 +49,46 @@ func Load() Config {
 		Port:    getEnv("PORT", "8080"),
 		DBPath:  getEnv("DB_PATH", "finance.db"),
 		Workers: getEnvInt("WORKERS", 5),
+		Schedules: map[string]string{
+			"tefas":     getEnv("SCHEDULE_TEFAS", "0 17 * * 1-5"),      // prices out by 20:00 Istanbul
+			"isyatirim": getEnv("SCHEDULE_ISYATIRIM", "30 15 * * 1-5"), // Borsa Istanbul closes 18:00
+			"yahoo":     getEnv("SCHEDULE_YAHOO", "30 21 * * 1-5"),     // after the New York close
+		},
+		Limits: map[string]SourceLimit{
+			"tefas":     sourceLimit("TEFAS", 2, 120),
+			"isyatirim": sourceLimit("ISYATIRIM", 2, 120),
+			"yahoo":     sourceLimit("YAHOO", 3, 120),
+			"tcmb":      sourceLimit("TCMB", 0, 300), // exchange rates are not queued as jobs
+		},
+		HTTPTimeout:  getEnvInt("HTTP_TIMEOUT", 30),
+		HTTPAttempts: getEnvInt("HTTP_ATTEMPTS", 3),
+		ScraperMode:  getEnv("SCRAPER_MODE", "live"),
+		FixtureDir:   getEnv("SCRAPER_FIXTURES", "fixtures"),
+
+		ArchiveResponses: getEnv("ARCHIVE_RESPONSES", "false") == "true",
+
+		Quality: QualityRules{
+			RejectInvalid: getEnv("QUALITY_REJECT_INVALID", "true") == "true",
+			RejectFuture:  getEnv("QUALITY_REJECT_FUTURE", "true") == "true",
+			MaxMove: map[string]int{
+				"fund":      getEnvInt("QUALITY_MAX_MOVE_FUND", 20),
+				"stock":     getEnvInt("QUALITY_MAX_MOVE_STOCK", 50),
+				"index":     getEnvInt("QUALITY_MAX_MOVE_INDEX", 25),
+				"fx":        getEnvInt("QUALITY_MAX_MOVE_FX", 25),
+				"commodity": getEnvInt("QUALITY_MAX_MOVE_COMMODITY", 30),
+				"crypto":    getEnvInt("QUALITY_MAX_MOVE_CRYPTO", 60),
+			},
+			ConfirmRun: getEnvInt("QUALITY_CONFIRM_RUN", 3),
+			StaleRun:   getEnvInt("QUALITY_STALE_RUN", 10),
+		},
+	}
+}
+
+// sourceLimit reads JOBS_<NAME> and RATE_LIMIT_<NAME>.
+func sourceLimit(name string, jobs, perMinute int) SourceLimit {
+	return SourceLimit{
+		Jobs:          getEnvInt("JOBS_"+name, jobs),
+		RatePerMinute: getEnvInt("RATE_LIMIT_"+name, perMinute),
 	}
 }
 
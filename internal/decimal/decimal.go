@@ -0,0 +1,169 @@
+// Package decimal provides exact base-10 arithmetic for prices and rates.
+//
+// Values are arbitrary-precision rationals, so sums, products and quotients
+// carry no rounding error. Rounding happens only when a value that has no
+// finite decimal expansion (e.g. 1/3) is formatted; it is then rounded to
+// Scale fractional digits.
+package decimal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits kept when formatting a value
+// with no finite decimal expansion.
+const Scale = 18
+
+// Decimal is an exact decimal number. The zero value is 0.
+type Decimal struct {
+	r *big.Rat
+}
+
+// Parse reads a decimal string such as "13.027306" or "-1.5e-3".
+func Parse(s string) (Decimal, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	return Decimal{r: r}, nil
+}
+
+// FromFloat returns the shortest decimal that round-trips to f. Upstream
+// sources publish at most 15 significant digits, so this recovers the exact
+// published value of anything parsed into or stored as a float64.
+func FromFloat(f float64) Decimal {
+	d, _ := Parse(strconv.FormatFloat(f, 'f', -1, 64))
+	return d
+}
+
+func (d Decimal) rat() *big.Rat {
+	if d.r == nil {
+		return new(big.Rat)
+	}
+	return d.r
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool { return d.r == nil || d.r.Sign() == 0 }
+
+// Equal reports whether d and o are the same number.
+func (d Decimal) Equal(o Decimal) bool { return d.rat().Cmp(o.rat()) == 0 }
+
+// Sign returns -1, 0 or +1 as d is negative, zero or positive.
+func (d Decimal) Sign() int { return d.rat().Sign() }
+
+// Mul returns d*o.
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal{r: new(big.Rat).Mul(d.rat(), o.rat())}
+}
+
+// Quo returns d/o. Dividing by zero yields zero.
+func (d Decimal) Quo(o Decimal) Decimal {
+	if o.IsZero() {
+		return Decimal{}
+	}
+	return Decimal{r: new(big.Rat).Quo(d.rat(), o.rat())}
+}
+
+// Inv returns 1/d, or zero when d is zero.
+func (d Decimal) Inv() Decimal {
+	return Decimal{r: big.NewRat(1, 1)}.Quo(d)
+}
+
+// Float64 returns the nearest float64.
+func (d Decimal) Float64() float64 {
+	f, _ := d.rat().Float64()
+	return f
+}
+
+// String formats d without an exponent. Terminating decimals are printed
+// exactly with no trailing zeros; others are rounded to Scale digits.
+func (d Decimal) String() string {
+	r := d.rat()
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := r.FloatString(fractionDigits(r.Denom()))
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// fractionDigits returns how many fractional digits the exact expansion of a
+// fraction with this (reduced) denominator needs, capped at Scale. Only
+// denominators of the form 2^a*5^b terminate, after max(a, b) digits.
+func fractionDigits(denom *big.Int) int {
+	n := new(big.Int).Set(denom)
+	rem := new(big.Int)
+	count := func(p int64) int {
+		k, f := 0, big.NewInt(p)
+		for rem.Rem(n, f).Sign() == 0 {
+			n.Quo(n, f)
+			k++
+		}
+		return k
+	}
+	a, b := count(2), count(5)
+	if !n.IsInt64() || n.Int64() != 1 {
+		return Scale
+	}
+	return min(max(a, b), Scale)
+}
+
+// MarshalJSON encodes d as a JSON string so clients don't parse it into a
+// binary float.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON accepts a JSON string or number. null leaves d unchanged.
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	if unq, err := strconv.Unquote(s); err == nil {
+		s = unq
+	}
+	return d.parse(s)
+}
+
+// Value stores d as decimal text.
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+// Scan reads a decimal stored as text. Numbers stored as REAL or INTEGER
+// are read too; NULL reads as zero.
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+	case string:
+		return d.parse(v)
+	case []byte:
+		return d.parse(string(v))
+	case float64:
+		*d = FromFloat(v)
+	case int64:
+		*d = Decimal{r: new(big.Rat).SetInt64(v)}
+	default:
+		return fmt.Errorf("cannot scan %T into a decimal", src)
+	}
+	return nil
+}
+
+func (d *Decimal) parse(s string) error {
+	v, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
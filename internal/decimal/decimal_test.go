@@ -0,0 +1,111 @@
+package decimal
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func mustParse(t *testing.T, s string) Decimal {
+	t.Helper()
+	d, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Decimal
+		want string
+	}{
+		{Decimal{}, "0"},
+		{FromFloat(13.027306), "13.027306"},
+		{FromFloat(0.1).Mul(FromFloat(3)), "0.3"},
+		{mustParse(t, "1").Quo(mustParse(t, "8")), "0.125"},
+		{mustParse(t, "1").Quo(mustParse(t, "3")), "0.333333333333333333"},
+		{mustParse(t, "2").Quo(mustParse(t, "3")), "0.666666666666666667"},
+		{mustParse(t, "-1.50"), "-1.5"},
+		{mustParse(t, "1e3"), "1000"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	if !mustParse(t, "1.50").Equal(mustParse(t, "1.5")) || !(Decimal{}).Equal(mustParse(t, "0")) {
+		t.Error("expected equal decimals")
+	}
+	// Equal as float64, not as decimals.
+	if mustParse(t, "0.36759812345678901").Equal(mustParse(t, "0.367598123456789")) {
+		t.Error("expected different decimals")
+	}
+}
+
+func TestQuo_NoFloatDrift(t *testing.T) {
+	// 13.027306 TRY at 35.4379 USDTRY, then back: exact round trip.
+	native := FromFloat(13.027306)
+	rate := FromFloat(35.4379)
+	if got := native.Quo(rate).Mul(rate).String(); got != "13.027306" {
+		t.Errorf("round trip = %s, want 13.027306", got)
+	}
+}
+
+func TestQuo_ByZero(t *testing.T) {
+	if got := FromFloat(1).Quo(Decimal{}); !got.IsZero() {
+		t.Errorf("expected zero, got %s", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	b, err := json.Marshal(FromFloat(0.367598))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"0.367598"` {
+		t.Errorf("marshal = %s", b)
+	}
+
+	var d Decimal
+	if err := json.Unmarshal([]byte(`"1.25"`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "1.25" {
+		t.Errorf("unmarshal string = %s", d)
+	}
+	if err := json.Unmarshal([]byte(`2.5`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.String() != "2.5" {
+		t.Errorf("unmarshal number = %s", d)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src  any
+		want string
+	}{
+		{"0.12345678901234567", "0.12345678901234567"},
+		{[]byte("35.4379"), "35.4379"},
+		{0.367598, "0.367598"},
+		{int64(42), "42"},
+		{nil, "0"},
+	}
+	for _, tt := range tests {
+		var d Decimal
+		if err := d.Scan(tt.src); err != nil {
+			t.Fatalf("Scan(%v): %v", tt.src, err)
+		}
+		if d.String() != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, d, tt.want)
+		}
+	}
+	var d Decimal
+	if err := d.Scan("abc"); err == nil {
+		t.Error("expected an error for invalid text")
+	}
+}
@@ -0,0 +1,77 @@
+package fund
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/tefas"
+)
+
+// TEFAS fund types (the "fontip" parameter).
+const (
+	TypeSecurities = "YAT"  // securities investment funds (default)
+	TypePension    = "EMK"  // private pension (BES) funds
+	TypeExchange   = "BYF"  // exchange-traded funds
+	TypeRealEstate = "GYF"  // real-estate investment funds
+	TypeVenture    = "GSYF" // venture capital investment funds
+
+	DefaultType = tefas.DefaultFundType
+)
+
+var fundTypes = map[string]bool{
+	TypeSecurities: true,
+	TypePension:    true,
+	TypeExchange:   true,
+	TypeRealEstate: true,
+	TypeVenture:    true,
+}
+
+// SeriesSymbol returns the key under which a fund's prices, jobs and
+// statistics are stored. Default-type funds keep their bare code so existing
+// data stays addressable; other types are prefixed ("EMK:AEA") so the same
+// code under two fund types never collides. fundType may be empty, in which
+// case the prefix on symbol (or the default type) applies.
+func SeriesSymbol(fundType, symbol string) (string, error) {
+	prefixType, code := tefas.SplitSymbol(symbol)
+	if fundType == "" {
+		fundType = prefixType
+	} else if strings.Contains(symbol, tefas.TypeSeparator) && fundType != prefixType {
+		return "", fmt.Errorf("fundType %s conflicts with symbol prefix %s", fundType, prefixType)
+	}
+	if !fundTypes[fundType] {
+		return "", fmt.Errorf("fundType must be one of YAT, EMK, BYF, GYF, GSYF")
+	}
+	if fundType == DefaultType {
+		return code, nil
+	}
+	return fundType + tefas.TypeSeparator + code, nil
+}
+
+// Stats is the daily fund metadata TEFAS publishes next to the price.
+type Stats struct {
+	ID                int64     `json:"id"`
+	Code              string    `json:"code"`
+	Date              time.Time `json:"date"`
+	SharesOutstanding float64   `json:"sharesOutstanding"`
+	Investors         int64     `json:"investors"`
+	TotalValue        float64   `json:"totalValue"`
+	CreatedAt         time.Time `json:"createdAt"`
+}
+
+// Allocation is a fund's portfolio breakdown on one day. Weights maps the
+// TEFAS asset class code (HS stocks, DT government bonds, R repo, ...) to its
+// percentage of the portfolio.
+type Allocation struct {
+	Code    string             `json:"code"`
+	Date    time.Time          `json:"date"`
+	Weights map[string]float64 `json:"weights"`
+}
+
+// SymbolInfo is descriptive metadata for a symbol of a given source.
+type SymbolInfo struct {
+	Source    string    `json:"source"`
+	Symbol    string    `json:"symbol"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,32 @@
+package fund
+
+import "testing"
+
+func TestSeriesSymbol(t *testing.T) {
+	tests := []struct {
+		fundType, symbol string
+		want             string
+		wantErr          bool
+	}{
+		{"", "YAC", "YAC", false},
+		{"YAT", "YAC", "YAC", false},
+		{"YAT", "YAT:YAC", "YAC", false},
+		{"EMK", "AEA", "EMK:AEA", false},
+		{"", "EMK:AEA", "EMK:AEA", false},
+		{"EMK", "EMK:AEA", "EMK:AEA", false},
+		{"BYF", "EMK:AEA", "", true},
+		{"XYZ", "AEA", "", true},
+		{"", "XYZ:AEA", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := SeriesSymbol(tt.fundType, tt.symbol)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("SeriesSymbol(%q, %q) error = %v, wantErr %v", tt.fundType, tt.symbol, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("SeriesSymbol(%q, %q) = %q, want %q", tt.fundType, tt.symbol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package fund
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	SaveStats(ctx context.Context, stats []Stats) (int64, error)
+	ListStats(ctx context.Context, code string, from, to time.Time) ([]Stats, error)
+	ExistingStatsDates(ctx context.Context, code string, from, to time.Time) (map[time.Time]bool, error)
+	SaveAllocations(ctx context.Context, allocs []Allocation) (int64, error)
+	ListAllocations(ctx context.Context, code string, from, to time.Time) ([]Allocation, error)
+	ExistingAllocationDates(ctx context.Context, code string, from, to time.Time) (map[time.Time]bool, error)
+	SaveSymbol(ctx context.Context, info SymbolInfo) error
+	GetSymbol(ctx context.Context, source, symbol string) (*SymbolInfo, error)
+}
@@ -0,0 +1,244 @@
+package fund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/job"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
+
+// Source is the scraper that provides fund data.
+const Source = "tefas"
+
+// Backfiller queues a scrape when the requested range is not yet covered.
+// Fund statistics are collected by the same TEFAS job that fetches prices.
+type Backfiller interface {
+	EnsureCoverage(ctx context.Context, source, symbol string, from, to time.Time) ([]*job.Job, error)
+	// ExistingDates returns the days with a stored price.
+	ExistingDates(ctx context.Context, source, symbol string, from, to time.Time) (map[time.Time]bool, error)
+}
+
+type Service struct {
+	repo     Repository
+	jobRepo  job.Repository
+	backfill Backfiller
+	alloc    scraper.AllocationScraper
+	notify   func() // optional: wake worker pool
+}
+
+func NewService(repo Repository, jobRepo job.Repository, backfill Backfiller, alloc scraper.AllocationScraper) *Service {
+	return &Service{repo: repo, jobRepo: jobRepo, backfill: backfill, alloc: alloc}
+}
+
+// SetNotify sets a callback invoked when a new pending job is created.
+func (s *Service) SetNotify(fn func()) { s.notify = fn }
+
+func (s *Service) GetStats(ctx context.Context, req GetStatsRequest) (*GetStatsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, _ := SeriesSymbol(req.FundType, req.Code)
+
+	endDate := req.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	var j *job.Job
+	if s.backfill != nil {
+		jobs, err := s.backfill.EnsureCoverage(ctx, Source, code, req.StartDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		if len(jobs) > 0 {
+			j = jobs[0]
+		} else if j, err = s.ensureStatsCoverage(ctx, code, req.StartDate, endDate); err != nil {
+			return nil, err
+		}
+	}
+
+	stats, err := s.repo.ListStats(ctx, code, req.StartDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("list fund stats: %w", err)
+	}
+
+	resp := &GetStatsResponse{Code: code, Stats: stats, Job: j}
+	if resp.Name, err = s.name(ctx, code); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetAllocation returns stored allocation history and queues an allocation
+// job when the range is not yet covered.
+func (s *Service) GetAllocation(ctx context.Context, req GetAllocationRequest) (*GetAllocationResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	code, _ := SeriesSymbol(req.FundType, req.Code)
+
+	endDate := req.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	j, err := s.ensureAllocationCoverage(ctx, code, req.StartDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	allocs, err := s.repo.ListAllocations(ctx, code, req.StartDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("list fund allocations: %w", err)
+	}
+
+	resp := &GetAllocationResponse{Code: code, Allocations: allocs, Job: j}
+	if resp.Name, err = s.name(ctx, code); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *Service) ensureAllocationCoverage(ctx context.Context, code string, from, to time.Time) (*job.Job, error) {
+	existing, err := s.repo.ExistingAllocationDates(ctx, code, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("check existing allocation dates: %w", err)
+	}
+
+	totalDays := calendar.TEFAS.Count(from, to)
+	coverageRatio := float64(len(existing)) / float64(max(totalDays, 1))
+	if coverageRatio > 0.8 && len(existing) > 0 {
+		return nil, nil
+	}
+	return s.queue(ctx, job.KindAllocation, code, from, to)
+}
+
+// ensureStatsCoverage queues a price job over the stored prices that have no
+// statistics, such as prices saved before statistics were collected. The job
+// finds the prices already stored and saves only the statistics.
+func (s *Service) ensureStatsCoverage(ctx context.Context, code string, from, to time.Time) (*job.Job, error) {
+	prices, err := s.backfill.ExistingDates(ctx, Source, code, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("check existing price dates: %w", err)
+	}
+	existing, err := s.repo.ExistingStatsDates(ctx, code, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("check existing stats dates: %w", err)
+	}
+
+	var first, last time.Time
+	for d := range prices {
+		if existing[d] {
+			continue
+		}
+		if first.IsZero() || d.Before(first) {
+			first = d
+		}
+		if d.After(last) {
+			last = d
+		}
+	}
+	if first.IsZero() {
+		return nil, nil
+	}
+	return s.queue(ctx, job.KindPrices, code, first, last)
+}
+
+// queue returns the active job of the kind for the range, creating a pending
+// one when there is none.
+func (s *Service) queue(ctx context.Context, kind job.Kind, code string, from, to time.Time) (*job.Job, error) {
+	const dateFormat = "2006-01-02"
+	active, err := s.jobRepo.FindActive(ctx, kind, Source, code, from.Format(dateFormat), to.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("find active job: %w", err)
+	}
+	if active != nil {
+		return active, nil
+	}
+
+	j := &job.Job{
+		Kind:      kind,
+		Source:    Source,
+		Symbol:    code,
+		StartDate: from,
+		EndDate:   to,
+		Status:    job.StatusPending,
+	}
+	if err := s.jobRepo.Create(ctx, j); err != nil {
+		return nil, fmt.Errorf("create job: %w", err)
+	}
+	if s.notify != nil {
+		s.notify()
+	}
+	return j, nil
+}
+
+// Process implements job.Processor for allocation jobs.
+func (s *Service) Process(ctx context.Context, j *job.Job) error {
+	if s.alloc == nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(fmt.Errorf("allocation scraper not configured")))
+	}
+
+	existing, err := s.repo.ExistingAllocationDates(ctx, j.Symbol, j.StartDate, j.EndDate)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("check existing allocation dates: %w", err))
+	}
+
+	scraped, err := s.alloc.ScrapeAllocation(ctx, j.Symbol, j.StartDate, j.EndDate)
+	var partial *scraper.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("scrape allocation: %w", err))
+	}
+
+	allocs := make([]Allocation, 0, len(scraped))
+	for _, a := range scraped {
+		if existing[a.Date] {
+			continue
+		}
+		allocs = append(allocs, Allocation{Code: j.Symbol, Date: a.Date, Weights: a.Weights})
+	}
+
+	n, err := s.repo.SaveAllocations(ctx, allocs)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("save fund allocations: %w", err))
+	}
+
+	slog.Info("saved fund allocations", "symbol", j.Symbol, "new", n, "total_scraped", len(scraped))
+
+	j.RecordsCount = n
+	if partial != nil {
+		return job.RecordPartial(ctx, s.jobRepo, j, partial, failedRanges(partial), s.notify)
+	}
+
+	j.Status = job.StatusCompleted
+	_ = s.jobRepo.Update(ctx, j)
+	return nil
+}
+
+func (s *Service) name(ctx context.Context, code string) (string, error) {
+	info, err := s.repo.GetSymbol(ctx, Source, code)
+	if err != nil {
+		return "", fmt.Errorf("get symbol: %w", err)
+	}
+	if info == nil {
+		return "", nil
+	}
+	return info.Name, nil
+}
+
+// failedRanges converts the failed chunks of a partial scrape to the job's
+// failed ranges.
+func failedRanges(partial *scraper.PartialError) []job.Range {
+	failed := make([]job.Range, len(partial.Failed))
+	for i, f := range partial.Failed {
+		failed[i] = job.Range{StartDate: f.From, EndDate: f.To, Error: f.Err.Error()}
+	}
+	return failed
+}
@@ -0,0 +1,142 @@
+package fund
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/job"
+)
+
+// --- mock fund repo ---
+type mockRepo struct {
+	stats []Stats
+}
+
+func (m *mockRepo) SaveStats(_ context.Context, stats []Stats) (int64, error) {
+	m.stats = append(m.stats, stats...)
+	return int64(len(stats)), nil
+}
+func (m *mockRepo) ListStats(_ context.Context, _ string, from, to time.Time) ([]Stats, error) {
+	var out []Stats
+	for _, st := range m.stats {
+		if !st.Date.Before(from) && !st.Date.After(to) {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+func (m *mockRepo) ExistingStatsDates(ctx context.Context, code string, from, to time.Time) (map[time.Time]bool, error) {
+	stats, _ := m.ListStats(ctx, code, from, to)
+	dates := make(map[time.Time]bool)
+	for _, st := range stats {
+		dates[st.Date] = true
+	}
+	return dates, nil
+}
+func (m *mockRepo) SaveAllocations(_ context.Context, _ []Allocation) (int64, error) { return 0, nil }
+func (m *mockRepo) ListAllocations(_ context.Context, _ string, _, _ time.Time) ([]Allocation, error) {
+	return nil, nil
+}
+func (m *mockRepo) ExistingAllocationDates(_ context.Context, _ string, _, _ time.Time) (map[time.Time]bool, error) {
+	return map[time.Time]bool{}, nil
+}
+func (m *mockRepo) SaveSymbol(_ context.Context, _ SymbolInfo) error { return nil }
+func (m *mockRepo) GetSymbol(_ context.Context, _, _ string) (*SymbolInfo, error) {
+	return nil, nil
+}
+
+// --- mock job repo ---
+type mockJobRepo struct {
+	jobs   []*job.Job
+	nextID int64
+}
+
+func (m *mockJobRepo) Create(_ context.Context, j *job.Job) error {
+	m.nextID++
+	j.ID = m.nextID
+	m.jobs = append(m.jobs, j)
+	return nil
+}
+func (m *mockJobRepo) CreateBatch(ctx context.Context, jobs []*job.Job) error {
+	for _, j := range jobs {
+		_ = m.Create(ctx, j)
+	}
+	return nil
+}
+func (m *mockJobRepo) Update(_ context.Context, _ *job.Job) error             { return nil }
+func (m *mockJobRepo) Get(_ context.Context, _ int64) (*job.Job, error)       { return nil, nil }
+func (m *mockJobRepo) List(_ context.Context, _, _ string) ([]job.Job, error) { return nil, nil }
+func (m *mockJobRepo) FindActive(_ context.Context, kind job.Kind, source, symbol, from, to string) (*job.Job, error) {
+	for _, j := range m.jobs {
+		if j.Kind == kind && j.Source == source && j.Symbol == symbol &&
+			j.StartDate.Format("2006-01-02") == from && j.EndDate.Format("2006-01-02") == to {
+			return j, nil
+		}
+	}
+	return nil, nil
+}
+func (m *mockJobRepo) ClaimPending(_ context.Context, _ []string) (*job.Job, error) {
+	return nil, nil
+}
+func (m *mockJobRepo) RecoverStale(_ context.Context) (int64, error)    { return 0, nil }
+func (m *mockJobRepo) Cancel(_ context.Context, _ int64) (bool, error)  { return false, nil }
+func (m *mockJobRepo) Requeue(_ context.Context, _ int64) (bool, error) { return false, nil }
+
+// --- mock backfiller: the requested prices are already stored ---
+type mockBackfill struct {
+	prices map[time.Time]bool
+}
+
+func (m *mockBackfill) EnsureCoverage(_ context.Context, _, _ string, _, _ time.Time) ([]*job.Job, error) {
+	return nil, nil
+}
+func (m *mockBackfill) ExistingDates(_ context.Context, _, _ string, from, to time.Time) (map[time.Time]bool, error) {
+	dates := make(map[time.Time]bool)
+	for d := range m.prices {
+		if !d.Before(from) && !d.After(to) {
+			dates[d] = true
+		}
+	}
+	return dates, nil
+}
+
+func TestGetStats_StoredPricesWithoutStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	repo := &mockRepo{stats: []Stats{{Code: "YAC", Date: day(2)}}}
+	jobRepo := &mockJobRepo{}
+	backfill := &mockBackfill{prices: map[time.Time]bool{day(2): true, day(3): true, day(4): true}}
+	svc := NewService(repo, jobRepo, backfill, nil)
+	ctx := context.Background()
+	req := GetStatsRequest{Code: "YAC", StartDate: day(1), EndDate: day(31)}
+
+	resp, err := svc.GetStats(ctx, req)
+	if err != nil {
+		t.Fatalf("get stats: %v", err)
+	}
+	j := resp.Job
+	if j == nil || j.Kind != job.KindPrices || j.Source != Source || j.Symbol != "YAC" ||
+		!j.StartDate.Equal(day(3)) || !j.EndDate.Equal(day(4)) {
+		t.Fatalf("expected a price job for Jan 3-4, got %+v", j)
+	}
+
+	// Asking again returns the queued job instead of another one.
+	resp, err = svc.GetStats(ctx, req)
+	if err != nil {
+		t.Fatalf("get stats: %v", err)
+	}
+	if len(jobRepo.jobs) != 1 || resp.Job == nil || resp.Job.ID != j.ID {
+		t.Errorf("expected the queued job again, got %+v and %d jobs", resp.Job, len(jobRepo.jobs))
+	}
+
+	// Once every stored price has statistics nothing is queued.
+	repo.stats = append(repo.stats, Stats{Code: "YAC", Date: day(3)}, Stats{Code: "YAC", Date: day(4)})
+	jobRepo.jobs = nil
+	resp, err = svc.GetStats(ctx, req)
+	if err != nil {
+		t.Fatalf("get stats: %v", err)
+	}
+	if resp.Job != nil || len(resp.Stats) != 3 {
+		t.Errorf("expected 3 stats and no job, got %+v", resp)
+	}
+}
@@ -0,0 +1,56 @@
+package fund
+
+import (
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+	"github.com/ahmethakanbesel/finance-api/internal/job"
+)
+
+type GetStatsRequest struct {
+	Code      string
+	FundType  string // empty means code prefix or YAT
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func (r GetStatsRequest) Validate() *apperror.AppError {
+	if len(r.Code) < 2 {
+		return apperror.New(apperror.BadRequest, "code must be at least 2 characters")
+	}
+	if r.StartDate.IsZero() {
+		return apperror.New(apperror.BadRequest, "startDate is required")
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
+	}
+	if _, err := SeriesSymbol(r.FundType, r.Code); err != nil {
+		return apperror.New(apperror.BadRequest, err.Error())
+	}
+	return nil
+}
+
+type GetStatsResponse struct {
+	Code  string   `json:"code"`
+	Name  string   `json:"name,omitempty"`
+	Stats []Stats  `json:"stats"`
+	Job   *job.Job `json:"job,omitempty"`
+}
+
+type GetAllocationRequest struct {
+	Code      string
+	FundType  string // empty means code prefix or YAT
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+func (r GetAllocationRequest) Validate() *apperror.AppError {
+	return GetStatsRequest(r).Validate()
+}
+
+type GetAllocationResponse struct {
+	Code        string       `json:"code"`
+	Name        string       `json:"name,omitempty"`
+	Allocations []Allocation `json:"allocations"`
+	Job         *job.Job     `json:"job,omitempty"`
+}
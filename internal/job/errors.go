@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"errors"
+)
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that the job fails without being retried, e.g. for
+// an unknown source or a misconfigured processor.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Retryable reports whether a job that failed with err may succeed if run
+// again. Errors are retryable unless they are marked Permanent, come from a
+// cancelled context, or carry a Temporary method that reports false (e.g. an
+// upstream HTTP 404). Timeouts are always retryable.
+func Retryable(err error) bool {
+	var perm *permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var temp interface{ Temporary() bool }
+	if errors.As(err, &temp) {
+		return temp.Temporary()
+	}
+	return true
+}
@@ -9,17 +9,112 @@ const (
 	StatusRunning   Status = "running"
 	StatusCompleted Status = "completed"
 	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+	// StatusPartial is a finished job whose range was stored except for
+	// FailedRanges, which are queued again as follow-up jobs.
+	StatusPartial Status = "partial"
 )
 
+// Kind identifies the dataset a job fetches and selects its Processor.
+type Kind string
+
+const (
+	KindPrices     Kind = "prices"
+	KindAllocation Kind = "allocation"
+	// KindRefresh fetches prices again, dates already stored included, and
+	// overwrites the stored values the source has since corrected.
+	KindRefresh Kind = "refresh"
+	// KindReparse rebuilds prices from archived upstream responses instead
+	// of fetching them.
+	KindReparse Kind = "reparse"
+)
+
+// Active reports whether the job is waiting or being processed.
+func (s Status) Active() bool {
+	return s == StatusPending || s == StatusRunning
+}
+
 type Job struct {
-	ID           int64     `json:"id"`
-	Source       string    `json:"source"`
-	Symbol       string    `json:"symbol"`
-	StartDate    time.Time `json:"startDate"`
-	EndDate      time.Time `json:"endDate"`
-	Status       Status    `json:"status"`
-	Error        string    `json:"error,omitempty"`
-	RecordsCount int64     `json:"recordsCount"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
+	ID           int64      `json:"id"`
+	Kind         Kind       `json:"kind"`
+	Source       string     `json:"source"`
+	Symbol       string     `json:"symbol"`
+	StartDate    time.Time  `json:"startDate"`
+	EndDate      time.Time  `json:"endDate"`
+	Status       Status     `json:"status"`
+	Error        string     `json:"error,omitempty"`
+	RecordsCount int64      `json:"recordsCount"`
+	Attempts     int        `json:"attempts"`
+	MaxAttempts  int        `json:"maxAttempts"`
+	NextRunAt    *time.Time `json:"nextRunAt,omitempty"`    // set while a failed attempt waits to be retried
+	FailedRanges []Range    `json:"failedRanges,omitempty"` // sub-ranges a partial job could not fetch
+	ParentID     int64      `json:"parentId,omitempty"`     // partial job a follow-up was queued for
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+}
+
+// Range is a sub-range of a job's date range.
+type Range struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// DefaultMaxAttempts is the number of times a job is run before a retryable
+// failure is recorded as failed.
+const DefaultMaxAttempts = 3
+
+// Backoff delays after a failed attempt: the first retry waits baseBackoff and
+// every further retry doubles the wait, up to maxBackoff.
+const (
+	baseBackoff = 30 * time.Second
+	maxBackoff  = 30 * time.Minute
+)
+
+// Backoff returns how long to wait before running a job again after its
+// attempt-th attempt failed.
+func Backoff(attempt int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// Fail records a failed attempt. A retryable error on a job with attempts left
+// puts it back to pending, to be claimed again after Backoff; anything else
+// marks the job failed.
+func (j *Job) Fail(err error, now time.Time) {
+	j.Error = err.Error()
+	if Retryable(err) && j.Attempts < j.MaxAttempts {
+		next := now.UTC().Add(Backoff(j.Attempts)).Truncate(time.Second)
+		j.Status = StatusPending
+		j.NextRunAt = &next
+		return
+	}
+	j.Status = StatusFailed
+	j.NextRunAt = nil
+}
+
+// Partial records that the job's data was stored except for the failed
+// sub-ranges, and returns a pending follow-up job for each of them.
+func (j *Job) Partial(err error, failed []Range) []*Job {
+	j.Status = StatusPartial
+	j.Error = err.Error()
+	j.FailedRanges = failed
+	j.NextRunAt = nil
+
+	followUps := make([]*Job, len(failed))
+	for i, r := range failed {
+		followUps[i] = &Job{
+			Kind:      j.Kind,
+			Source:    j.Source,
+			Symbol:    j.Symbol,
+			StartDate: r.StartDate,
+			EndDate:   r.EndDate,
+			Status:    StatusPending,
+			ParentID:  j.ID,
+		}
+	}
+	return followUps
 }
@@ -0,0 +1,127 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type statusErr struct{ temporary bool }
+
+func (e statusErr) Error() string   { return "upstream error" }
+func (e statusErr) Temporary() bool { return e.temporary }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain error", errors.New("connection reset"), true},
+		{"deadline", fmt.Errorf("scrape: %w", context.DeadlineExceeded), true},
+		{"cancelled", fmt.Errorf("scrape: %w", context.Canceled), false},
+		{"permanent", Permanent(errors.New("unknown source")), false},
+		{"wrapped permanent", fmt.Errorf("scrape: %w", Permanent(errors.New("bad"))), false},
+		{"temporary status", fmt.Errorf("scrape: %w", statusErr{temporary: true}), true},
+		{"client status", fmt.Errorf("scrape: %w", statusErr{temporary: false}), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.want {
+				t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, w := range want {
+		if got := Backoff(i + 1); got != w {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+	if got := Backoff(20); got != 30*time.Minute {
+		t.Errorf("Backoff(20) = %v, want cap of 30m", got)
+	}
+}
+
+func TestJob_Fail(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	j := &Job{Status: StatusRunning, Attempts: 1, MaxAttempts: 3}
+	j.Fail(errors.New("timeout"), now)
+	if j.Status != StatusPending || j.NextRunAt == nil || !j.NextRunAt.Equal(now.Add(30*time.Second)) {
+		t.Errorf("expected pending retry at +30s, got %s %v", j.Status, j.NextRunAt)
+	}
+	if j.Error != "timeout" {
+		t.Errorf("expected error to be kept, got %q", j.Error)
+	}
+
+	j = &Job{Status: StatusRunning, Attempts: 3, MaxAttempts: 3}
+	j.Fail(errors.New("timeout"), now)
+	if j.Status != StatusFailed || j.NextRunAt != nil {
+		t.Errorf("expected failed after last attempt, got %s %v", j.Status, j.NextRunAt)
+	}
+
+	j = &Job{Status: StatusRunning, Attempts: 1, MaxAttempts: 3}
+	j.Fail(Permanent(errors.New("unknown source")), now)
+	if j.Status != StatusFailed {
+		t.Errorf("expected permanent error to fail the job, got %s", j.Status)
+	}
+}
+
+func TestJob_Partial(t *testing.T) {
+	j := &Job{
+		ID:        7,
+		Kind:      KindPrices,
+		Source:    "tefas",
+		Symbol:    "YAC",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		Status:    StatusRunning,
+	}
+	failed := []Range{{
+		StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
+		Error:     "timeout",
+	}}
+
+	followUps := j.Partial(errors.New("1 of 3 chunks failed"), failed)
+	if j.Status != StatusPartial || len(j.FailedRanges) != 1 || j.Status.Active() {
+		t.Errorf("expected inactive partial job with one failed range, got %s %v", j.Status, j.FailedRanges)
+	}
+	if len(followUps) != 1 {
+		t.Fatalf("expected one follow-up, got %d", len(followUps))
+	}
+	f := followUps[0]
+	if f.Status != StatusPending || f.ParentID != 7 || f.Kind != KindPrices || f.Symbol != "YAC" ||
+		!f.StartDate.Equal(failed[0].StartDate) || !f.EndDate.Equal(failed[0].EndDate) {
+		t.Errorf("unexpected follow-up: %+v", f)
+	}
+}
+
+func TestRecordPartial(t *testing.T) {
+	repo := newMockRepo()
+	ctx := context.Background()
+	j := &Job{Kind: KindAllocation, Source: "tefas", Symbol: "YAC", Status: StatusRunning}
+	_ = repo.Create(ctx, j)
+
+	mar, apr := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)
+	failed := []Range{{StartDate: mar, EndDate: apr, Error: "timeout"}}
+	notified := false
+	if err := RecordPartial(ctx, repo, j, errors.New("1 of 3 chunks failed"), failed, func() { notified = true }); err != nil {
+		t.Fatalf("record partial: %v", err)
+	}
+
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != StatusPartial || len(got.FailedRanges) != 1 || got.FailedRanges[0].Error != "timeout" {
+		t.Errorf("unexpected job: %+v", got)
+	}
+	jobs, _ := repo.List(ctx, "tefas", "YAC")
+	if len(jobs) != 2 || !notified {
+		t.Errorf("expected a queued follow-up and a notify, got %d jobs, notified %v", len(jobs), notified)
+	}
+}
@@ -1,13 +1,57 @@
 package job
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
 
 type Repository interface {
 	Create(ctx context.Context, j *Job) error
+	// CreateBatch creates all jobs in a single transaction.
+	CreateBatch(ctx context.Context, jobs []*Job) error
+	// Update stores a job's status and progress. A cancelled job keeps its
+	// status, so that a processor finishing after the cancel cannot
+	// overwrite it.
 	Update(ctx context.Context, j *Job) error
 	Get(ctx context.Context, id int64) (*Job, error)
 	List(ctx context.Context, source, symbol string) ([]Job, error)
-	FindActive(ctx context.Context, source, symbol string, from, to string) (*Job, error)
-	ClaimPending(ctx context.Context) (*Job, error)
+	FindActive(ctx context.Context, kind Kind, source, symbol string, from, to string) (*Job, error)
+	// ClaimPending marks the oldest due pending job running and returns it,
+	// or nil when there is none. Jobs of the skipped sources are left alone.
+	ClaimPending(ctx context.Context, skipSources []string) (*Job, error)
 	RecoverStale(ctx context.Context) (int64, error)
+	// Cancel marks a pending or running job cancelled. It reports false when
+	// the job is in any other status.
+	Cancel(ctx context.Context, id int64) (bool, error)
+	// Requeue resets a failed or cancelled job to pending. It reports false
+	// when the job is in any other status.
+	Requeue(ctx context.Context, id int64) (bool, error)
+}
+
+// RecordFailure records a failed attempt of j, as Job.Fail does, and stores
+// it. It returns err so that processors can return its result.
+func RecordFailure(ctx context.Context, repo Repository, j *Job, err error) error {
+	j.Fail(err, time.Now())
+	_ = repo.Update(ctx, j)
+	return err
+}
+
+// RecordPartial marks j partial with err, stores it and queues a follow-up
+// job for every failed range. notify, when set, is called once the
+// follow-ups are queued.
+func RecordPartial(ctx context.Context, repo Repository, j *Job, err error, failed []Range, notify func()) error {
+	followUps := j.Partial(err, failed)
+	if err := repo.CreateBatch(ctx, followUps); err != nil {
+		return RecordFailure(ctx, repo, j, fmt.Errorf("queue follow-up jobs: %w", err))
+	}
+	_ = repo.Update(ctx, j)
+
+	slog.Warn("job partially completed", "job", j.ID, "source", j.Source, "symbol", j.Symbol,
+		"failed_ranges", len(followUps), "error", err)
+	if notify != nil {
+		notify()
+	}
+	return nil
 }
@@ -2,17 +2,35 @@ package job
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
 )
 
 type Service struct {
-	repo Repository
+	repo    Repository
+	notify  func()           // optional: wake worker pool
+	cancel  func(int64) bool // optional: stop a job being processed
+	running func(int64) bool // optional: whether a job is still being processed
 }
 
 func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
+// SetNotify sets a callback invoked when a job becomes pending.
+func (s *Service) SetNotify(fn func()) { s.notify = fn }
+
+// SetCancel sets the callback that stops a running job, normally
+// WorkerPool.Cancel.
+func (s *Service) SetCancel(fn func(id int64) bool) { s.cancel = fn }
+
+// SetRunning sets the callback that reports whether a job is still being
+// processed, normally WorkerPool.Running.
+func (s *Service) SetRunning(fn func(id int64) bool) { s.running = fn }
+
 func (s *Service) RecoverStaleJobs(ctx context.Context) error {
 	n, err := s.repo.RecoverStale(ctx)
 	if err != nil {
@@ -37,3 +55,109 @@ func (s *Service) List(ctx context.Context, req ListJobsRequest) ([]Job, error)
 	}
 	return s.repo.List(ctx, req.Source, req.Symbol)
 }
+
+// Create queues a job for the range. An identical active job is returned
+// instead of queueing a duplicate; created reports which happened.
+func (s *Service) Create(ctx context.Context, req CreateJobRequest) (j *Job, created bool, err error) {
+	if err := req.Validate(); err != nil {
+		return nil, false, err
+	}
+	if req.Kind == "" {
+		req.Kind = KindPrices
+	}
+	if req.EndDate.IsZero() {
+		req.EndDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	const dateFormat = "2006-01-02"
+	active, err := s.repo.FindActive(ctx, req.Kind, req.Source, req.Symbol, req.StartDate.Format(dateFormat), req.EndDate.Format(dateFormat))
+	if err != nil {
+		return nil, false, fmt.Errorf("find active job: %w", err)
+	}
+	if active != nil {
+		return active, false, nil
+	}
+
+	j = &Job{
+		Kind:      req.Kind,
+		Source:    req.Source,
+		Symbol:    req.Symbol,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Status:    StatusPending,
+	}
+	if err := s.repo.Create(ctx, j); err != nil {
+		return nil, false, fmt.Errorf("create job: %w", err)
+	}
+	if s.notify != nil {
+		s.notify()
+	}
+	return j, true, nil
+}
+
+// Cancel stops a pending or running job. A running job's context is
+// cancelled so that its processor returns early.
+func (s *Service) Cancel(ctx context.Context, req GetJobRequest) (*Job, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	j, err := s.repo.Get(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !j.Status.Active() {
+		return nil, apperror.New(apperror.Conflict, fmt.Sprintf("job is %s and cannot be cancelled", j.Status))
+	}
+
+	ok, err := s.repo.Cancel(ctx, j.ID)
+	if err != nil {
+		return nil, fmt.Errorf("cancel job: %w", err)
+	}
+	if !ok {
+		return nil, apperror.New(apperror.Conflict, "job finished before it could be cancelled")
+	}
+	if s.cancel != nil {
+		s.cancel(j.ID)
+	}
+	return s.repo.Get(ctx, j.ID)
+}
+
+// Retry requeues a failed or cancelled job.
+func (s *Service) Retry(ctx context.Context, req GetJobRequest) (*Job, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	j, err := s.repo.Get(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if j.Status != StatusFailed && j.Status != StatusCancelled {
+		return nil, apperror.New(apperror.Conflict, fmt.Sprintf("job is %s; only failed or cancelled jobs can be retried", j.Status))
+	}
+	// A cancelled job's worker may not have returned yet; requeued, the job
+	// could be claimed again while that run still updates it.
+	if s.running != nil && s.running(j.ID) {
+		return nil, apperror.New(apperror.Conflict, "job is still stopping; retry it once it has stopped")
+	}
+
+	const dateFormat = "2006-01-02"
+	active, err := s.repo.FindActive(ctx, j.Kind, j.Source, j.Symbol, j.StartDate.Format(dateFormat), j.EndDate.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("find active job: %w", err)
+	}
+	if active != nil {
+		return nil, apperror.New(apperror.Conflict, fmt.Sprintf("job %d already covers this range", active.ID))
+	}
+
+	ok, err := s.repo.Requeue(ctx, j.ID)
+	if err != nil {
+		return nil, fmt.Errorf("retry job: %w", err)
+	}
+	if !ok {
+		return nil, apperror.New(apperror.Conflict, "job changed status before it could be retried")
+	}
+	if s.notify != nil {
+		s.notify()
+	}
+	return s.repo.Get(ctx, j.ID)
+}
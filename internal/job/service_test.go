@@ -2,8 +2,10 @@ package job
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 )
 
 type mockRepo struct {
@@ -28,9 +30,19 @@ func (m *mockRepo) Create(_ context.Context, j *Job) error {
 	return nil
 }
 
+func (m *mockRepo) CreateBatch(ctx context.Context, jobs []*Job) error {
+	for _, j := range jobs {
+		_ = m.Create(ctx, j)
+	}
+	return nil
+}
+
 func (m *mockRepo) Update(_ context.Context, j *Job) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if prev, ok := m.jobs[j.ID]; ok && prev.Status == StatusCancelled {
+		return nil
+	}
 	cp := *j
 	m.jobs[j.ID] = &cp
 	return nil
@@ -63,16 +75,20 @@ func (m *mockRepo) List(_ context.Context, source, symbol string) ([]Job, error)
 	return result, nil
 }
 
-func (m *mockRepo) FindActive(_ context.Context, _, _, _, _ string) (*Job, error) {
+func (m *mockRepo) FindActive(_ context.Context, _ Kind, _, _, _, _ string) (*Job, error) {
 	return nil, nil
 }
 
-func (m *mockRepo) ClaimPending(_ context.Context) (*Job, error) {
+func (m *mockRepo) ClaimPending(_ context.Context, skipSources []string) (*Job, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	for _, j := range m.jobs {
-		if j.Status == StatusPending {
+		if slices.Contains(skipSources, j.Source) {
+			continue
+		}
+		if j.Status == StatusPending && (j.NextRunAt == nil || !j.NextRunAt.After(time.Now())) {
 			j.Status = StatusRunning
+			j.Attempts++
 			cp := *j
 			return &cp, nil
 		}
@@ -84,6 +100,32 @@ func (m *mockRepo) RecoverStale(_ context.Context) (int64, error) {
 	return m.staleCount, m.recoverErr
 }
 
+func (m *mockRepo) Cancel(_ context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok || !j.Status.Active() {
+		return false, nil
+	}
+	j.Status = StatusCancelled
+	j.NextRunAt = nil
+	return true, nil
+}
+
+func (m *mockRepo) Requeue(_ context.Context, id int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok || (j.Status != StatusFailed && j.Status != StatusCancelled) {
+		return false, nil
+	}
+	j.Status = StatusPending
+	j.Error = ""
+	j.Attempts = 0
+	j.NextRunAt = nil
+	return true, nil
+}
+
 type notFoundErr struct{}
 
 func (e *notFoundErr) Error() string { return "not found" }
@@ -144,3 +186,72 @@ func TestService_List(t *testing.T) {
 		t.Errorf("expected 1 job, got %d", len(jobs))
 	}
 }
+
+func TestService_Create(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	notified := false
+	svc.SetNotify(func() { notified = true })
+
+	j, created, err := svc.Create(context.Background(), CreateJobRequest{
+		Source:    "tefas",
+		Symbol:    "YAC",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !created || j.ID == 0 || j.Kind != KindPrices || j.Status != StatusPending || j.EndDate.IsZero() {
+		t.Errorf("unexpected job %+v (created=%v)", j, created)
+	}
+	if !notified {
+		t.Error("expected notify to be called")
+	}
+
+	if _, _, err := svc.Create(context.Background(), CreateJobRequest{Kind: "quotes", Source: "tefas", Symbol: "YAC"}); err == nil {
+		t.Error("expected validation error for unknown kind")
+	}
+}
+
+func TestService_CancelAndRetry(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	var stopped int64
+	svc.SetCancel(func(id int64) bool { stopped = id; return true })
+	ctx := context.Background()
+
+	_ = repo.Create(ctx, &Job{Source: "tefas", Symbol: "YAC", Status: StatusRunning})
+
+	// Running jobs cannot be retried
+	if _, err := svc.Retry(ctx, GetJobRequest{ID: 1}); err == nil {
+		t.Error("expected conflict retrying a running job")
+	}
+
+	j, err := svc.Cancel(ctx, GetJobRequest{ID: 1})
+	if err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if j.Status != StatusCancelled || stopped != 1 {
+		t.Errorf("expected cancelled job and stopped worker, got %s (stopped %d)", j.Status, stopped)
+	}
+
+	if _, err := svc.Cancel(ctx, GetJobRequest{ID: 1}); err == nil {
+		t.Error("expected conflict cancelling a cancelled job")
+	}
+
+	// Not while its worker is still stopping.
+	stopping := true
+	svc.SetRunning(func(id int64) bool { return stopping && id == 1 })
+	if _, err := svc.Retry(ctx, GetJobRequest{ID: 1}); err == nil {
+		t.Error("expected conflict retrying a job that is still stopping")
+	}
+	stopping = false
+
+	j, err = svc.Retry(ctx, GetJobRequest{ID: 1})
+	if err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if j.Status != StatusPending {
+		t.Errorf("expected pending, got %s", j.Status)
+	}
+}
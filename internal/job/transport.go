@@ -1,6 +1,10 @@
 package job
 
-import "github.com/ahmethakanbesel/finance-api/internal/apperror"
+import (
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
 
 type GetJobRequest struct {
 	ID int64
@@ -21,3 +25,33 @@ type ListJobsRequest struct {
 func (r ListJobsRequest) Validate() *apperror.AppError {
 	return nil
 }
+
+// CreateJobRequest queues a job explicitly, regardless of what is stored.
+type CreateJobRequest struct {
+	Kind      Kind // defaults to KindPrices
+	Source    string
+	Symbol    string
+	StartDate time.Time
+	EndDate   time.Time // defaults to today
+}
+
+func (r CreateJobRequest) Validate() *apperror.AppError {
+	switch r.Kind {
+	case "", KindPrices, KindRefresh, KindAllocation, KindReparse:
+	default:
+		return apperror.New(apperror.BadRequest, "kind must be prices, refresh, allocation or reparse")
+	}
+	if r.Source == "" {
+		return apperror.New(apperror.BadRequest, "source is required")
+	}
+	if len(r.Symbol) < 2 {
+		return apperror.New(apperror.BadRequest, "symbol must be at least 2 characters")
+	}
+	if r.StartDate.IsZero() {
+		return apperror.New(apperror.BadRequest, "startDate is required")
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package job
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
 	"sync"
 	"time"
@@ -12,6 +13,33 @@ type Processor interface {
 	Process(ctx context.Context, j *Job) error
 }
 
+// Router is a Processor that dispatches jobs to the Processor registered for
+// their Kind. Jobs of an unknown kind are marked failed.
+type Router struct {
+	repo       Repository
+	processors map[Kind]Processor
+}
+
+// NewRouter creates an empty Router.
+func NewRouter(repo Repository) *Router {
+	return &Router{repo: repo, processors: make(map[Kind]Processor)}
+}
+
+// Handle registers p for jobs of the given kind.
+func (r *Router) Handle(kind Kind, p Processor) {
+	r.processors[kind] = p
+}
+
+// Process implements Processor.
+func (r *Router) Process(ctx context.Context, j *Job) error {
+	p, ok := r.processors[j.Kind]
+	if !ok {
+		err := fmt.Errorf("no processor for job kind %q", j.Kind)
+		return RecordFailure(ctx, r.repo, j, Permanent(err))
+	}
+	return p.Process(ctx, j)
+}
+
 // WorkerPool runs a fixed number of goroutines that claim and process pending jobs.
 type WorkerPool struct {
 	repo         Repository
@@ -19,20 +47,79 @@ type WorkerPool struct {
 	workers      int
 	notify       chan struct{}
 	pollInterval time.Duration
+	sourceLimits map[string]int // jobs of a source processed at once
+
+	claimMu sync.Mutex // serialises claims so that source limits hold
+	mu      sync.Mutex
+	running map[int64]*runningJob
+	active  map[string]int // running jobs per source
+}
+
+// runningJob is a job claimed by a worker.
+type runningJob struct {
+	ctx       context.Context // the job's own context, derived from the pool's
+	cancel    context.CancelFunc
+	cancelled bool
 }
 
 // NewWorkerPool creates a pool with the given number of workers.
-func NewWorkerPool(repo Repository, processor Processor, workers int) *WorkerPool {
+func NewWorkerPool(repo Repository, processor Processor, workers int, opts ...PoolOption) *WorkerPool {
 	if workers <= 0 {
 		workers = 1
 	}
-	return &WorkerPool{
+	wp := &WorkerPool{
 		repo:         repo,
 		processor:    processor,
 		workers:      workers,
 		notify:       make(chan struct{}, 1),
 		pollInterval: 5 * time.Second,
+		sourceLimits: make(map[string]int),
+		running:      make(map[int64]*runningJob),
+		active:       make(map[string]int),
+	}
+	for _, o := range opts {
+		o(wp)
 	}
+	return wp
+}
+
+type PoolOption func(*WorkerPool)
+
+// WithSourceLimit caps how many jobs of a source are processed at once.
+// Pending jobs of a source at its limit wait while workers take jobs of other
+// sources. Zero or less means no limit beyond the pool size.
+func WithSourceLimit(source string, n int) PoolOption {
+	return func(wp *WorkerPool) {
+		if n > 0 {
+			wp.sourceLimits[source] = n
+		}
+	}
+}
+
+// Cancel cancels the context of a job being processed. It reports false when
+// no worker is processing the job. A job being claimed is waited for, so that
+// a job cancelled right after its claim is still stopped.
+func (wp *WorkerPool) Cancel(id int64) bool {
+	wp.claimMu.Lock()
+	defer wp.claimMu.Unlock()
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	rj, ok := wp.running[id]
+	if !ok {
+		return false
+	}
+	rj.cancelled = true
+	rj.cancel()
+	return true
+}
+
+// Running reports whether a worker still holds the job, from its claim
+// until its last status update.
+func (wp *WorkerPool) Running(id int64) bool {
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	_, ok := wp.running[id]
+	return ok
 }
 
 // Notify wakes idle workers to check for pending jobs. Non-blocking.
@@ -80,7 +167,7 @@ func (wp *WorkerPool) drain(ctx context.Context, id int) {
 			return
 		}
 
-		j, err := wp.repo.ClaimPending(ctx)
+		j, err := wp.claim(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
 				return // shutting down
@@ -92,10 +179,96 @@ func (wp *WorkerPool) drain(ctx context.Context, id int) {
 			return // no more pending jobs
 		}
 
-		slog.Info("worker: processing job", "worker", id, "job", j.ID, "source", j.Source, "symbol", j.Symbol)
+		slog.Info("worker: processing job", "worker", id, "job", j.ID, "kind", j.Kind, "source", j.Source, "symbol", j.Symbol)
+
+		wp.process(ctx, id, j)
+	}
+}
+
+// claim claims a pending job of a source with a free slot, takes the slot
+// and registers the job as running. Claims are serialised so that two
+// workers cannot both take a source's last slot.
+func (wp *WorkerPool) claim(ctx context.Context) (*Job, error) {
+	wp.claimMu.Lock()
+	defer wp.claimMu.Unlock()
 
-		if err := wp.processor.Process(ctx, j); err != nil {
-			slog.Error("worker: process job", "worker", id, "job", j.ID, "error", err)
+	wp.mu.Lock()
+	var saturated []string
+	for source, limit := range wp.sourceLimits {
+		if wp.active[source] >= limit {
+			saturated = append(saturated, source)
 		}
 	}
+	wp.mu.Unlock()
+
+	j, err := wp.repo.ClaimPending(ctx, saturated)
+	if err != nil || j == nil {
+		return nil, err
+	}
+	jctx, cancel := context.WithCancel(ctx)
+	wp.mu.Lock()
+	wp.active[j.Source]++
+	wp.running[j.ID] = &runningJob{ctx: jctx, cancel: cancel}
+	wp.mu.Unlock()
+	return j, nil
+}
+
+// release frees the slot of a finished job. Workers idling because the
+// source was at its limit are woken.
+func (wp *WorkerPool) release(j *Job) {
+	wp.mu.Lock()
+	wp.active[j.Source]--
+	if wp.active[j.Source] <= 0 {
+		delete(wp.active, j.Source)
+	}
+	_, limited := wp.sourceLimits[j.Source]
+	wp.mu.Unlock()
+	if limited {
+		wp.Notify()
+	}
+}
+
+// process runs a claimed job under its own context so that Cancel can stop
+// it. A cancelled job that did not finish is recorded as cancelled; the
+// repository keeps a cancelled job's status, so the processor's own final
+// update cannot overwrite it. The job stays registered until its last
+// update, so that it cannot be retried while this run may still write it.
+func (wp *WorkerPool) process(ctx context.Context, id int, j *Job) {
+	defer wp.release(j)
+
+	wp.mu.Lock()
+	rj := wp.running[j.ID]
+	wp.mu.Unlock()
+	defer rj.cancel()
+	defer func() {
+		wp.mu.Lock()
+		if wp.running[j.ID] == rj {
+			delete(wp.running, j.ID)
+		}
+		wp.mu.Unlock()
+	}()
+
+	err := wp.processor.Process(rj.ctx, j)
+
+	wp.mu.Lock()
+	cancelled := rj.cancelled
+	wp.mu.Unlock()
+
+	if err == nil {
+		return
+	}
+	if cancelled {
+		slog.Info("worker: job cancelled", "worker", id, "job", j.ID)
+		j.Status = StatusCancelled
+		j.Error = ""
+		j.NextRunAt = nil
+		_ = wp.repo.Update(ctx, j)
+		return
+	}
+	if j.Status == StatusPending && j.NextRunAt != nil {
+		slog.Warn("worker: job will be retried", "worker", id, "job", j.ID,
+			"attempt", j.Attempts, "max_attempts", j.MaxAttempts, "next_run_at", j.NextRunAt, "error", err)
+		return
+	}
+	slog.Error("worker: process job", "worker", id, "job", j.ID, "error", err)
 }
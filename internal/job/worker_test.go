@@ -2,6 +2,7 @@ package job
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -54,6 +55,71 @@ func TestWorkerPool_ProcessesPendingJobs(t *testing.T) {
 	<-done
 }
 
+// concurrencyProcessor records the most jobs of each source it processed at
+// once.
+type concurrencyProcessor struct {
+	mu        sync.Mutex
+	running   map[string]int
+	max       map[string]int
+	processed atomic.Int64
+}
+
+func (c *concurrencyProcessor) Process(_ context.Context, j *Job) error {
+	c.mu.Lock()
+	c.running[j.Source]++
+	c.max[j.Source] = max(c.max[j.Source], c.running[j.Source])
+	c.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running[j.Source]--
+	c.mu.Unlock()
+	c.processed.Add(1)
+	return nil
+}
+
+func TestWorkerPool_SourceLimit(t *testing.T) {
+	repo := newMockRepo()
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		_ = repo.Create(ctx, &Job{Source: "tefas", Symbol: "YAC", Status: StatusPending})
+	}
+	_ = repo.Create(ctx, &Job{Source: "yahoo", Symbol: "AAPL", Status: StatusPending})
+
+	proc := &concurrencyProcessor{running: make(map[string]int), max: make(map[string]int)}
+	pool := NewWorkerPool(repo, proc, 3, WithSourceLimit("tefas", 1))
+	pool.pollInterval = 10 * time.Second // only Notify wakes idle workers
+
+	poolCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		pool.Run(poolCtx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	pool.Notify()
+
+	deadline := time.After(2 * time.Second)
+	for proc.processed.Load() < 5 {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for jobs to be processed, got %d", proc.processed.Load())
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	proc.mu.Lock()
+	defer proc.mu.Unlock()
+	if proc.max["tefas"] != 1 {
+		t.Errorf("expected at most 1 tefas job at once, got %d", proc.max["tefas"])
+	}
+}
+
 func TestWorkerPool_NotifyWakesWorker(t *testing.T) {
 	repo := newMockRepo()
 	proc := &mockProcessor{}
@@ -107,3 +173,162 @@ func TestWorkerPool_GracefulShutdown(t *testing.T) {
 		t.Fatal("timed out waiting for graceful shutdown")
 	}
 }
+
+func TestRouter_DispatchesByKind(t *testing.T) {
+	repo := newMockRepo()
+	ctx := context.Background()
+
+	prices := &mockProcessor{}
+	allocation := &mockProcessor{}
+	r := NewRouter(repo)
+	r.Handle(KindPrices, prices)
+	r.Handle(KindAllocation, allocation)
+
+	if err := r.Process(ctx, &Job{Kind: KindAllocation}); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if prices.processed.Load() != 0 || allocation.processed.Load() != 1 {
+		t.Errorf("processed prices=%d allocation=%d, want 0 and 1",
+			prices.processed.Load(), allocation.processed.Load())
+	}
+
+	j := &Job{Kind: "unknown", Status: StatusRunning}
+	_ = repo.Create(ctx, j)
+	if err := r.Process(ctx, j); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != StatusFailed {
+		t.Errorf("status = %q, want %q", got.Status, StatusFailed)
+	}
+}
+
+// blockingProcessor runs until its context is cancelled, then fails the job
+// the way a processor interrupted mid-scrape would.
+type blockingProcessor struct {
+	repo    Repository
+	started chan int64
+}
+
+func (b *blockingProcessor) Process(ctx context.Context, j *Job) error {
+	b.started <- j.ID
+	<-ctx.Done()
+	j.Status = StatusFailed
+	j.Error = ctx.Err().Error()
+	_ = b.repo.Update(context.Background(), j)
+	return ctx.Err()
+}
+
+func TestWorkerPool_CancelRunningJob(t *testing.T) {
+	repo := newMockRepo()
+	proc := &blockingProcessor{repo: repo, started: make(chan int64, 1)}
+	pool := NewWorkerPool(repo, proc, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	_ = repo.Create(ctx, &Job{Source: "tefas", Symbol: "YAC", Status: StatusPending})
+	pool.Notify()
+
+	var id int64
+	select {
+	case id = <-proc.started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for job to start")
+	}
+
+	if pool.Cancel(id + 1) {
+		t.Error("expected Cancel to report false for a job that is not running")
+	}
+	if !pool.Running(id) {
+		t.Error("expected the job to be running")
+	}
+	if !pool.Cancel(id) {
+		t.Fatal("expected Cancel to stop the running job")
+	}
+
+	deadline := time.After(2 * time.Second)
+	for {
+		got, _ := repo.Get(ctx, id)
+		if got.Status == StatusCancelled {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("status = %q, want %q", got.Status, StatusCancelled)
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// ctxProcessor completes the job unless its context is already cancelled.
+type ctxProcessor struct {
+	repo Repository
+}
+
+func (c *ctxProcessor) Process(ctx context.Context, j *Job) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	j.Status = StatusCompleted
+	return c.repo.Update(ctx, j)
+}
+
+func TestWorkerPool_CancelClaimedJob(t *testing.T) {
+	repo := newMockRepo()
+	pool := NewWorkerPool(repo, &ctxProcessor{repo: repo}, 1)
+	ctx := context.Background()
+	_ = repo.Create(ctx, &Job{Source: "tefas", Symbol: "YAC", Status: StatusPending})
+
+	// Cancelled between the claim and the start of processing.
+	j, err := pool.claim(ctx)
+	if err != nil || j == nil {
+		t.Fatalf("claim: %v, %v", j, err)
+	}
+	if ok, _ := repo.Cancel(ctx, j.ID); !ok {
+		t.Fatal("expected the repository to cancel the claimed job")
+	}
+	if !pool.Cancel(j.ID) {
+		t.Fatal("expected Cancel to find the claimed job")
+	}
+	pool.process(ctx, 0, j)
+
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != StatusCancelled {
+		t.Errorf("status = %q, want %q", got.Status, StatusCancelled)
+	}
+	if pool.Cancel(j.ID) || pool.Running(j.ID) {
+		t.Error("expected the job to be released once it is done")
+	}
+}
+
+func TestWorkerPool_CancelledStatusKept(t *testing.T) {
+	repo := newMockRepo()
+	pool := NewWorkerPool(repo, &ctxProcessor{repo: repo}, 1)
+	ctx := context.Background()
+	_ = repo.Create(ctx, &Job{Source: "tefas", Symbol: "YAC", Status: StatusPending})
+
+	// The pool never hears of the cancel, as when it lands after the
+	// processor's last context check: the job completes, but its final
+	// update leaves the cancel in place.
+	j, err := pool.claim(ctx)
+	if err != nil || j == nil {
+		t.Fatalf("claim: %v, %v", j, err)
+	}
+	_, _ = repo.Cancel(ctx, j.ID)
+	pool.process(ctx, 0, j)
+
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != StatusCancelled {
+		t.Errorf("status = %q, want %q", got.Status, StatusCancelled)
+	}
+}
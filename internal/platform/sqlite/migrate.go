@@ -0,0 +1,221 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSchemaTooNew is returned when the database has migrations applied that
+// this binary does not know about, i.e. it was migrated by a newer release.
+var ErrSchemaTooNew = errors.New("database schema is newer than this binary")
+
+// Migration is a single embedded schema change. Files are named
+// NNN_description.sql; NNN is the version.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus describes a migration known to the binary, the database,
+// or both.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Known     bool // false when the DB has a version this binary doesn't ship
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    INTEGER PRIMARY KEY,
+    name       TEXT NOT NULL,
+    applied_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+)`
+
+// loadMigrations reads and orders all *.sql files under dir in fsys.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	names, err := fs.Glob(fsys, path.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Migration, 0, len(names))
+	seen := make(map[int]string, len(names))
+	for _, name := range names {
+		base := path.Base(name)
+		prefix, _, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNN_name.sql", base)
+		}
+		v, err := strconv.Atoi(prefix)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", base, prefix)
+		}
+		if prev, dup := seen[v]; dup {
+			return nil, fmt.Errorf("migration %s: version %d already used by %s", base, v, prev)
+		}
+		seen[v] = base
+
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, Migration{Version: v, Name: strings.TrimSuffix(base, ".sql"), SQL: string(b)})
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+// Migrate applies all pending embedded migrations.
+func (db *DB) Migrate(ctx context.Context) error {
+	list, err := loadMigrations(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	return applyMigrations(ctx, db.DB, list)
+}
+
+// MigrationStatus reports every migration known to the binary or recorded in
+// the database, ordered by version. It does not modify the database.
+func (db *DB) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	list, err := loadMigrations(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatus(ctx, db.DB, list)
+}
+
+func applyMigrations(ctx context.Context, db *sql.DB, list []Migration) error {
+	if err := bootstrapMigrations(ctx, db); err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	latest := 0
+	if len(list) > 0 {
+		latest = list[len(list)-1].Version
+	}
+	for v := range applied {
+		if v > latest {
+			return fmt.Errorf("%w: database at version %d, binary supports up to %d", ErrSchemaTooNew, v, latest)
+		}
+	}
+
+	for _, m := range list {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := applyMigration(ctx, db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration and records it in the same transaction so
+// a failed step leaves neither schema changes nor a version row behind.
+func applyMigration(ctx context.Context, db *sql.DB, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%s: begin tx: %w", m.Name, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, m.SQL); err != nil {
+		return fmt.Errorf("%s: %w", m.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name,
+	); err != nil {
+		return fmt.Errorf("%s: record version: %w", m.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("%s: commit: %w", m.Name, err)
+	}
+	return nil
+}
+
+// bootstrapMigrations creates the schema_migrations table.
+func bootstrapMigrations(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+func hasMigrationsTable(ctx context.Context, db *sql.DB) (bool, error) {
+	var n int
+	if err := db.QueryRowContext(ctx,
+		`SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	).Scan(&n); err != nil {
+		return false, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	return n > 0, nil
+}
+
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]MigrationStatus, error) {
+	rows, err := db.QueryContext(ctx, `SELECT version, name, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int]MigrationStatus)
+	for rows.Next() {
+		var st MigrationStatus
+		var appliedStr string
+		if err := rows.Scan(&st.Version, &st.Name, &appliedStr); err != nil {
+			return nil, fmt.Errorf("scan migration: %w", err)
+		}
+		st.Applied = true
+		st.AppliedAt, _ = time.Parse(time.RFC3339, appliedStr)
+		applied[st.Version] = st
+	}
+	return applied, rows.Err()
+}
+
+func migrationStatus(ctx context.Context, db *sql.DB, list []Migration) ([]MigrationStatus, error) {
+	exists, err := hasMigrationsTable(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[int]MigrationStatus{}
+	if exists {
+		if applied, err = appliedMigrations(ctx, db); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]MigrationStatus, 0, len(list)+len(applied))
+	for _, m := range list {
+		st, ok := applied[m.Version]
+		if !ok {
+			st = MigrationStatus{Version: m.Version}
+		}
+		st.Name = m.Name
+		st.Known = true
+		result = append(result, st)
+		delete(applied, m.Version)
+	}
+	for _, st := range applied {
+		result = append(result, st)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+	return result, nil
+}
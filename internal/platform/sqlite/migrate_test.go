@@ -0,0 +1,184 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func openRaw(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "test.db"), WithoutMigrations())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func testMigrations(t *testing.T, files fstest.MapFS) []Migration {
+	t.Helper()
+	list, err := loadMigrations(files, "m")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	return list
+}
+
+func TestOpen_AppliesEmbeddedMigrations(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	status, err := db.MigrationStatus(context.Background())
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(status) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	for _, st := range status {
+		if !st.Applied || !st.Known {
+			t.Errorf("migration %03d %s: applied=%v known=%v", st.Version, st.Name, st.Applied, st.Known)
+		}
+	}
+}
+
+func TestApplyMigrations_Ordered_And_Idempotent(t *testing.T) {
+	db := openRaw(t)
+	ctx := context.Background()
+
+	list := testMigrations(t, fstest.MapFS{
+		"m/002_add.sql":    {Data: []byte("ALTER TABLE t ADD COLUMN b TEXT;")},
+		"m/001_create.sql": {Data: []byte("CREATE TABLE t (a TEXT);")},
+	})
+	if list[0].Version != 1 || list[1].Version != 2 {
+		t.Fatalf("expected ordered versions, got %d, %d", list[0].Version, list[1].Version)
+	}
+
+	if err := applyMigrations(ctx, db.DB, list); err != nil {
+		t.Fatalf("first apply: %v", err)
+	}
+	// ALTER TABLE would fail if re-run.
+	if err := applyMigrations(ctx, db.DB, list); err != nil {
+		t.Fatalf("second apply: %v", err)
+	}
+}
+
+func TestApplyMigrations_FailedStepRollsBack(t *testing.T) {
+	db := openRaw(t)
+	ctx := context.Background()
+
+	list := testMigrations(t, fstest.MapFS{
+		"m/001_create.sql": {Data: []byte("CREATE TABLE t (a TEXT);")},
+		"m/002_broken.sql": {Data: []byte("CREATE TABLE u (a TEXT); NOT VALID SQL;")},
+	})
+
+	if err := applyMigrations(ctx, db.DB, list); err == nil {
+		t.Fatal("expected error from broken migration")
+	}
+
+	status, err := migrationStatus(ctx, db.DB, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status[0].Applied || status[1].Applied {
+		t.Errorf("expected only 001 applied, got %+v", status)
+	}
+
+	var n int
+	_ = db.QueryRow(`SELECT count(*) FROM sqlite_master WHERE name = 'u'`).Scan(&n)
+	if n != 0 {
+		t.Error("expected table from failed migration to be rolled back")
+	}
+}
+
+func TestApplyMigrations_RefusesNewerSchema(t *testing.T) {
+	db := openRaw(t)
+	ctx := context.Background()
+
+	newer := testMigrations(t, fstest.MapFS{
+		"m/001_create.sql": {Data: []byte("CREATE TABLE t (a TEXT);")},
+		"m/002_add.sql":    {Data: []byte("ALTER TABLE t ADD COLUMN b TEXT;")},
+	})
+	if err := applyMigrations(ctx, db.DB, newer); err != nil {
+		t.Fatal(err)
+	}
+
+	err := applyMigrations(ctx, db.DB, newer[:1])
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+
+	status, err := migrationStatus(ctx, db.DB, newer[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 2 || status[1].Known {
+		t.Errorf("expected unknown version 2 in status, got %+v", status)
+	}
+}
+
+func TestLoadMigrations_InvalidName(t *testing.T) {
+	_, err := loadMigrations(fstest.MapFS{"m/initial.sql": {Data: []byte("")}}, "m")
+	if err == nil {
+		t.Fatal("expected error for file without version prefix")
+	}
+}
+
+func TestDecimalValuesMigration_KeepsRows(t *testing.T) {
+	db := openRaw(t)
+	ctx := context.Background()
+
+	list, err := loadMigrations(migrations, "migrations")
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	before := slices.IndexFunc(list, func(m Migration) bool { return m.Name == "014_decimal_values" })
+	if before < 0 {
+		t.Fatal("decimal_values migration not found")
+	}
+	if err := applyMigrations(ctx, db.DB, list[:before]); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO prices (source, symbol, date, close_price, volume, currency)
+		VALUES ('tefas', 'YAC', '2024-01-02', 0.367598, NULL, 'TRY'), ('yahoo', 'AAPL', '2024-01-02', 185.64, 82488700, 'USD')`); err != nil {
+		t.Fatalf("insert prices: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `INSERT INTO exchange_rates (source, pair, date, rate, bid, ask)
+		VALUES ('tcmb', 'USDTRY', '2024-01-02', 35.2802, 35.2802, 35.3438)`); err != nil {
+		t.Fatalf("insert rate: %v", err)
+	}
+
+	if err := applyMigrations(ctx, db.DB, list); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	var closes []string
+	rows, err := db.QueryContext(ctx, "SELECT close_price || ' ' || COALESCE(volume, '-') FROM prices ORDER BY symbol DESC")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		closes = append(closes, s)
+	}
+	if want := []string{"0.367598 -", "185.64 82488700.0"}; !slices.Equal(closes, want) {
+		t.Errorf("prices = %q, want %q", closes, want)
+	}
+	var rate, ask string
+	if err := db.QueryRowContext(ctx, "SELECT rate, ask FROM exchange_rates").Scan(&rate, &ask); err != nil {
+		t.Fatalf("query rate: %v", err)
+	}
+	if rate != "35.2802" || ask != "35.3438" {
+		t.Errorf("rate = %s, ask = %s", rate, ask)
+	}
+}
@@ -0,0 +1,6 @@
+-- Full daily bars. Sources that only publish a single price per day (TEFAS)
+-- leave these NULL; close_price remains the canonical value.
+ALTER TABLE prices ADD COLUMN open_price REAL;
+ALTER TABLE prices ADD COLUMN high_price REAL;
+ALTER TABLE prices ADD COLUMN low_price  REAL;
+ALTER TABLE prices ADD COLUMN volume     REAL;
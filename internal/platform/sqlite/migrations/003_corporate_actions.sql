@@ -0,0 +1,17 @@
+-- Split- and dividend-adjusted close as reported by the source.
+ALTER TABLE prices ADD COLUMN adj_close REAL;
+
+CREATE TABLE corporate_actions (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    source      TEXT NOT NULL,
+    symbol      TEXT NOT NULL,
+    date        TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    amount      REAL,
+    numerator   REAL,
+    denominator REAL,
+    currency    TEXT NOT NULL,
+    created_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(source, symbol, date, type)
+);
+CREATE INDEX idx_corporate_actions_lookup ON corporate_actions (source, symbol, date);
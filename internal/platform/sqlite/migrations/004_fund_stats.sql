@@ -0,0 +1,19 @@
+CREATE TABLE fund_stats (
+    id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+    code               TEXT    NOT NULL,
+    date               TEXT    NOT NULL,
+    shares_outstanding REAL    NOT NULL,
+    investors          INTEGER NOT NULL,
+    total_value        REAL    NOT NULL,
+    created_at         TEXT    NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(code, date)
+);
+CREATE INDEX idx_fund_stats_lookup ON fund_stats (code, date);
+
+CREATE TABLE symbols (
+    source     TEXT NOT NULL,
+    symbol     TEXT NOT NULL,
+    name       TEXT NOT NULL,
+    updated_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    PRIMARY KEY (source, symbol)
+);
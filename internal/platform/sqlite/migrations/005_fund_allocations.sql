@@ -0,0 +1,16 @@
+ALTER TABLE jobs ADD COLUMN kind TEXT NOT NULL DEFAULT 'prices';
+DROP INDEX IF EXISTS idx_jobs_lookup;
+CREATE INDEX idx_jobs_lookup ON jobs (kind, source, symbol, status);
+
+-- One row per fund, day and asset class; weight is a percentage of the
+-- portfolio. Asset keys are TEFAS column codes (HS, DT, R, ...).
+CREATE TABLE fund_allocations (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    code       TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    asset      TEXT NOT NULL,
+    weight     REAL NOT NULL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(code, date, asset)
+);
+CREATE INDEX idx_fund_allocations_lookup ON fund_allocations (code, date);
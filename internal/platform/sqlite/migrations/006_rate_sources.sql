@@ -0,0 +1,19 @@
+-- Rates are now kept per provider (yahoo, tcmb), and providers that publish
+-- buying/selling rates store them as bid/ask. SQLite cannot change a UNIQUE
+-- constraint in place, so the table is rebuilt; existing rows came from Yahoo.
+CREATE TABLE exchange_rates_new (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    source     TEXT NOT NULL DEFAULT 'yahoo',
+    pair       TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    rate       REAL NOT NULL,
+    bid        REAL,
+    ask        REAL,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(source, pair, date)
+);
+INSERT INTO exchange_rates_new (id, pair, date, rate, created_at)
+    SELECT id, pair, date, rate, created_at FROM exchange_rates;
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_new RENAME TO exchange_rates;
+CREATE INDEX idx_rates_lookup ON exchange_rates (source, pair, date);
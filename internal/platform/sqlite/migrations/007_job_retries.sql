@@ -0,0 +1,5 @@
+-- Failed attempts with a retryable error are requeued until max_attempts is
+-- reached; next_run_at holds the job back until its backoff has elapsed.
+ALTER TABLE jobs ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE jobs ADD COLUMN max_attempts INTEGER NOT NULL DEFAULT 3;
+ALTER TABLE jobs ADD COLUMN next_run_at TEXT;
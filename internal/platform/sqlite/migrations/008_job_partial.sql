@@ -0,0 +1,4 @@
+-- failed_ranges is a JSON array of the sub-ranges a partial job could not
+-- fetch; each is queued again as a follow-up job pointing at its parent.
+ALTER TABLE jobs ADD COLUMN failed_ranges TEXT;
+ALTER TABLE jobs ADD COLUMN parent_id INTEGER REFERENCES jobs(id);
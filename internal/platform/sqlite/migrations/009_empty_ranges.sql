@@ -0,0 +1,13 @@
+-- empty_ranges records trading days a source was asked for and returned no
+-- prices, e.g. before a fund's launch, so that coverage checks stop queueing
+-- them. Ranges of recent dates expire (expires_at) because the source may
+-- still publish them late; NULL never expires.
+CREATE TABLE empty_ranges (
+    source     TEXT NOT NULL,
+    symbol     TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    end_date   TEXT NOT NULL,
+    checked_at TEXT NOT NULL,
+    expires_at TEXT,
+    PRIMARY KEY (source, symbol, start_date)
+);
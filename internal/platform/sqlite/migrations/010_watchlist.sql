@@ -0,0 +1,8 @@
+CREATE TABLE watchlist (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    source       TEXT NOT NULL,
+    symbol       TEXT NOT NULL,
+    created_at   TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    refreshed_at TEXT,
+    UNIQUE(source, symbol)
+);
@@ -0,0 +1,25 @@
+-- response_bodies holds the gzip-compressed upstream response bodies,
+-- addressed by the SHA-256 of the uncompressed body so that a response
+-- fetched again unchanged is stored once.
+CREATE TABLE response_bodies (
+    hash TEXT PRIMARY KEY,
+    size INTEGER NOT NULL,
+    body BLOB NOT NULL
+);
+
+-- responses records every archived upstream fetch: what was asked for, when,
+-- and which body came back.
+CREATE TABLE responses (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    source       TEXT NOT NULL,
+    symbol       TEXT NOT NULL,
+    dataset      TEXT NOT NULL,
+    method       TEXT NOT NULL,
+    url          TEXT NOT NULL,
+    request_body BLOB,
+    status       INTEGER NOT NULL,
+    body_hash    TEXT NOT NULL REFERENCES response_bodies(hash),
+    fetched_at   TEXT NOT NULL
+);
+
+CREATE INDEX idx_responses_symbol ON responses(source, symbol, dataset, fetched_at);
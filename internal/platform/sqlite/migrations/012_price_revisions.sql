@@ -0,0 +1,15 @@
+-- price_revisions records every stored price value that a later fetch
+-- overwrote, one row per changed field, with the job that changed it.
+CREATE TABLE price_revisions (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    source     TEXT NOT NULL,
+    symbol     TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    field      TEXT NOT NULL,
+    old_value  REAL,
+    new_value  REAL,
+    job_id     INTEGER,
+    revised_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+
+CREATE INDEX idx_price_revisions_symbol ON price_revisions(source, symbol, date);
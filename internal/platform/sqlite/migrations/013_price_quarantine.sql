@@ -0,0 +1,18 @@
+-- quarantined_prices holds scraped prices that failed a quality check and
+-- were not stored in prices. flags is a JSON array of the failed checks. A
+-- point is removed once a later fetch of its date passes.
+CREATE TABLE quarantined_prices (
+    source      TEXT NOT NULL,
+    symbol      TEXT NOT NULL,
+    date        TEXT NOT NULL,
+    open_price  REAL,
+    high_price  REAL,
+    low_price   REAL,
+    close_price REAL,
+    adj_close   REAL,
+    volume      REAL,
+    flags       TEXT NOT NULL,
+    job_id      INTEGER,
+    flagged_at  TEXT NOT NULL,
+    PRIMARY KEY (source, symbol, date)
+);
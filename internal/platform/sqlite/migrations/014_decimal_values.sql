@@ -0,0 +1,45 @@
+-- Prices and rates are stored as decimal text, exactly as the source
+-- published them, instead of binary floats. SQLite cannot change a column's
+-- type in place, so both tables are rebuilt. Existing REAL values are
+-- written with 15 significant digits, which every source publishes within.
+CREATE TABLE prices_new (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    source      TEXT NOT NULL,
+    symbol      TEXT NOT NULL,
+    date        TEXT NOT NULL,
+    open_price  TEXT,
+    high_price  TEXT,
+    low_price   TEXT,
+    close_price TEXT NOT NULL,
+    adj_close   TEXT,
+    volume      TEXT,
+    currency    TEXT NOT NULL,
+    created_at  TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(source, symbol, date)
+);
+INSERT INTO prices_new (id, source, symbol, date, open_price, high_price, low_price, close_price, adj_close,
+                        volume, currency, created_at)
+    SELECT id, source, symbol, date, CAST(open_price AS TEXT), CAST(high_price AS TEXT), CAST(low_price AS TEXT),
+           CAST(close_price AS TEXT), CAST(adj_close AS TEXT), CAST(volume AS TEXT), currency, created_at
+    FROM prices;
+DROP TABLE prices;
+ALTER TABLE prices_new RENAME TO prices;
+CREATE INDEX idx_prices_lookup ON prices (source, symbol, date);
+
+CREATE TABLE exchange_rates_new (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    source     TEXT NOT NULL DEFAULT 'yahoo',
+    pair       TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    rate       TEXT NOT NULL,
+    bid        TEXT,
+    ask        TEXT,
+    created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now')),
+    UNIQUE(source, pair, date)
+);
+INSERT INTO exchange_rates_new (id, source, pair, date, rate, bid, ask, created_at)
+    SELECT id, source, pair, date, CAST(rate AS TEXT), CAST(bid AS TEXT), CAST(ask AS TEXT), created_at
+    FROM exchange_rates;
+DROP TABLE exchange_rates;
+ALTER TABLE exchange_rates_new RENAME TO exchange_rates;
+CREATE INDEX idx_rates_lookup ON exchange_rates (source, pair, date);
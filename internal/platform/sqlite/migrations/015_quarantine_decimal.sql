@@ -0,0 +1,26 @@
+-- Quarantined prices are stored as decimal text like prices, so that a point
+-- confirmed later is stored with the decimals the source published. fetches
+-- counts the fetches in a row that returned the same flagged close.
+CREATE TABLE quarantined_prices_new (
+    source      TEXT NOT NULL,
+    symbol      TEXT NOT NULL,
+    date        TEXT NOT NULL,
+    open_price  TEXT,
+    high_price  TEXT,
+    low_price   TEXT,
+    close_price TEXT,
+    adj_close   TEXT,
+    volume      TEXT,
+    flags       TEXT NOT NULL,
+    job_id      INTEGER,
+    flagged_at  TEXT NOT NULL,
+    fetches     INTEGER NOT NULL DEFAULT 1,
+    PRIMARY KEY (source, symbol, date)
+);
+INSERT INTO quarantined_prices_new (source, symbol, date, open_price, high_price, low_price, close_price, adj_close,
+                                    volume, flags, job_id, flagged_at)
+    SELECT source, symbol, date, CAST(open_price AS TEXT), CAST(high_price AS TEXT), CAST(low_price AS TEXT),
+           CAST(close_price AS TEXT), CAST(adj_close AS TEXT), CAST(volume AS TEXT), flags, job_id, flagged_at
+    FROM quarantined_prices;
+DROP TABLE quarantined_prices;
+ALTER TABLE quarantined_prices_new RENAME TO quarantined_prices;
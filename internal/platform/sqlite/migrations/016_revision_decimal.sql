@@ -0,0 +1,19 @@
+-- Revision values are stored as decimal text like the prices they replace,
+-- so a revision shows the stored value exactly.
+CREATE TABLE price_revisions_new (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    source     TEXT NOT NULL,
+    symbol     TEXT NOT NULL,
+    date       TEXT NOT NULL,
+    field      TEXT NOT NULL,
+    old_value  TEXT,
+    new_value  TEXT,
+    job_id     INTEGER,
+    revised_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))
+);
+INSERT INTO price_revisions_new (id, source, symbol, date, field, old_value, new_value, job_id, revised_at)
+    SELECT id, source, symbol, date, field, CAST(old_value AS TEXT), CAST(new_value AS TEXT), job_id, revised_at
+    FROM price_revisions;
+DROP TABLE price_revisions;
+ALTER TABLE price_revisions_new RENAME TO price_revisions;
+CREATE INDEX idx_price_revisions_symbol ON price_revisions(source, symbol, date);
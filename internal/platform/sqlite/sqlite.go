@@ -1,21 +1,42 @@
 package sqlite
 
 import (
+	"context"
 	"database/sql"
-	_ "embed"
+	"embed"
 	"fmt"
 
 	_ "modernc.org/sqlite" // Register sqlite driver
 )
 
-//go:embed migrations/001_initial.sql
-var migration string
+//go:embed migrations/*.sql
+var migrations embed.FS
 
 type DB struct {
 	*sql.DB
 }
 
-func Open(dsn string) (*DB, error) {
+type options struct {
+	skipMigrations bool
+}
+
+// Option configures Open.
+type Option func(*options)
+
+// WithoutMigrations opens the database as-is. Used by the migrate command to
+// report status without changing the schema.
+func WithoutMigrations() Option {
+	return func(o *options) { o.skipMigrations = true }
+}
+
+// Open opens the database and applies pending migrations. It fails with
+// ErrSchemaTooNew if the database was migrated by a newer binary.
+func Open(dsn string, opts ...Option) (*DB, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	db, err := sql.Open("sqlite", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
@@ -40,15 +61,15 @@ func Open(dsn string) (*DB, error) {
 		}
 	}
 
-	if err := migrate(db); err != nil {
+	d := &DB{db}
+	if o.skipMigrations {
+		return d, nil
+	}
+
+	if err := d.Migrate(context.Background()); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("migrate: %w", err)
 	}
 
-	return &DB{db}, nil
-}
-
-func migrate(db *sql.DB) error {
-	_, err := db.Exec(migration)
-	return err
+	return d, nil
 }
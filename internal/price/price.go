@@ -1,6 +1,11 @@
 package price
 
-import "time"
+import (
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
 
 type Source string
 
@@ -15,6 +20,11 @@ type Currency string
 const (
 	CurrencyTRY Currency = "TRY"
 	CurrencyUSD Currency = "USD"
+	CurrencyEUR Currency = "EUR"
+	CurrencyGBP Currency = "GBP"
+	CurrencyCHF Currency = "CHF"
+	CurrencyJPY Currency = "JPY"
+	CurrencyXAU Currency = "XAU" // troy ounce of gold
 )
 
 type Price struct {
@@ -22,7 +32,116 @@ type Price struct {
 	Source     Source    `json:"source"`
 	Symbol     string    `json:"symbol"`
 	Date       time.Time `json:"date"`
+	Open       float64   `json:"open,omitempty"`
+	High       float64   `json:"high,omitempty"`
+	Low        float64   `json:"low,omitempty"`
 	ClosePrice float64   `json:"closePrice"`
+	AdjClose   float64   `json:"adjClose,omitempty"`
+	Volume     float64   `json:"volume,omitempty"`
 	Currency   Currency  `json:"currency"`
 	CreatedAt  time.Time `json:"createdAt"`
+	// Reported lists the optional values the source published; the others
+	// are zero and stored as missing.
+	Reported scraper.Field `json:"-"`
+	// Exact holds the values as stored, without float rounding. Scraped
+	// prices without published decimals leave it nil.
+	Exact *scraper.Exact `json:"-"`
+}
+
+// Decimals returns the values of p as exact decimals: Exact when set, or
+// the shortest decimal of each float.
+func (p Price) Decimals() scraper.Exact {
+	if p.Exact != nil {
+		return *p.Exact
+	}
+	return scraper.Exact{
+		Open:       decimal.FromFloat(p.Open),
+		High:       decimal.FromFloat(p.High),
+		Low:        decimal.FromFloat(p.Low),
+		ClosePrice: decimal.FromFloat(p.ClosePrice),
+		AdjClose:   decimal.FromFloat(p.AdjClose),
+		Volume:     decimal.FromFloat(p.Volume),
+	}
+}
+
+// Revision is a stored price value that a later fetch changed. Field is the
+// JSON name of the value; a zero value was either zero or missing. Values
+// are the stored decimals.
+type Revision struct {
+	ID        int64           `json:"id"`
+	Source    Source          `json:"source"`
+	Symbol    string          `json:"symbol"`
+	Date      time.Time       `json:"date"`
+	Field     string          `json:"field"`
+	OldValue  decimal.Decimal `json:"oldValue"`
+	NewValue  decimal.Decimal `json:"newValue"`
+	JobID     int64           `json:"jobId,omitempty"`
+	RevisedAt time.Time       `json:"revisedAt"`
+}
+
+// Revisions lists the values of old that next changes, attributed to jobID.
+func Revisions(old, next Price, jobID int64) []Revision {
+	o, n := old.Decimals(), next.Decimals()
+	fields := []struct {
+		name      string
+		old, next decimal.Decimal
+		optional  scraper.Field
+	}{
+		{"open", o.Open, n.Open, scraper.FieldOpen},
+		{"high", o.High, n.High, scraper.FieldHigh},
+		{"low", o.Low, n.Low, scraper.FieldLow},
+		{"closePrice", o.ClosePrice, n.ClosePrice, 0},
+		{"adjClose", o.AdjClose, n.AdjClose, scraper.FieldAdjClose},
+		{"volume", o.Volume, n.Volume, scraper.FieldVolume},
+	}
+	var revs []Revision
+	for _, f := range fields {
+		if f.old.Equal(f.next) && old.Reported.Has(f.optional) == next.Reported.Has(f.optional) {
+			continue
+		}
+		revs = append(revs, Revision{
+			Source:   next.Source,
+			Symbol:   next.Symbol,
+			Date:     next.Date,
+			Field:    f.name,
+			OldValue: f.old,
+			NewValue: f.next,
+			JobID:    jobID,
+		})
+	}
+	return revs
+}
+
+type ActionType string
+
+const (
+	ActionDividend ActionType = "dividend"
+	ActionSplit    ActionType = "split"
+)
+
+// CorporateAction is a dividend or split. Amount is the cash dividend per
+// share in Currency; splits are Numerator:Denominator.
+type CorporateAction struct {
+	ID          int64      `json:"id"`
+	Source      Source     `json:"source"`
+	Symbol      string     `json:"symbol"`
+	Date        time.Time  `json:"date"`
+	Type        ActionType `json:"type"`
+	Amount      float64    `json:"amount,omitempty"`
+	Numerator   float64    `json:"numerator,omitempty"`
+	Denominator float64    `json:"denominator,omitempty"`
+	Currency    Currency   `json:"currency"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// EmptyRange is a run of trading days a source was asked for and returned no
+// prices, such as the days before a fund's launch. Ranges of recent dates
+// expire, because the source may still publish them late.
+type EmptyRange struct {
+	Source    Source
+	Symbol    string
+	StartDate time.Time
+	EndDate   time.Time
+	CheckedAt time.Time
+	ExpiresAt *time.Time // nil when the range never expires
 }
@@ -7,6 +7,20 @@ import (
 
 type Repository interface {
 	SavePrices(ctx context.Context, prices []Price) (int64, error)
+	// UpsertPrices saves prices, overwriting stored values of the same dates
+	// and recording each overwritten value as a revision by jobID. It returns
+	// how many prices were inserted or changed.
+	UpsertPrices(ctx context.Context, prices []Price, jobID int64) (int64, error)
+	ListRevisions(ctx context.Context, source Source, symbol string, from, to time.Time) ([]Revision, error)
 	ListPrices(ctx context.Context, source Source, symbol string, from, to time.Time) ([]Price, error)
+	// LatestPrices returns the last n stored prices dated before before,
+	// oldest first.
+	LatestPrices(ctx context.Context, source Source, symbol string, before time.Time, n int) ([]Price, error)
 	ExistingDates(ctx context.Context, source Source, symbol string, from, to time.Time) (map[time.Time]bool, error)
+	SaveActions(ctx context.Context, actions []CorporateAction) (int64, error)
+	ListActions(ctx context.Context, source Source, symbol string, from, to time.Time) ([]CorporateAction, error)
+	SaveEmptyRanges(ctx context.Context, ranges []EmptyRange) error
+	// EmptyRanges returns the empty ranges overlapping [from, to] that have
+	// not expired by now.
+	EmptyRanges(ctx context.Context, source Source, symbol string, from, to, now time.Time) ([]EmptyRange, error)
 }
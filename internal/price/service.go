@@ -2,30 +2,85 @@ package price
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
 	"github.com/ahmethakanbesel/finance-api/internal/rate"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 )
 
 type Service struct {
-	priceRepo Repository
-	jobRepo   job.Repository
-	registry  *scraper.Registry
-	rateSvc   *rate.Service
-	notify    func() // optional: wake worker pool
+	priceRepo     Repository
+	jobRepo       job.Repository
+	registry      *scraper.Registry
+	rateSvc       *rate.Service
+	fundRepo      fund.Repository    // optional: persist TEFAS fund statistics
+	archive       archive.Repository // optional: rebuild prices from archived responses
+	checker       *quality.Checker   // optional: check prices before they are stored
+	quarantine    quality.Repository // with checker: prices that failed a check
+	notify        func()             // optional: wake worker pool
+	emptyTTL      time.Duration      // how long an empty answer for recent dates is trusted
+	quarantineTTL time.Duration      // how long a quarantined day is not fetched again
 }
 
-func NewService(priceRepo Repository, jobRepo job.Repository, registry *scraper.Registry, rateSvc *rate.Service) *Service {
-	return &Service{
-		priceRepo: priceRepo,
-		jobRepo:   jobRepo,
-		registry:  registry,
-		rateSvc:   rateSvc,
+func NewService(priceRepo Repository, jobRepo job.Repository, registry *scraper.Registry, rateSvc *rate.Service, opts ...Option) *Service {
+	s := &Service{
+		priceRepo:     priceRepo,
+		jobRepo:       jobRepo,
+		registry:      registry,
+		emptyTTL:      defaultEmptyTTL,
+		quarantineTTL: defaultQuarantineTTL,
+		rateSvc:       rateSvc,
 	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+type Option func(*Service)
+
+// WithFundRepository stores fund statistics and names reported by scrapers
+// that implement scraper.FundScraper.
+func WithFundRepository(repo fund.Repository) Option {
+	return func(s *Service) { s.fundRepo = repo }
+}
+
+// WithArchive enables reparse jobs, which rebuild prices from the responses
+// kept in repo.
+func WithArchive(repo archive.Repository) Option {
+	return func(s *Service) { s.archive = repo }
+}
+
+// WithQuality checks scraped prices with checker before they are stored.
+// Prices that fail are kept in repo instead, and reported with the prices of
+// their range.
+func WithQuality(checker *quality.Checker, repo quality.Repository) Option {
+	return func(s *Service) { s.checker, s.quarantine = checker, repo }
+}
+
+// WithEmptyTTL sets how long a source's empty answer for recent dates is
+// trusted before they are fetched again. Older empty dates are never
+// refetched.
+func WithEmptyTTL(d time.Duration) Option {
+	return func(s *Service) { s.emptyTTL = d }
+}
+
+// WithQuarantineTTL sets how long a quarantined day counts as fetched. After
+// it passes, the day is fetched and checked again.
+func WithQuarantineTTL(d time.Duration) Option {
+	return func(s *Service) { s.quarantineTTL = d }
 }
 
 // SetNotify sets a callback invoked when a new pending job is created.
@@ -40,6 +95,8 @@ func (s *Service) GetPrices(ctx context.Context, req GetPricesRequest) (*GetPric
 		return nil, err
 	}
 
+	symbol := req.SeriesSymbol()
+
 	endDate := req.EndDate
 	if endDate.IsZero() {
 		endDate = time.Now().Truncate(24 * time.Hour)
@@ -50,71 +107,336 @@ func (s *Service) GetPrices(ctx context.Context, req GetPricesRequest) (*GetPric
 	if err != nil {
 		return nil, err
 	}
-	nativeCurrency := Currency(sc.NativeCurrency(req.Symbol))
+	nativeCurrency := Currency(sc.NativeCurrency(symbol))
 
-	// Check existing dates in DB (no currency filter — prices stored in native currency)
-	existing, err := s.priceRepo.ExistingDates(ctx, req.Source, req.Symbol, req.StartDate, endDate)
+	jobs, err := s.EnsureCoverage(ctx, string(req.Source), symbol, req.StartDate, endDate)
 	if err != nil {
-		return nil, fmt.Errorf("check existing dates: %w", err)
+		return nil, err
 	}
 
-	// Count expected business days (rough heuristic: weekdays)
-	totalDays := countWeekdays(req.StartDate, endDate)
-	coverageRatio := float64(len(existing)) / float64(max(totalDays, 1))
+	// Fetch all prices from DB (native currency)
+	prices, err := s.priceRepo.ListPrices(ctx, req.Source, symbol, req.StartDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("list prices: %w", err)
+	}
 
-	var j *job.Job
+	actions, err := s.priceRepo.ListActions(ctx, req.Source, symbol, req.StartDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("list corporate actions: %w", err)
+	}
 
-	// If we don't have good coverage, queue a scraping job
-	if coverageRatio <= 0.8 || len(existing) == 0 {
-		// Dedup: check if there's already an active job for this range
-		dateFormat := "2006-01-02"
-		active, findErr := s.jobRepo.FindActive(ctx, string(req.Source), req.Symbol,
-			req.StartDate.Format(dateFormat), endDate.Format(dateFormat))
-		if findErr != nil {
-			return nil, fmt.Errorf("find active job: %w", findErr)
+	// Build PricePoints with conversion
+	conv, err := s.conversion(ctx, nativeCurrency, req, endDate)
+	if err != nil {
+		return nil, err
+	}
+	points, err := convertPrices(prices, nativeCurrency, req, conv)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantined, err := s.quarantined(ctx, req.Source, symbol, req.StartDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &GetPricesResponse{Prices: points, Actions: actions, Quarantined: quarantined}
+	resp.setJobs(jobs)
+	return resp, nil
+}
+
+// GetRevisions returns the changes made to stored prices of a symbol, by
+// price date and then in the order they were made.
+func (s *Service) GetRevisions(ctx context.Context, req GetRevisionsRequest) (*GetRevisionsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.registry.Get(string(req.Source)); err != nil {
+		return nil, apperror.New(apperror.BadRequest, err.Error())
+	}
+
+	endDate := req.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	symbol := req.SeriesSymbol()
+	revs, err := s.priceRepo.ListRevisions(ctx, req.Source, symbol, req.StartDate, endDate)
+	if err != nil {
+		return nil, fmt.Errorf("list price revisions: %w", err)
+	}
+	if revs == nil {
+		revs = []Revision{}
+	}
+	return &GetRevisionsResponse{Source: req.Source, Symbol: symbol, Revisions: revs}, nil
+}
+
+// GetPricesBatch returns the prices of several symbols over a shared range.
+// Missing jobs are queued together in one transaction, and each exchange
+// rate conversion is loaded once for all symbols that need it.
+func (s *Service) GetPricesBatch(ctx context.Context, req BatchPricesRequest) (*BatchPricesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	endDate := req.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	items := make([]GetPricesRequest, len(req.Items))
+	natives := make([]Currency, len(req.Items))
+	results := make([]*SymbolPrices, len(req.Items))
+	var pending []*job.Job
+	for i := range req.Items {
+		items[i] = req.item(i)
+		symbol := items[i].SeriesSymbol()
+
+		sc, err := s.registry.Get(string(items[i].Source))
+		if err != nil {
+			return nil, apperror.New(apperror.BadRequest, fmt.Sprintf("items[%d]: %s", i, err))
 		}
+		natives[i] = Currency(sc.NativeCurrency(symbol))
 
-		if active != nil {
-			j = active
-		} else {
-			// Create pending job for the worker pool to pick up
-			j = &job.Job{
-				Source:    string(req.Source),
-				Symbol:    req.Symbol,
-				StartDate: req.StartDate,
-				EndDate:   endDate,
-				Status:    job.StatusPending,
+		jobs, create, err := s.coverage(ctx, string(items[i].Source), symbol, req.StartDate, endDate, today)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, create...)
+		results[i] = &SymbolPrices{Source: items[i].Source}
+		results[i].setJobs(jobs)
+	}
+
+	if len(pending) > 0 {
+		if err := s.jobRepo.CreateBatch(ctx, pending); err != nil {
+			return nil, fmt.Errorf("create jobs: %w", err)
+		}
+		if s.notify != nil {
+			s.notify()
+		}
+	}
+
+	resp := &BatchPricesResponse{Symbols: make(map[string]*SymbolPrices, len(items))}
+	conversions := make(map[Currency]*rate.Conversion)
+	for i, item := range items {
+		symbol := item.SeriesSymbol()
+		nativeCurrency := natives[i]
+
+		prices, err := s.priceRepo.ListPrices(ctx, item.Source, symbol, req.StartDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("list prices: %w", err)
+		}
+		actions, err := s.priceRepo.ListActions(ctx, item.Source, symbol, req.StartDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("list corporate actions: %w", err)
+		}
+
+		conv, ok := conversions[nativeCurrency]
+		if !ok && len(prices) > 0 {
+			conv, err = s.conversion(ctx, nativeCurrency, item, endDate)
+			if err != nil {
+				return nil, err
+			}
+			conversions[nativeCurrency] = conv
+		}
+		points, err := convertPrices(prices, nativeCurrency, item, conv)
+		if err != nil {
+			return nil, err
+		}
+
+		quarantined, err := s.quarantined(ctx, item.Source, symbol, req.StartDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+
+		results[i].Prices = points
+		results[i].Actions = actions
+		results[i].Quarantined = quarantined
+		resp.Symbols[symbol] = results[i]
+	}
+
+	return resp, nil
+}
+
+// GetPricesWide is GetPricesBatch pivoted to one row per date. Dates are the
+// union of every symbol's trading days, so sources with different calendars
+// line up; with fill=ffill a symbol's last close is carried over days it did
+// not trade.
+func (s *Service) GetPricesWide(ctx context.Context, req BatchPricesRequest) (*WidePricesResponse, error) {
+	batch, err := s.GetPricesBatch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]string, len(req.Items))
+	for i := range req.Items {
+		symbols[i] = req.item(i).SeriesSymbol()
+	}
+
+	resp := &WidePricesResponse{Currency: req.Currency, Symbols: symbols}
+	closes := make([]map[time.Time]PricePoint, len(symbols))
+	var dates []time.Time
+	seen := make(map[time.Time]bool)
+	for i, sym := range symbols {
+		sp := batch.Symbols[sym]
+		if sp.Job != nil {
+			if resp.Jobs == nil {
+				resp.Jobs = make(map[string]*job.Job)
 			}
-			if createErr := s.jobRepo.Create(ctx, j); createErr != nil {
-				return nil, fmt.Errorf("create job: %w", createErr)
+			resp.Jobs[sym] = sp.Job
+		}
+		closes[i] = make(map[time.Time]PricePoint, len(sp.Prices))
+		for _, p := range sp.Prices {
+			closes[i][p.Date] = p
+			if !seen[p.Date] {
+				seen[p.Date] = true
+				dates = append(dates, p.Date)
+			}
+		}
+	}
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+
+	exact := req.Precision == PrecisionExact
+	last := make([]*PricePoint, len(symbols))
+	resp.Rows = make([]WideRow, len(dates))
+	for d, date := range dates {
+		row := WideRow{Date: date, Values: make([]*float64, len(symbols))}
+		if exact {
+			row.Exact = make([]*decimal.Decimal, len(symbols))
+		}
+		for i, sym := range symbols {
+			p, ok := closes[i][date]
+			if ok {
+				last[i] = &p
+			} else if req.Fill == FillFFill && last[i] != nil {
+				p = *last[i]
+				row.Filled = append(row.Filled, sym)
+			} else {
+				continue
 			}
-			if s.notify != nil {
-				s.notify()
+			row.Values[i] = &p.ClosePrice
+			if exact && p.Exact != nil {
+				row.Exact[i] = &p.Exact.ClosePrice
 			}
 		}
+		resp.Rows[d] = row
 	}
 
-	// Fetch all prices from DB (native currency)
-	prices, err := s.priceRepo.ListPrices(ctx, req.Source, req.Symbol, req.StartDate, endDate)
+	return resp, nil
+}
+
+// EnsureCoverage queues scraping jobs for the parts of the range the stored
+// data does not cover, so that extending a long series only fetches the new
+// days. It returns the pending or running jobs, in date order, and none when
+// no scrape is needed.
+func (s *Service) EnsureCoverage(ctx context.Context, source, symbol string, from, to time.Time) ([]*job.Job, error) {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.ensureCoverage(ctx, source, symbol, from, to, today)
+}
+
+// RefreshCoverage is EnsureCoverage for callers that run after the source
+// has published the day's price, such as the watchlist scheduler: a missing
+// price for today is fetched as well.
+func (s *Service) RefreshCoverage(ctx context.Context, source, symbol string, from, to time.Time) ([]*job.Job, error) {
+	return s.ensureCoverage(ctx, source, symbol, from, to, time.Time{})
+}
+
+// ExistingDates returns the days in the range with a stored price.
+func (s *Service) ExistingDates(ctx context.Context, source, symbol string, from, to time.Time) (map[time.Time]bool, error) {
+	return s.priceRepo.ExistingDates(ctx, Source(source), symbol, from, to)
+}
+
+func (s *Service) ensureCoverage(ctx context.Context, source, symbol string, from, to, today time.Time) ([]*job.Job, error) {
+	jobs, create, err := s.coverage(ctx, source, symbol, from, to, today)
+	if err != nil || len(create) == 0 {
+		return jobs, err
+	}
+
+	if err := s.jobRepo.CreateBatch(ctx, create); err != nil {
+		return nil, fmt.Errorf("create jobs: %w", err)
+	}
+	if s.notify != nil {
+		s.notify()
+	}
+	return jobs, nil
+}
+
+// coverage returns a job for every missing sub-range of the range: the active
+// job already covering it, or a new pending job that the caller must create.
+// create lists the new jobs, which are also part of jobs. A missing price for
+// today alone is not queued; a zero today disables that.
+func (s *Service) coverage(ctx context.Context, source, symbol string, from, to, today time.Time) (jobs, create []*job.Job, err error) {
+	// Check existing dates in DB (no currency filter — prices stored in native currency)
+	existing, err := s.priceRepo.ExistingDates(ctx, Source(source), symbol, from, to)
 	if err != nil {
-		return nil, fmt.Errorf("list prices: %w", err)
+		return nil, nil, fmt.Errorf("check existing dates: %w", err)
 	}
 
-	// Build PricePoints with conversion
-	points, err := s.convertPrices(ctx, prices, nativeCurrency, req.Currency, req.StartDate, endDate)
+	// Days the source already answered without prices count as covered.
+	now := time.Now().UTC()
+	empty, err := s.priceRepo.EmptyRanges(ctx, Source(source), symbol, from, to, now)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("check empty ranges: %w", err)
+	}
+	for _, e := range empty {
+		for d := e.StartDate; !d.After(e.EndDate); d = d.AddDate(0, 0, 1) {
+			existing[d] = true
+		}
+	}
+
+	// Recently quarantined days were fetched; fetching them again right away
+	// would only quarantine them again. Once the quarantine TTL passes they
+	// are fetched again, in case the upstream corrected them, until the
+	// upstream has returned the same close maxQuarantineFetches times.
+	quarantined, err := s.quarantined(ctx, Source(source), symbol, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, p := range quarantined {
+		if now.Sub(p.FlaggedAt) < s.quarantineTTL || p.Fetches >= maxQuarantineFetches {
+			existing[p.Date] = true
+		}
 	}
 
-	return &GetPricesResponse{Prices: points, Job: j}, nil
+	const dateFormat = "2006-01-02"
+	for _, r := range missingRanges(calendar.For(source, symbol), existing, from, to, today) {
+		// Dedup: check if there's already an active job for this range
+		active, err := s.jobRepo.FindActive(ctx, job.KindPrices, source, symbol, r.From.Format(dateFormat), r.To.Format(dateFormat))
+		if err != nil {
+			return nil, nil, fmt.Errorf("find active job: %w", err)
+		}
+		if active != nil {
+			jobs = append(jobs, active)
+			continue
+		}
+
+		// Pending job for the worker pool to pick up
+		j := &job.Job{
+			Kind:      job.KindPrices,
+			Source:    source,
+			Symbol:    symbol,
+			StartDate: r.From,
+			EndDate:   r.To,
+			Status:    job.StatusPending,
+		}
+		jobs = append(jobs, j)
+		create = append(create, j)
+	}
+	return jobs, create, nil
 }
 
 // Process implements job.Processor. Called by the worker pool with a claimed
 // (running) job. It scrapes prices, saves them, and marks the job completed or failed.
+// Refresh jobs also overwrite stored prices that the source has revised.
 func (s *Service) Process(ctx context.Context, j *job.Job) error {
+	if j.Kind == job.KindReparse {
+		return s.reparse(ctx, j)
+	}
+
 	sc, err := s.registry.Get(j.Source)
 	if err != nil {
-		return s.failJob(ctx, j, err)
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(err))
 	}
 
 	nativeCurrency := Currency(sc.NativeCurrency(j.Symbol))
@@ -122,79 +444,356 @@ func (s *Service) Process(ctx context.Context, j *job.Job) error {
 	// Check existing dates to avoid duplicates
 	existing, err := s.priceRepo.ExistingDates(ctx, Source(j.Source), j.Symbol, j.StartDate, j.EndDate)
 	if err != nil {
-		return s.failJob(ctx, j, fmt.Errorf("check existing dates: %w", err))
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("check existing dates: %w", err))
 	}
 
-	// Scrape
-	scraped, err := sc.Scrape(ctx, j.Symbol, j.StartDate, j.EndDate)
-	if err != nil {
-		return s.failJob(ctx, j, fmt.Errorf("scrape: %w", err))
+	// Scrape. When only some chunks failed, the rest is saved and the job
+	// ends up partial.
+	res, err := s.scrape(ctx, sc, j)
+	var partial *scraper.PartialError
+	if err != nil && !errors.As(err, &partial) {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("scrape: %w", err))
 	}
+	scraped := res.prices
 
-	// Filter out already-existing dates
-	newPrices := make([]Price, 0, len(scraped))
+	// Filter out already-existing dates, unless they are being refreshed
+	refresh := j.Kind == job.KindRefresh
+	fresh := make([]scraper.ScrapedPrice, 0, len(scraped))
 	for _, sp := range scraped {
-		if existing[sp.Date] {
+		if existing[sp.Date] && !refresh {
 			continue
 		}
-		newPrices = append(newPrices, Price{
-			Source:     Source(j.Source),
-			Symbol:     j.Symbol,
-			Date:       sp.Date,
-			ClosePrice: sp.ClosePrice,
-			Currency:   nativeCurrency,
-		})
+		fresh = append(fresh, sp)
+	}
+	fresh, err = s.screen(ctx, j, fresh)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, err)
+	}
+	newPrices := make([]Price, len(fresh))
+	for i, sp := range fresh {
+		newPrices[i] = newPrice(j, sp, nativeCurrency)
+	}
+
+	actions := make([]CorporateAction, len(res.actions))
+	for i, a := range res.actions {
+		actions[i] = CorporateAction{
+			Source:      Source(j.Source),
+			Symbol:      j.Symbol,
+			Date:        a.Date,
+			Type:        ActionType(a.Type),
+			Amount:      a.Amount,
+			Numerator:   a.Numerator,
+			Denominator: a.Denominator,
+			Currency:    nativeCurrency,
+		}
+	}
+	if _, err := s.priceRepo.SaveActions(ctx, actions); err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("save corporate actions: %w", err))
+	}
+
+	if err := s.saveFundStats(ctx, j, res.stats); err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, err)
 	}
 
 	// Save
-	n, err := s.priceRepo.SavePrices(ctx, newPrices)
+	var n int64
+	if refresh {
+		n, err = s.priceRepo.UpsertPrices(ctx, newPrices, j.ID)
+	} else {
+		n, err = s.priceRepo.SavePrices(ctx, newPrices)
+	}
 	if err != nil {
-		return s.failJob(ctx, j, fmt.Errorf("save prices: %w", err))
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("save prices: %w", err))
 	}
 
 	slog.Info("saved prices", "source", j.Source, "symbol", j.Symbol, "new", n, "total_scraped", len(scraped))
 
+	// Remember the days the source had nothing for, so that coverage checks
+	// stop queueing them. Losing them only costs a refetch.
+	have := existing
+	for _, sp := range scraped {
+		have[sp.Date] = true
+	}
+	var failed []scraper.ChunkError
+	if partial != nil {
+		failed = partial.Failed
+	}
+	empty := emptyRanges(calendar.For(j.Source, j.Symbol), j, have, failed, time.Now().UTC(), s.emptyTTL)
+	if err := s.priceRepo.SaveEmptyRanges(ctx, empty); err != nil {
+		slog.Warn("save empty ranges", "source", j.Source, "symbol", j.Symbol, "error", err)
+	}
+
+	j.RecordsCount = n
+	if partial != nil {
+		return job.RecordPartial(ctx, s.jobRepo, j, partial, failedRanges(partial), s.notify)
+	}
+
 	// Mark completed
 	j.Status = job.StatusCompleted
-	j.RecordsCount = n
 	_ = s.jobRepo.Update(ctx, j)
 	return nil
 }
 
-func (s *Service) failJob(ctx context.Context, j *job.Job, err error) error {
-	j.Status = job.StatusFailed
-	j.Error = err.Error()
+// reparse rebuilds the job's prices from the archived responses of its
+// symbol, without fetching. Where several responses cover a date the latest
+// one wins, and stored prices are overwritten with what it parses to.
+func (s *Service) reparse(ctx context.Context, j *job.Job) error {
+	if s.archive == nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(errors.New("response archive is not configured")))
+	}
+	sc, err := s.registry.Get(j.Source)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(err))
+	}
+	rp, ok := sc.(scraper.Reparser)
+	if !ok {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(fmt.Errorf("%s responses cannot be reparsed", j.Source)))
+	}
+
+	entries, err := s.archive.List(ctx, j.Source, j.Symbol, archive.DatasetPrices)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("list archived responses: %w", err))
+	}
+	if len(entries) == 0 {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(fmt.Errorf("no archived responses for %s/%s", j.Source, j.Symbol)))
+	}
+
+	byDate := make(map[time.Time]scraper.ScrapedPrice)
+	var parseErr error
+	parsed := 0
+	for _, e := range entries {
+		scraped, err := rp.Parse(j.Symbol, e.Body)
+		if err != nil {
+			slog.Warn("reparse: skip response", "source", j.Source, "symbol", j.Symbol, "response", e.ID, "error", err)
+			parseErr = err
+			continue
+		}
+		parsed++
+		for _, sp := range scraped {
+			if !sp.Date.Before(j.StartDate) && !sp.Date.After(j.EndDate) {
+				byDate[sp.Date] = sp
+			}
+		}
+	}
+	if parsed == 0 {
+		return job.RecordFailure(ctx, s.jobRepo, j, job.Permanent(fmt.Errorf("parse archived responses: %w", parseErr)))
+	}
+
+	byDay := slices.SortedFunc(maps.Values(byDate), func(a, b scraper.ScrapedPrice) int { return a.Date.Compare(b.Date) })
+	screened, err := s.screen(ctx, j, byDay)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, err)
+	}
+
+	nativeCurrency := Currency(sc.NativeCurrency(j.Symbol))
+	prices := make([]Price, len(screened))
+	for i, sp := range screened {
+		prices[i] = newPrice(j, sp, nativeCurrency)
+	}
+
+	n, err := s.priceRepo.UpsertPrices(ctx, prices, j.ID)
+	if err != nil {
+		return job.RecordFailure(ctx, s.jobRepo, j, fmt.Errorf("save prices: %w", err))
+	}
+
+	slog.Info("reparsed prices", "source", j.Source, "symbol", j.Symbol, "responses", len(entries),
+		"changed", n, "total_parsed", len(prices))
+
+	j.RecordsCount = n
+	j.Status = job.StatusCompleted
 	_ = s.jobRepo.Update(ctx, j)
-	return err
+	return nil
 }
 
-func (s *Service) convertPrices(ctx context.Context, prices []Price, nativeCurrency, requestedCurrency Currency, from, to time.Time) ([]PricePoint, error) {
-	needConversion := nativeCurrency != requestedCurrency
+// screen runs the quality checks over the prices a job is about to store and
+// returns those that pass, in date order. The others are quarantined, and
+// dates that pass now are released from an earlier quarantine. The result
+// may include earlier quarantined prices that a confirmed move accepts.
+func (s *Service) screen(ctx context.Context, j *job.Job, prices []scraper.ScrapedPrice) ([]scraper.ScrapedPrice, error) {
+	if s.checker == nil || len(prices) == 0 {
+		return prices, nil
+	}
 
-	var rates map[time.Time]float64
-	if needConversion {
-		if s.rateSvc == nil {
-			return nil, fmt.Errorf("currency conversion unavailable: rate service not configured")
-		}
-		var err error
-		rates, err = s.rateSvc.GetRates(ctx, rate.PairUSDTRY, from, to)
+	first := slices.MinFunc(prices, func(a, b scraper.ScrapedPrice) int { return a.Date.Compare(b.Date) }).Date
+	latest, err := s.priceRepo.LatestPrices(ctx, Source(j.Source), j.Symbol, first, s.checker.HistoryLen())
+	if err != nil {
+		return nil, fmt.Errorf("list latest prices: %w", err)
+	}
+	history := make([]float64, len(latest))
+	for i, p := range latest {
+		history[i] = p.ClosePrice
+	}
+	// Points quarantined between the stored history and these prices may
+	// be the start of a new level that these prices confirm.
+	var pending []quality.Point
+	if len(latest) > 0 {
+		pending, err = s.quarantine.List(ctx, j.Source, j.Symbol, latest[len(latest)-1].Date.AddDate(0, 0, 1), first.AddDate(0, 0, -1))
 		if err != nil {
-			return nil, fmt.Errorf("get exchange rates: %w", err)
+			return nil, fmt.Errorf("list quarantined prices: %w", err)
+		}
+	}
+
+	accepted, flagged := s.checker.Check(j.Source, j.Symbol, history, pending, prices)
+	for i := range flagged {
+		flagged[i].JobID = j.ID
+	}
+	if err := s.quarantine.Quarantine(ctx, flagged); err != nil {
+		return nil, fmt.Errorf("quarantine prices: %w", err)
+	}
+	dates := make([]time.Time, len(accepted))
+	for i, sp := range accepted {
+		dates[i] = sp.Date
+	}
+	if err := s.quarantine.Release(ctx, j.Source, j.Symbol, dates); err != nil {
+		return nil, fmt.Errorf("release quarantined prices: %w", err)
+	}
+
+	if len(flagged) > 0 {
+		slog.Warn("quarantined prices", "job", j.ID, "source", j.Source, "symbol", j.Symbol,
+			"flagged", len(flagged), "first", flagged[0].Date.Format("2006-01-02"), "flags", flagged[0].Flags)
+	}
+	return accepted, nil
+}
+
+// quarantined returns the quarantined prices of a series in [from, to], or
+// none when quality checks are off.
+func (s *Service) quarantined(ctx context.Context, source Source, symbol string, from, to time.Time) ([]quality.Point, error) {
+	if s.quarantine == nil {
+		return nil, nil
+	}
+	points, err := s.quarantine.List(ctx, string(source), symbol, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined prices: %w", err)
+	}
+	return points, nil
+}
+
+// newPrice is a scraped price stored for the job's symbol.
+func newPrice(j *job.Job, sp scraper.ScrapedPrice, currency Currency) Price {
+	return Price{
+		Source:     Source(j.Source),
+		Symbol:     j.Symbol,
+		Date:       sp.Date,
+		Open:       sp.Open,
+		High:       sp.High,
+		Low:        sp.Low,
+		ClosePrice: sp.ClosePrice,
+		AdjClose:   sp.AdjClose,
+		Volume:     sp.Volume,
+		Currency:   currency,
+		Reported:   sp.Reported,
+		Exact:      sp.Exact,
+	}
+}
+
+type scrapeResult struct {
+	prices  []scraper.ScrapedPrice
+	actions []scraper.CorporateAction
+	stats   []scraper.FundStats
+}
+
+// scrape uses the richest method the scraper offers so that data returned in
+// the same upstream response (corporate actions, fund statistics) is kept.
+func (s *Service) scrape(ctx context.Context, sc scraper.Scraper, j *job.Job) (scrapeResult, error) {
+	var res scrapeResult
+	var err error
+	switch x := sc.(type) {
+	case scraper.ActionScraper:
+		res.prices, res.actions, err = x.ScrapeWithActions(ctx, j.Symbol, j.StartDate, j.EndDate)
+	case scraper.FundScraper:
+		if s.fundRepo == nil {
+			res.prices, err = sc.Scrape(ctx, j.Symbol, j.StartDate, j.EndDate)
+			break
+		}
+		res.prices, res.stats, err = x.ScrapeWithStats(ctx, j.Symbol, j.StartDate, j.EndDate)
+	default:
+		res.prices, err = sc.Scrape(ctx, j.Symbol, j.StartDate, j.EndDate)
+	}
+	return res, err
+}
+
+// saveFundStats stores daily fund statistics and the latest reported name.
+func (s *Service) saveFundStats(ctx context.Context, j *job.Job, scraped []scraper.FundStats) error {
+	if s.fundRepo == nil || len(scraped) == 0 {
+		return nil
+	}
+
+	stats := make([]fund.Stats, len(scraped))
+	latest := scraped[0]
+	for i, st := range scraped {
+		stats[i] = fund.Stats{
+			Code:              j.Symbol,
+			Date:              st.Date,
+			SharesOutstanding: st.SharesOutstanding,
+			Investors:         st.Investors,
+			TotalValue:        st.TotalValue,
+		}
+		if st.Date.After(latest.Date) {
+			latest = st
 		}
-		if len(rates) == 0 {
-			return nil, fmt.Errorf("no exchange rates available for %s in the requested date range", rate.PairUSDTRY)
+	}
+	if _, err := s.fundRepo.SaveStats(ctx, stats); err != nil {
+		return fmt.Errorf("save fund stats: %w", err)
+	}
+
+	if latest.Name != "" {
+		if err := s.fundRepo.SaveSymbol(ctx, fund.SymbolInfo{Source: j.Source, Symbol: j.Symbol, Name: latest.Name}); err != nil {
+			return fmt.Errorf("save symbol: %w", err)
 		}
+	}
+	return nil
+}
+
+// conversion loads the exchange rates from the native into the requested
+// currency over the range, or returns nil when no conversion is needed.
+func (s *Service) conversion(ctx context.Context, nativeCurrency Currency, req GetPricesRequest, to time.Time) (*rate.Conversion, error) {
+	if nativeCurrency == req.Currency {
+		return nil, nil
+	}
+	if s.rateSvc == nil {
+		return nil, fmt.Errorf("currency conversion unavailable: rate service not configured")
+	}
+	conv, err := s.rateSvc.GetConversion(ctx, req.RateSource, string(nativeCurrency), string(req.Currency), req.StartDate, to)
+	if ae, ok := err.(*apperror.AppError); ok {
+		return nil, ae
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get exchange rates: %w", err)
+	}
+	if len(conv.Rates) == 0 {
+		return nil, fmt.Errorf("no exchange rates available for %s in the requested date range", conv.Path)
+	}
+	return conv, nil
+}
 
+// convertPrices builds the response points, converting with conv unless it
+// is nil.
+func convertPrices(prices []Price, nativeCurrency Currency, req GetPricesRequest, conv *rate.Conversion) ([]PricePoint, error) {
+	requestedCurrency := req.Currency
+	needConversion := conv != nil
+	exact := req.Precision == PrecisionExact
+
+	var rates map[time.Time]float64
+	var exactRates map[time.Time]decimal.Decimal
+	if needConversion {
 		// Forward-fill rates for all price dates
 		priceDates := make([]time.Time, len(prices))
 		for i, p := range prices {
 			priceDates[i] = p.Date
 		}
-		rates = rate.ForwardFill(rates, priceDates)
+		rates = rate.ForwardFill(conv.Rates, priceDates)
+		if exact {
+			exactRates = rate.ForwardFill(conv.Exact, priceDates)
+		}
 	}
 
 	points := make([]PricePoint, len(prices))
-	for i, p := range prices {
+	for i, raw := range prices {
+		p := raw
+		if req.Adjusted {
+			p = adjust(raw)
+		}
+
 		pp := PricePoint{
 			Symbol:         p.Symbol,
 			Date:           p.Date,
@@ -203,16 +802,31 @@ func (s *Service) convertPrices(ctx context.Context, prices []Price, nativeCurre
 			Currency:       requestedCurrency,
 			Source:         p.Source,
 			Rate:           1.0,
+			Open:           p.Open,
+			High:           p.High,
+			Low:            p.Low,
 			ClosePrice:     p.ClosePrice,
+			AdjClose:       p.AdjClose,
+			Volume:         p.Volume,
+			Reported:       p.Reported,
 		}
 
 		if needConversion {
 			r, ok := rates[p.Date]
 			if !ok || r <= 0 {
-				return nil, fmt.Errorf("missing exchange rate for %s on %s", rate.PairUSDTRY, p.Date.Format("2006-01-02"))
+				return nil, fmt.Errorf("missing exchange rate for %s on %s", conv.Path, p.Date.Format("2006-01-02"))
 			}
 			pp.Rate = r
-			pp.ClosePrice = convert(p.ClosePrice, nativeCurrency, requestedCurrency, r)
+			pp.RatePath = conv.Path
+			pp.Open = conv.Apply(p.Open, r)
+			pp.High = conv.Apply(p.High, r)
+			pp.Low = conv.Apply(p.Low, r)
+			pp.ClosePrice = conv.Apply(p.ClosePrice, r)
+			pp.AdjClose = conv.Apply(p.AdjClose, r)
+		}
+
+		if exact {
+			pp.Exact = exactPrice(raw, req.Adjusted, conv, exactRates[p.Date])
 		}
 
 		points[i] = pp
@@ -221,26 +835,186 @@ func (s *Service) convertPrices(ctx context.Context, prices []Price, nativeCurre
 	return points, nil
 }
 
-// convert applies exchange rate conversion.
-// The rate is always USDTRY (how many TRY per 1 USD).
-func convert(price float64, from, to Currency, usdtryRate float64) float64 {
-	switch {
-	case from == CurrencyTRY && to == CurrencyUSD:
-		return price / usdtryRate
-	case from == CurrencyUSD && to == CurrencyTRY:
-		return price * usdtryRate
-	default:
-		return price
+// exactPrice recomputes a point from the stored decimals in decimal
+// arithmetic. conv is nil when no conversion is needed.
+func exactPrice(p Price, adjusted bool, conv *rate.Conversion, r decimal.Decimal) *ExactPrice {
+	v := p.Decimals()
+	e := &ExactPrice{
+		Open:       v.Open,
+		High:       v.High,
+		Low:        v.Low,
+		ClosePrice: v.ClosePrice,
+		AdjClose:   v.AdjClose,
+		Rate:       decimal.FromFloat(1),
+	}
+	if adjusted && !e.AdjClose.IsZero() && !e.ClosePrice.IsZero() {
+		f := e.AdjClose.Quo(e.ClosePrice)
+		e.Open = e.Open.Mul(f)
+		e.High = e.High.Mul(f)
+		e.Low = e.Low.Mul(f)
+		e.ClosePrice = e.AdjClose
+	}
+	e.NativePrice = e.ClosePrice
+
+	if conv != nil {
+		e.Rate = r
+		e.Open = conv.ApplyExact(e.Open, r)
+		e.High = conv.ApplyExact(e.High, r)
+		e.Low = conv.ApplyExact(e.Low, r)
+		e.ClosePrice = conv.ApplyExact(e.ClosePrice, r)
+		e.AdjClose = conv.ApplyExact(e.AdjClose, r)
+	}
+	return e
+}
+
+// adjust rescales a bar so that close equals the source's adjusted close.
+// Open, high and low use the same factor; NativePrice in the response then
+// reflects the adjusted close too. Bars without an adjusted close are
+// returned unchanged.
+func adjust(p Price) Price {
+	if p.AdjClose == 0 || p.ClosePrice == 0 {
+		return p
 	}
+	f := p.AdjClose / p.ClosePrice
+	p.Open *= f
+	p.High *= f
+	p.Low *= f
+	p.ClosePrice = p.AdjClose
+	return p
 }
 
-func countWeekdays(from, to time.Time) int {
-	count := 0
+const (
+	// minGapDistance merges missing runs separated by fewer stored trading
+	// days, so that a patchy series is fetched in one job rather than one per
+	// hole.
+	minGapDistance = 20
+	// recentEmptyDays is how many days back a source may still publish a
+	// price late. Empty answers for these days expire after the empty TTL;
+	// older ones are kept for good.
+	recentEmptyDays = 7
+	defaultEmptyTTL = 6 * time.Hour
+	// defaultQuarantineTTL is how long a quarantined day is left alone
+	// before it is fetched again.
+	defaultQuarantineTTL = 24 * time.Hour
+	// maxQuarantineFetches is how many fetches in a row returning the same
+	// flagged close leave a day quarantined without fetching it again. A
+	// refresh job still checks it.
+	maxQuarantineFetches = 3
+)
+
+// missingRanges returns the runs of trading days in [from, to] without a
+// stored price. A trailing run of just today is skipped while earlier data
+// exists, because the day's price is usually published after the close.
+// Closures the calendar does not know about are fetched once and then
+// recorded as empty ranges, which the caller counts as stored.
+func missingRanges(cal *calendar.Calendar, existing map[time.Time]bool, from, to, today time.Time) []scraper.DateRange {
+	type run struct {
+		scraper.DateRange
+		before, after bool // stored prices precede / follow the run
+		stored        int  // stored trading days between the previous run and this one
+	}
+
+	var runs []run
+	var cur *run
+	storedSince := 0
+	seen := false
 	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
-		wd := d.Weekday()
-		if wd != time.Saturday && wd != time.Sunday {
-			count++
+		if !cal.IsTradingDay(d) {
+			continue
+		}
+		if existing[d] {
+			if cur != nil {
+				cur.after = true
+				runs = append(runs, *cur)
+				cur = nil
+			}
+			seen = true
+			storedSince++
+			continue
+		}
+		if cur == nil {
+			cur = &run{DateRange: scraper.DateRange{From: d}, before: seen, stored: storedSince}
+			storedSince = 0
+		}
+		cur.To = d
+	}
+	if cur != nil {
+		runs = append(runs, *cur)
+	}
+
+	var ranges []scraper.DateRange
+	distance := 0 // stored trading days since the last kept range
+	for _, r := range runs {
+		distance += r.stored
+		if r.before && !r.after && r.From.Equal(today) && r.To.Equal(today) {
+			continue
+		}
+		if n := len(ranges); n > 0 && distance < minGapDistance {
+			ranges[n-1].To = r.To
+		} else {
+			ranges = append(ranges, r.DateRange)
+		}
+		distance = 0
+	}
+	return ranges
+}
+
+// emptyRanges returns the runs of trading days in the job's range that have
+// neither a stored nor a scraped price. Chunks that failed and days after
+// today are left out, and runs are split where the recent days begin so that
+// only those expire.
+func emptyRanges(cal *calendar.Calendar, j *job.Job, have map[time.Time]bool, failed []scraper.ChunkError, now time.Time, ttl time.Duration) []EmptyRange {
+	today := now.Truncate(24 * time.Hour)
+	recent := today.AddDate(0, 0, -recentEmptyDays)
+	expires := now.Add(ttl)
+
+	var ranges []EmptyRange
+	var cur *EmptyRange
+	flush := func() {
+		if cur != nil {
+			ranges = append(ranges, *cur)
+			cur = nil
+		}
+	}
+	for d := j.StartDate; !d.After(j.EndDate) && !d.After(today); d = d.AddDate(0, 0, 1) {
+		if !cal.IsTradingDay(d) {
+			continue
+		}
+		if have[d] || inChunks(failed, d) {
+			flush()
+			continue
+		}
+		isRecent := !d.Before(recent)
+		if cur != nil && (cur.ExpiresAt != nil) != isRecent {
+			flush()
 		}
+		if cur == nil {
+			cur = &EmptyRange{Source: Source(j.Source), Symbol: j.Symbol, StartDate: d, CheckedAt: now}
+			if isRecent {
+				cur.ExpiresAt = &expires
+			}
+		}
+		cur.EndDate = d
+	}
+	flush()
+	return ranges
+}
+
+func inChunks(chunks []scraper.ChunkError, d time.Time) bool {
+	for _, c := range chunks {
+		if !d.Before(c.From) && !d.After(c.To) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedRanges converts the failed chunks of a partial scrape to the job's
+// failed ranges.
+func failedRanges(partial *scraper.PartialError) []job.Range {
+	failed := make([]job.Range, len(partial.Failed))
+	for i, f := range partial.Failed {
+		failed[i] = job.Range{StartDate: f.From, EndDate: f.To, Error: f.Err.Error()}
 	}
-	return count
+	return failed
 }
@@ -2,18 +2,32 @@ package price
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
 	"github.com/ahmethakanbesel/finance-api/internal/rate"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 // --- mock price repo ---
 type mockPriceRepo struct {
-	prices []Price
-	dates  map[time.Time]bool
+	prices  []Price
+	dates   map[time.Time]bool
+	actions []CorporateAction
+	empty   []EmptyRange
+	revs    []Revision
 }
 
 func (m *mockPriceRepo) SavePrices(_ context.Context, prices []Price) (int64, error) {
@@ -21,21 +35,85 @@ func (m *mockPriceRepo) SavePrices(_ context.Context, prices []Price) (int64, er
 	return int64(len(prices)), nil
 }
 
-func (m *mockPriceRepo) ListPrices(_ context.Context, _ Source, _ string, _, _ time.Time) ([]Price, error) {
-	return m.prices, nil
+func (m *mockPriceRepo) UpsertPrices(_ context.Context, prices []Price, jobID int64) (int64, error) {
+	var n int64
+	for _, p := range prices {
+		i := slices.IndexFunc(m.prices, func(q Price) bool { return q.Date.Equal(p.Date) && q.Symbol == p.Symbol })
+		switch {
+		case i < 0:
+			m.prices = append(m.prices, p)
+		case m.prices[i] != p:
+			m.revs = append(m.revs, Revisions(m.prices[i], p, jobID)...)
+			m.prices[i] = p
+		default:
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (m *mockPriceRepo) LatestPrices(_ context.Context, _ Source, _ string, before time.Time, n int) ([]Price, error) {
+	var out []Price
+	for _, p := range m.prices {
+		if p.Date.Before(before) {
+			out = append(out, p)
+		}
+	}
+	slices.SortFunc(out, func(a, b Price) int { return a.Date.Compare(b.Date) })
+	return out[max(0, len(out)-n):], nil
+}
+
+func (m *mockPriceRepo) ListRevisions(_ context.Context, _ Source, _ string, _, _ time.Time) ([]Revision, error) {
+	return m.revs, nil
+}
+
+func (m *mockPriceRepo) ListPrices(_ context.Context, _ Source, symbol string, _, _ time.Time) ([]Price, error) {
+	var out []Price
+	for _, p := range m.prices {
+		if p.Symbol == "" || p.Symbol == symbol {
+			out = append(out, p)
+		}
+	}
+	return out, nil
 }
 
 func (m *mockPriceRepo) ExistingDates(_ context.Context, _ Source, _ string, _, _ time.Time) (map[time.Time]bool, error) {
 	if m.dates == nil {
 		return make(map[time.Time]bool), nil
 	}
-	return m.dates, nil
+	return maps.Clone(m.dates), nil
+}
+
+func (m *mockPriceRepo) SaveActions(_ context.Context, actions []CorporateAction) (int64, error) {
+	m.actions = append(m.actions, actions...)
+	return int64(len(actions)), nil
+}
+
+func (m *mockPriceRepo) ListActions(_ context.Context, _ Source, _ string, _, _ time.Time) ([]CorporateAction, error) {
+	return m.actions, nil
+}
+
+func (m *mockPriceRepo) SaveEmptyRanges(_ context.Context, ranges []EmptyRange) error {
+	m.empty = append(m.empty, ranges...)
+	return nil
+}
+
+func (m *mockPriceRepo) EmptyRanges(_ context.Context, _ Source, _ string, _, _, now time.Time) ([]EmptyRange, error) {
+	var out []EmptyRange
+	for _, e := range m.empty {
+		if e.ExpiresAt == nil || e.ExpiresAt.After(now) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
 }
 
 // --- mock job repo ---
 type mockJobRepo struct {
-	jobs   []*job.Job
-	nextID int64
+	jobs    []*job.Job
+	nextID  int64
+	batches int
 }
 
 func (m *mockJobRepo) Create(_ context.Context, j *job.Job) error {
@@ -45,6 +123,13 @@ func (m *mockJobRepo) Create(_ context.Context, j *job.Job) error {
 	m.jobs = append(m.jobs, &cp)
 	return nil
 }
+func (m *mockJobRepo) CreateBatch(ctx context.Context, jobs []*job.Job) error {
+	m.batches++
+	for _, j := range jobs {
+		_ = m.Create(ctx, j)
+	}
+	return nil
+}
 func (m *mockJobRepo) Update(_ context.Context, j *job.Job) error {
 	for i, existing := range m.jobs {
 		if existing.ID == j.ID {
@@ -64,13 +149,15 @@ func (m *mockJobRepo) Get(_ context.Context, id int64) (*job.Job, error) {
 	return nil, nil
 }
 func (m *mockJobRepo) List(_ context.Context, _, _ string) ([]job.Job, error) { return nil, nil }
-func (m *mockJobRepo) FindActive(_ context.Context, _, _, _, _ string) (*job.Job, error) {
+func (m *mockJobRepo) FindActive(_ context.Context, _ job.Kind, _, _, _, _ string) (*job.Job, error) {
 	return nil, nil
 }
-func (m *mockJobRepo) ClaimPending(_ context.Context) (*job.Job, error) {
+func (m *mockJobRepo) ClaimPending(_ context.Context, _ []string) (*job.Job, error) {
 	return nil, nil
 }
-func (m *mockJobRepo) RecoverStale(_ context.Context) (int64, error) { return 0, nil }
+func (m *mockJobRepo) RecoverStale(_ context.Context) (int64, error)    { return 0, nil }
+func (m *mockJobRepo) Cancel(_ context.Context, _ int64) (bool, error)  { return false, nil }
+func (m *mockJobRepo) Requeue(_ context.Context, _ int64) (bool, error) { return false, nil }
 
 // --- mock scraper ---
 type mockScraper struct {
@@ -91,10 +178,21 @@ func (m *mockScraper) Scrape(_ context.Context, _ string, _, _ time.Time) ([]scr
 	return m.prices, nil
 }
 
+// --- mock scraper with corporate actions ---
+type mockActionScraper struct {
+	mockScraper
+	actions []scraper.CorporateAction
+}
+
+func (m *mockActionScraper) ScrapeWithActions(_ context.Context, _ string, _, _ time.Time) ([]scraper.ScrapedPrice, []scraper.CorporateAction, error) {
+	return m.prices, m.actions, nil
+}
+
 // --- mock rate repo ---
 type mockRateRepo struct {
 	rates []rate.Rate
 	dates map[time.Time]bool
+	lists int
 }
 
 func (m *mockRateRepo) SaveRates(_ context.Context, rates []rate.Rate) (int64, error) {
@@ -102,15 +200,31 @@ func (m *mockRateRepo) SaveRates(_ context.Context, rates []rate.Rate) (int64, e
 	return int64(len(rates)), nil
 }
 
-func (m *mockRateRepo) ListRates(_ context.Context, _ string, _, _ time.Time) ([]rate.Rate, error) {
-	return m.rates, nil
+func (m *mockRateRepo) ListRates(_ context.Context, _, pair string, _, _ time.Time) ([]rate.Rate, error) {
+	m.lists++
+	var out []rate.Rate
+	for _, r := range m.rates {
+		if r.Pair == pair {
+			out = append(out, r)
+		}
+	}
+	return out, nil
 }
 
-func (m *mockRateRepo) ExistingDates(_ context.Context, _ string, _, _ time.Time) (map[time.Time]bool, error) {
-	if m.dates == nil {
-		return make(map[time.Time]bool), nil
+func (m *mockRateRepo) ListPairs(_ context.Context) ([]rate.PairCoverage, error) {
+	return nil, nil
+}
+
+func (m *mockRateRepo) ExistingDates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]bool, error) {
+	if m.dates != nil {
+		return m.dates, nil
+	}
+	rates, _ := m.ListRates(ctx, source, pair, from, to)
+	dates := make(map[time.Time]bool, len(rates))
+	for _, r := range rates {
+		dates[r.Date] = true
 	}
-	return m.dates, nil
+	return dates, nil
 }
 
 func TestGetPrices_QueueJob(t *testing.T) {
@@ -156,6 +270,253 @@ func TestGetPrices_QueueJob(t *testing.T) {
 	}
 }
 
+// weekdays marks every weekday in [from, to] as stored, except skip.
+func weekdays(from, to time.Time, skip ...time.Time) map[time.Time]bool {
+	dates := make(map[time.Time]bool)
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if wd := d.Weekday(); wd != time.Saturday && wd != time.Sunday && !slices.ContainsFunc(skip, d.Equal) {
+			dates[d] = true
+		}
+	}
+	return dates
+}
+
+func TestGetPrices_QueuesOnlyMissingRange(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	priceRepo := &mockPriceRepo{dates: weekdays(day(2014, 1, 1), day(2024, 1, 5))}
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{})
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+
+	resp, err := svc.GetPrices(context.Background(), GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "YAC",
+		Currency:  CurrencyTRY,
+		StartDate: day(2014, 1, 1),
+		EndDate:   day(2024, 1, 12),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job == nil || resp.Jobs != nil {
+		t.Fatalf("expected a single job, got %+v %v", resp.Job, resp.Jobs)
+	}
+	if !resp.Job.StartDate.Equal(day(2024, 1, 8)) || !resp.Job.EndDate.Equal(day(2024, 1, 12)) {
+		t.Errorf("expected job for 2024-01-08..2024-01-12, got %s..%s",
+			resp.Job.StartDate.Format("2006-01-02"), resp.Job.EndDate.Format("2006-01-02"))
+	}
+}
+
+func TestGetPrices_RemembersEmptyRanges(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	// The fund launched in 2023; the source has nothing before.
+	priceRepo := &mockPriceRepo{dates: weekdays(day(2023, 1, 2), day(2024, 1, 12))}
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{})
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+
+	req := GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "YAC",
+		Currency:  CurrencyTRY,
+		StartDate: day(2020, 1, 1),
+		EndDate:   day(2024, 1, 12),
+	}
+	resp, err := svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job == nil {
+		t.Fatal("expected a job for the range before the launch")
+	}
+	j := resp.Job
+	j.Status = job.StatusRunning
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	if len(priceRepo.empty) != 1 || !priceRepo.empty[0].StartDate.Equal(day(2020, 1, 2)) || priceRepo.empty[0].ExpiresAt != nil {
+		t.Fatalf("expected one permanent empty range from 2020-01-02, got %+v", priceRepo.empty)
+	}
+
+	resp, err = svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job != nil {
+		t.Errorf("expected no job once the empty range is known, got %s..%s",
+			resp.Job.StartDate.Format("2006-01-02"), resp.Job.EndDate.Format("2006-01-02"))
+	}
+}
+
+func TestGetPrices_ShortGapFetchedOnce(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	// Two days the calendar trades but the source has nothing for.
+	priceRepo := &mockPriceRepo{dates: weekdays(day(2), day(31), day(11), day(12))}
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{})
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+
+	req := GetPricesRequest{Source: SourceTefas, Symbol: "YAC", Currency: CurrencyTRY, StartDate: day(2), EndDate: day(31)}
+	resp, err := svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job == nil || !resp.Job.StartDate.Equal(day(11)) || !resp.Job.EndDate.Equal(day(12)) {
+		t.Fatalf("expected a job for Jan 11-12, got %+v", resp.Job)
+	}
+	j := resp.Job
+	j.Status = job.StatusRunning
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	resp, err = svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job != nil {
+		t.Errorf("expected no job once the gap is recorded as empty, got %+v", resp.Job)
+	}
+}
+
+func TestEmptyRanges(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	j := &job.Job{Source: "tefas", Symbol: "YAC", StartDate: day(1, 1), EndDate: day(2, 9)}
+	have := weekdays(day(1, 15), day(1, 19))
+	failed := []scraper.ChunkError{{DateRange: scraper.DateRange{From: day(1, 8), To: day(1, 10)}}}
+	now := day(2, 5).Add(12 * time.Hour)
+
+	got := emptyRanges(calendar.Weekdays, j, have, failed, now, time.Hour)
+
+	want := []struct {
+		from, to time.Time
+		expires  bool
+	}{
+		{day(1, 1), day(1, 5), false},
+		{day(1, 11), day(1, 12), false},
+		{day(1, 22), day(1, 26), false},
+		{day(1, 29), day(2, 5), true}, // recent days; after today is left out
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d ranges %+v, want %d", len(got), got, len(want))
+	}
+	for i, w := range want {
+		g := got[i]
+		if !g.StartDate.Equal(w.from) || !g.EndDate.Equal(w.to) || (g.ExpiresAt != nil) != w.expires {
+			t.Errorf("range %d = %s..%s (expires %v), want %s..%s (expires %v)", i,
+				g.StartDate.Format("2006-01-02"), g.EndDate.Format("2006-01-02"), g.ExpiresAt != nil,
+				w.from.Format("2006-01-02"), w.to.Format("2006-01-02"), w.expires)
+		}
+	}
+}
+
+func TestMissingRanges(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	type span struct{ from, to time.Time }
+	today := day(12, 31)
+
+	tests := []struct {
+		name     string
+		cal      *calendar.Calendar
+		existing map[time.Time]bool
+		from, to time.Time
+		today    time.Time
+		want     []span
+	}{
+		{
+			name: "nothing stored",
+			from: day(1, 1), to: day(1, 31), today: today,
+			want: []span{{day(1, 1), day(1, 31)}},
+		},
+		{
+			name:     "fully stored",
+			existing: weekdays(day(1, 1), day(1, 31)),
+			from:     day(1, 1), to: day(1, 31), today: today,
+		},
+		{
+			name:     "missing head and tail",
+			existing: weekdays(day(3, 1), day(3, 29)),
+			from:     day(1, 1), to: day(5, 31), today: today,
+			want: []span{{day(1, 1), day(2, 29)}, {day(4, 1), day(5, 31)}},
+		},
+		{
+			name:     "short hole between stored days",
+			existing: weekdays(day(4, 1), day(4, 30), day(4, 11), day(4, 12)),
+			from:     day(4, 1), to: day(4, 30), today: today,
+			want: []span{{day(4, 11), day(4, 12)}},
+		},
+		{
+			name:     "holidays of the calendar are not missing",
+			cal:      calendar.TEFAS,
+			existing: weekdays(day(4, 1), day(4, 30), weekdaysList(day(4, 9), day(4, 12))...),
+			from:     day(4, 1), to: day(4, 30), today: today,
+		},
+		{
+			name:     "long hole between stored days",
+			existing: weekdays(day(1, 1), day(6, 28), weekdaysList(day(3, 4), day(3, 15))...),
+			from:     day(1, 1), to: day(6, 28), today: today,
+			want: []span{{day(3, 4), day(3, 15)}},
+		},
+		{
+			name:     "nearby holes are merged",
+			existing: weekdays(day(1, 1), day(1, 31), append(weekdaysList(day(1, 8), day(1, 15)), weekdaysList(day(1, 22), day(1, 29))...)...),
+			from:     day(1, 1), to: day(1, 31), today: today,
+			want: []span{{day(1, 8), day(1, 29)}},
+		},
+		{
+			name:     "today not yet published",
+			existing: weekdays(day(12, 2), day(12, 30)),
+			from:     day(12, 2), to: day(12, 31), today: today,
+		},
+		{
+			name:     "today due after publication",
+			existing: weekdays(day(12, 2), day(12, 30)),
+			from:     day(12, 2), to: day(12, 31),
+			want: []span{{day(12, 31), day(12, 31)}},
+		},
+		{
+			name: "only today requested",
+			from: day(12, 31), to: day(12, 31), today: today,
+			want: []span{{day(12, 31), day(12, 31)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existing := tt.existing
+			if existing == nil {
+				existing = map[time.Time]bool{}
+			}
+			cal := tt.cal
+			if cal == nil {
+				cal = calendar.Weekdays
+			}
+			got := missingRanges(cal, existing, tt.from, tt.to, tt.today)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d ranges %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, w := range tt.want {
+				if !got[i].From.Equal(w.from) || !got[i].To.Equal(w.to) {
+					t.Errorf("range %d = %s..%s, want %s..%s", i,
+						got[i].From.Format("2006-01-02"), got[i].To.Format("2006-01-02"),
+						w.from.Format("2006-01-02"), w.to.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
+
+// weekdaysList lists the weekdays in [from, to].
+func weekdaysList(from, to time.Time) []time.Time {
+	var out []time.Time
+	for d := range weekdays(from, to) {
+		out = append(out, d)
+	}
+	return out
+}
+
 func TestProcess_ScrapeAndSave(t *testing.T) {
 	priceRepo := &mockPriceRepo{}
 	jobRepo := &mockJobRepo{}
@@ -195,6 +556,399 @@ func TestProcess_ScrapeAndSave(t *testing.T) {
 	}
 }
 
+// --- mock archive ---
+type mockArchive struct {
+	entries []archive.Entry
+}
+
+func (m *mockArchive) Save(_ context.Context, e *archive.Entry) error {
+	m.entries = append(m.entries, *e)
+	return nil
+}
+
+func (m *mockArchive) List(_ context.Context, source, symbol, dataset string) ([]archive.Entry, error) {
+	var out []archive.Entry
+	for _, e := range m.entries {
+		if e.Source == source && e.Symbol == symbol && e.Dataset == dataset {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// reparseScraper parses bodies of "date=close" lines.
+type reparseScraper struct {
+	mockScraper
+}
+
+func (m *reparseScraper) Parse(_ string, body []byte) ([]scraper.ScrapedPrice, error) {
+	var prices []scraper.ScrapedPrice
+	for line := range strings.Lines(string(body)) {
+		date, closeStr, ok := strings.Cut(strings.TrimSpace(line), "=")
+		d, err := time.Parse("2006-01-02", date)
+		if !ok || err != nil {
+			return nil, errors.New("bad line " + line)
+		}
+		var c float64
+		if err := json.Unmarshal([]byte(closeStr), &c); err != nil {
+			return nil, err
+		}
+		prices = append(prices, scraper.ScrapedPrice{Date: d, ClosePrice: c})
+	}
+	return prices, nil
+}
+
+func TestProcess_Reparse(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	priceRepo := &mockPriceRepo{prices: []Price{
+		{Source: SourceTefas, Symbol: "YAC", Date: day(2), ClosePrice: 123, Currency: CurrencyTRY},
+		{Source: SourceTefas, Symbol: "YAC", Date: day(3), ClosePrice: 1.24, Currency: CurrencyTRY},
+	}}
+	jobRepo := &mockJobRepo{}
+	arch := &mockArchive{entries: []archive.Entry{
+		{ID: 1, Source: "tefas", Symbol: "YAC", Dataset: archive.DatasetPrices, Body: []byte("2024-01-02=1.20\n2024-01-03=1.24\n")},
+		{ID: 2, Source: "tefas", Symbol: "YAC", Dataset: archive.DatasetPrices, Body: []byte("garbage")},
+		// Fetched later: wins for Jan 2. Jan 9 is outside the job's range.
+		{ID: 3, Source: "tefas", Symbol: "YAC", Dataset: archive.DatasetPrices, Body: []byte("2024-01-02=1.23\n2024-01-04=1.25\n2024-01-09=1.3")},
+	}}
+
+	reg := scraper.NewRegistry()
+	reg.Register(&reparseScraper{})
+	svc := NewService(priceRepo, jobRepo, reg, nil, WithArchive(arch))
+
+	j := &job.Job{Kind: job.KindReparse, Source: "tefas", Symbol: "YAC", StartDate: day(1), EndDate: day(5), Status: job.StatusRunning}
+	_ = jobRepo.Create(context.Background(), j)
+
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if j.Status != job.StatusCompleted || j.RecordsCount != 2 {
+		t.Errorf("expected completed with 2 changed records, got %s with %d", j.Status, j.RecordsCount)
+	}
+
+	got := make(map[time.Time]float64)
+	for _, p := range priceRepo.prices {
+		got[p.Date] = p.ClosePrice
+	}
+	want := map[time.Time]float64{day(2): 1.23, day(3): 1.24, day(4): 1.25}
+	if !maps.Equal(got, want) {
+		t.Errorf("prices = %v, want %v", got, want)
+	}
+	if len(priceRepo.revs) != 1 || priceRepo.revs[0].OldValue.String() != "123" || priceRepo.revs[0].JobID != j.ID {
+		t.Errorf("unexpected revisions %+v", priceRepo.revs)
+	}
+}
+
+func TestProcess_ReparseUnsupported(t *testing.T) {
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{})
+	svc := NewService(&mockPriceRepo{}, jobRepo, reg, nil, WithArchive(&mockArchive{}))
+
+	j := &job.Job{Kind: job.KindReparse, Source: "tefas", Symbol: "YAC", Status: job.StatusRunning, MaxAttempts: 3}
+	_ = jobRepo.Create(context.Background(), j)
+
+	if err := svc.Process(context.Background(), j); err == nil {
+		t.Fatal("expected error")
+	}
+	if j.Status != job.StatusFailed {
+		t.Errorf("expected failed without retry, got %s", j.Status)
+	}
+}
+
+func TestProcess_Refresh(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	stored := []Price{
+		{Source: SourceTefas, Symbol: "YAC", Date: day(2), ClosePrice: 1.23, Currency: CurrencyTRY},
+		{Source: SourceTefas, Symbol: "YAC", Date: day(3), ClosePrice: 1.20, Currency: CurrencyTRY},
+	}
+	priceRepo := &mockPriceRepo{prices: slices.Clone(stored), dates: map[time.Time]bool{day(2): true, day(3): true}}
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{prices: []scraper.ScrapedPrice{
+		{Date: day(2), ClosePrice: 1.23},
+		{Date: day(3), ClosePrice: 1.24}, // corrected by the source
+		{Date: day(4), ClosePrice: 1.25},
+	}})
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+
+	for _, kind := range []job.Kind{job.KindPrices, job.KindRefresh} {
+		j := &job.Job{Kind: kind, Source: "tefas", Symbol: "YAC", StartDate: day(1), EndDate: day(5), Status: job.StatusRunning}
+		_ = jobRepo.Create(context.Background(), j)
+		if err := svc.Process(context.Background(), j); err != nil {
+			t.Fatalf("%s: unexpected error: %v", kind, err)
+		}
+		if kind == job.KindPrices && (len(priceRepo.prices) != 3 || priceRepo.prices[1].ClosePrice != 1.20) {
+			t.Fatalf("prices job overwrote stored prices: %+v", priceRepo.prices)
+		}
+		if kind == job.KindRefresh && j.RecordsCount != 1 {
+			t.Errorf("expected 1 changed price, got %d", j.RecordsCount)
+		}
+	}
+
+	if priceRepo.prices[1].ClosePrice != 1.24 {
+		t.Errorf("expected corrected close 1.24, got %v", priceRepo.prices[1].ClosePrice)
+	}
+	if len(priceRepo.revs) != 1 {
+		t.Fatalf("expected 1 revision, got %+v", priceRepo.revs)
+	}
+	if r := priceRepo.revs[0]; r.Source != SourceTefas || r.Symbol != "YAC" || !r.Date.Equal(day(3)) || r.Field != "closePrice" ||
+		r.OldValue.String() != "1.2" || r.NewValue.String() != "1.24" || r.JobID != 2 {
+		t.Errorf("unexpected revision %+v", r)
+	}
+}
+
+// --- mock quarantine ---
+type mockQuarantine struct {
+	points map[time.Time]quality.Point
+}
+
+func (m *mockQuarantine) Quarantine(_ context.Context, points []quality.Point) error {
+	for _, p := range points {
+		m.points[p.Date] = p
+	}
+	return nil
+}
+
+func (m *mockQuarantine) Release(_ context.Context, _, _ string, dates []time.Time) error {
+	for _, d := range dates {
+		delete(m.points, d)
+	}
+	return nil
+}
+
+func (m *mockQuarantine) List(_ context.Context, _, _ string, _, _ time.Time) ([]quality.Point, error) {
+	return slices.SortedFunc(maps.Values(m.points), func(a, b quality.Point) int { return a.Date.Compare(b.Date) }), nil
+}
+
+func TestProcess_QuarantinesFlaggedPrices(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	priceRepo := &mockPriceRepo{prices: []Price{{Source: SourceTefas, Symbol: "YAC", Date: day(1), ClosePrice: 1.2}}}
+	jobRepo := &mockJobRepo{}
+	quarantine := &mockQuarantine{points: map[time.Time]quality.Point{
+		day(3): {Date: day(3), Flags: []quality.Flag{{Rule: quality.RuleMove}}}, // passes this time
+	}}
+	ms := &mockScraper{prices: []scraper.ScrapedPrice{
+		{Date: day(2), ClosePrice: 12.4},
+		{Date: day(3), ClosePrice: 1.23},
+		{Date: day(4), ClosePrice: 0},
+	}}
+	reg := scraper.NewRegistry()
+	reg.Register(ms)
+	checker := quality.NewChecker(quality.Rules{RejectInvalid: true, MaxMove: map[quality.Class]float64{quality.ClassFund: 0.2}})
+	svc := NewService(priceRepo, jobRepo, reg, nil, WithQuality(checker, quarantine))
+
+	j := &job.Job{Source: "tefas", Symbol: "YAC", StartDate: day(2), EndDate: day(5), Status: job.StatusRunning}
+	_ = jobRepo.Create(context.Background(), j)
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if j.Status != job.StatusCompleted || j.RecordsCount != 1 {
+		t.Errorf("expected completed with 1 record, got %s with %d", j.Status, j.RecordsCount)
+	}
+	if len(priceRepo.prices) != 2 || priceRepo.prices[1].ClosePrice != 1.23 {
+		t.Errorf("unexpected stored prices %+v", priceRepo.prices)
+	}
+	points, _ := quarantine.List(context.Background(), "tefas", "YAC", day(1), day(5))
+	if len(points) != 2 || !points[0].Date.Equal(day(2)) || points[0].Flags[0].Rule != quality.RuleMove ||
+		points[0].JobID != j.ID || !points[1].Date.Equal(day(4)) || points[1].Flags[0].Rule != quality.RuleInvalid {
+		t.Fatalf("unexpected quarantine %+v", points)
+	}
+	for _, d := range []time.Time{day(2), day(4)} {
+		if slices.ContainsFunc(priceRepo.empty, func(e EmptyRange) bool { return !d.Before(e.StartDate) && !d.After(e.EndDate) }) {
+			t.Errorf("quarantined day %s recorded as empty", d.Format("2006-01-02"))
+		}
+	}
+
+	// The quarantined days count as fetched and are reported with the prices.
+	priceRepo.dates = map[time.Time]bool{day(1): true, day(3): true}
+	resp, err := svc.GetPrices(context.Background(), GetPricesRequest{
+		Source: SourceTefas, Symbol: "YAC", Currency: CurrencyTRY, StartDate: day(1), EndDate: day(4),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job != nil || len(resp.Quarantined) != 2 {
+		t.Errorf("expected no job and 2 quarantined points, got %+v and %+v", resp.Job, resp.Quarantined)
+	}
+
+	// Once the quarantine TTL has passed they are fetched again.
+	for d, p := range quarantine.points {
+		p.FlaggedAt = p.FlaggedAt.Add(-2 * defaultQuarantineTTL)
+		quarantine.points[d] = p
+	}
+	resp, err = svc.GetPrices(context.Background(), GetPricesRequest{
+		Source: SourceTefas, Symbol: "YAC", Currency: CurrencyTRY, StartDate: day(1), EndDate: day(4),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job == nil {
+		t.Error("expected a job for the expired quarantine")
+	}
+
+	// Not once the source has returned the same close often enough.
+	jobRepo.jobs = nil
+	for d, p := range quarantine.points {
+		p.Fetches = maxQuarantineFetches
+		quarantine.points[d] = p
+	}
+	resp, err = svc.GetPrices(context.Background(), GetPricesRequest{
+		Source: SourceTefas, Symbol: "YAC", Currency: CurrencyTRY, StartDate: day(1), EndDate: day(4),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job != nil {
+		t.Errorf("expected no job for confirmed quarantined days, got %+v", resp.Job)
+	}
+}
+
+func TestProcess_ConfirmsStepChange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	priceRepo := &mockPriceRepo{prices: []Price{{Source: SourceTefas, Symbol: "YAC", Date: day(1), ClosePrice: 1}}}
+	jobRepo := &mockJobRepo{}
+	quarantine := &mockQuarantine{points: map[time.Time]quality.Point{}}
+	ms := &mockScraper{}
+	reg := scraper.NewRegistry()
+	reg.Register(ms)
+	checker := quality.NewChecker(quality.Rules{MaxMove: map[quality.Class]float64{quality.ClassFund: 0.2}, ConfirmRun: 2})
+	svc := NewService(priceRepo, jobRepo, reg, nil, WithQuality(checker, quarantine))
+
+	// The fund's unit price is split 1:10; each day is fetched on its own.
+	published, _ := decimal.Parse("0.100000000000000001")
+	for _, sp := range []scraper.ScrapedPrice{
+		{Date: day(2), ClosePrice: 0.1, Exact: &scraper.Exact{ClosePrice: published}},
+		{Date: day(3), ClosePrice: 0.101},
+	} {
+		ms.prices = []scraper.ScrapedPrice{sp}
+		j := &job.Job{Source: "tefas", Symbol: "YAC", StartDate: sp.Date, EndDate: sp.Date, Status: job.StatusRunning}
+		_ = jobRepo.Create(context.Background(), j)
+		if err := svc.Process(context.Background(), j); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(priceRepo.prices) != 3 || !priceRepo.prices[1].Date.Equal(day(2)) || priceRepo.prices[2].ClosePrice != 0.101 {
+		t.Errorf("expected the new level stored, got %+v", priceRepo.prices)
+	}
+	if e := priceRepo.prices[1].Exact; e == nil || e.ClosePrice.String() != published.String() {
+		t.Errorf("expected the quarantined day stored with its published close, got %+v", e)
+	}
+	if len(quarantine.points) != 0 {
+		t.Errorf("expected an empty quarantine, got %+v", quarantine.points)
+	}
+}
+
+// failingScraper returns err from every scrape.
+type failingScraper struct {
+	mockScraper
+	err error
+}
+
+func (m *failingScraper) Scrape(_ context.Context, _ string, _, _ time.Time) ([]scraper.ScrapedPrice, error) {
+	return nil, m.err
+}
+
+func TestProcess_RetriesTransientErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want job.Status
+	}{
+		{"rate limited", &httpx.RateLimitedError{Host: "tefas.test"}, job.StatusPending},
+		{"server error", &httpx.UpstreamDownError{Host: "tefas.test", StatusCode: 503}, job.StatusPending},
+		{"not found", &httpx.StatusError{Host: "tefas.test", StatusCode: 404}, job.StatusFailed},
+		{"parse failure", &httpx.ParseError{Host: "tefas.test", Err: errors.New("invalid character '<'")}, job.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobRepo := &mockJobRepo{}
+			reg := scraper.NewRegistry()
+			reg.Register(&failingScraper{err: tt.err})
+			svc := NewService(&mockPriceRepo{}, jobRepo, reg, nil)
+
+			j := &job.Job{
+				Source:      "tefas",
+				Symbol:      "YAC",
+				StartDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:     time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+				Status:      job.StatusRunning,
+				Attempts:    1,
+				MaxAttempts: job.DefaultMaxAttempts,
+			}
+			_ = jobRepo.Create(context.Background(), j)
+
+			if err := svc.Process(context.Background(), j); err == nil {
+				t.Fatal("expected scrape error")
+			}
+			if j.Status != tt.want {
+				t.Errorf("status = %s, want %s", j.Status, tt.want)
+			}
+			if (j.NextRunAt != nil) != (tt.want == job.StatusPending) {
+				t.Errorf("unexpected next run time %v for status %s", j.NextRunAt, j.Status)
+			}
+		})
+	}
+}
+
+// partialScraper returns prices together with a partial chunk failure.
+type partialScraper struct {
+	mockScraper
+	failed []scraper.ChunkError
+}
+
+func (m *partialScraper) Scrape(_ context.Context, _ string, _, _ time.Time) ([]scraper.ScrapedPrice, error) {
+	return m.prices, &scraper.PartialError{Chunks: 3, Failed: m.failed}
+}
+
+func TestProcess_PartialQueuesFollowUps(t *testing.T) {
+	priceRepo := &mockPriceRepo{}
+	jobRepo := &mockJobRepo{}
+	gap := scraper.DateRange{From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)}
+	reg := scraper.NewRegistry()
+	reg.Register(&partialScraper{
+		mockScraper: mockScraper{prices: []scraper.ScrapedPrice{
+			{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), ClosePrice: 1.23},
+		}},
+		failed: []scraper.ChunkError{{DateRange: gap, Err: errors.New("timeout")}},
+	})
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+	notified := 0
+	svc.SetNotify(func() { notified++ })
+
+	j := &job.Job{
+		Kind:      job.KindPrices,
+		Source:    "tefas",
+		Symbol:    "YAC",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		Status:    job.StatusRunning,
+	}
+	_ = jobRepo.Create(context.Background(), j)
+
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if j.Status != job.StatusPartial || len(j.FailedRanges) != 1 || j.RecordsCount != 1 {
+		t.Errorf("expected partial job with 1 record and 1 failed range, got %s %d %v", j.Status, j.RecordsCount, j.FailedRanges)
+	}
+	if len(priceRepo.prices) != 1 {
+		t.Errorf("expected the scraped price to be saved, got %d", len(priceRepo.prices))
+	}
+	if len(jobRepo.jobs) != 2 {
+		t.Fatalf("expected a follow-up job, got %d jobs", len(jobRepo.jobs))
+	}
+	f := jobRepo.jobs[1]
+	if f.ParentID != j.ID || f.Status != job.StatusPending || !f.StartDate.Equal(gap.From) || !f.EndDate.Equal(gap.To) {
+		t.Errorf("unexpected follow-up: %+v", f)
+	}
+	if notified != 1 {
+		t.Errorf("expected worker pool to be notified once, got %d", notified)
+	}
+}
+
 func TestGetPrices_ServedFromCache(t *testing.T) {
 	// Pre-fill enough dates to hit >80% coverage
 	dates := make(map[time.Time]bool)
@@ -263,7 +1017,7 @@ func TestGetPrices_ConvertTRYtoUSD(t *testing.T) {
 			time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC): true,
 		},
 	}
-	rateSvc := rate.NewService(rateRepo)
+	rateSvc := rate.NewService(rateRepo, rate.WithHTTPOptions(httpx.WithAttempts(1)))
 
 	svc := NewService(priceRepo, jobRepo, reg, rateSvc)
 
@@ -328,6 +1082,177 @@ func TestGetPrices_ConvertTRYtoUSD(t *testing.T) {
 	}
 }
 
+func TestGetPricesBatch(t *testing.T) {
+	day1 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	priceRepo := &mockPriceRepo{
+		prices: []Price{
+			{Source: SourceTefas, Date: day1, ClosePrice: 30.0, Currency: CurrencyTRY},
+			{Source: SourceTefas, Date: day2, ClosePrice: 60.0, Currency: CurrencyTRY},
+		},
+	}
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{nativeCurrency: "TRY"})
+
+	rateRepo := &mockRateRepo{
+		rates: []rate.Rate{
+			{Pair: rate.PairUSDTRY, Date: day1, Rate: 30.0},
+			{Pair: rate.PairUSDTRY, Date: day2, Rate: 30.0},
+		},
+		dates: map[time.Time]bool{day1: true, day2: true},
+	}
+
+	notified := 0
+	svc := NewService(priceRepo, jobRepo, reg, rate.NewService(rateRepo, rate.WithHTTPOptions(httpx.WithAttempts(1))))
+	svc.SetNotify(func() { notified++ })
+
+	resp, err := svc.GetPricesBatch(context.Background(), BatchPricesRequest{
+		Items: []BatchItem{
+			{Source: SourceTefas, Symbol: "yac"},
+			{Source: SourceTefas, Symbol: "AEA", FundType: "EMK"},
+		},
+		Currency:  CurrencyUSD,
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if jobRepo.batches != 1 || len(jobRepo.jobs) != 2 {
+		t.Errorf("expected both jobs queued in one batch, got %d batches, %d jobs", jobRepo.batches, len(jobRepo.jobs))
+	}
+	if notified != 1 {
+		t.Errorf("expected one notify, got %d", notified)
+	}
+	if rateRepo.lists != 1 {
+		t.Errorf("expected USDTRY to be loaded once, got %d loads", rateRepo.lists)
+	}
+
+	for _, sym := range []string{"YAC", "EMK:AEA"} {
+		sp, ok := resp.Symbols[sym]
+		if !ok {
+			t.Fatalf("missing %s in %v", sym, resp.Symbols)
+		}
+		if sp.Job == nil || sp.Job.Symbol != sym || sp.Job.Status != job.StatusPending {
+			t.Errorf("%s: unexpected job %+v", sym, sp.Job)
+		}
+		if len(sp.Prices) != 2 || sp.Prices[1].ClosePrice != 2.0 {
+			t.Errorf("%s: unexpected prices %+v", sym, sp.Prices)
+		}
+	}
+}
+
+func TestGetPricesWide(t *testing.T) {
+	d := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+	priceRepo := &mockPriceRepo{
+		prices: []Price{
+			{Source: SourceTefas, Symbol: "YAC", Date: d(2), ClosePrice: 1.0},
+			{Source: SourceTefas, Symbol: "YAC", Date: d(3), ClosePrice: 1.1},
+			{Source: SourceTefas, Symbol: "YAC", Date: d(5), ClosePrice: 1.3},
+			{Source: SourceTefas, Symbol: "AFT", Date: d(3), ClosePrice: 2.0},
+			{Source: SourceTefas, Symbol: "AFT", Date: d(4), ClosePrice: 2.1},
+		},
+	}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{nativeCurrency: "TRY"})
+	svc := NewService(priceRepo, &mockJobRepo{}, reg, nil)
+
+	req := BatchPricesRequest{
+		Items:     []BatchItem{{Source: SourceTefas, Symbol: "YAC"}, {Source: SourceTefas, Symbol: "AFT"}},
+		Currency:  CurrencyTRY,
+		StartDate: d(1),
+		EndDate:   d(5),
+		Layout:    LayoutWide,
+	}
+	value := func(v *float64) any {
+		if v == nil {
+			return nil
+		}
+		return *v
+	}
+
+	resp, err := svc.GetPricesWide(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(resp.Symbols, ",") != "YAC,AFT" {
+		t.Errorf("expected request order, got %v", resp.Symbols)
+	}
+	if len(resp.Jobs) != 2 {
+		t.Errorf("expected jobs for both symbols, got %v", resp.Jobs)
+	}
+	want := [][]any{{1.0, nil}, {1.1, 2.0}, {nil, 2.1}, {1.3, nil}}
+	if len(resp.Rows) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(resp.Rows))
+	}
+	for i, row := range resp.Rows {
+		for j, v := range row.Values {
+			if value(v) != want[i][j] {
+				t.Errorf("row %s col %d: expected %v, got %v", row.Date.Format(time.DateOnly), j, want[i][j], value(v))
+			}
+		}
+	}
+
+	req.Fill = FillFFill
+	resp, err = svc.GetPricesWide(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = [][]any{{1.0, nil}, {1.1, 2.0}, {1.1, 2.1}, {1.3, 2.1}}
+	for i, row := range resp.Rows {
+		for j, v := range row.Values {
+			if value(v) != want[i][j] {
+				t.Errorf("ffill row %s col %d: expected %v, got %v", row.Date.Format(time.DateOnly), j, want[i][j], value(v))
+			}
+		}
+	}
+	if f := resp.Rows[2].Filled; len(f) != 1 || f[0] != "YAC" {
+		t.Errorf("expected YAC filled on %s, got %v", resp.Rows[2].Date.Format(time.DateOnly), f)
+	}
+	if f := resp.Rows[0].Filled; len(f) != 0 {
+		t.Errorf("nothing to carry before the first price, got %v", f)
+	}
+}
+
+func TestBatchPricesRequest_Validate(t *testing.T) {
+	valid := BatchPricesRequest{
+		Items:     []BatchItem{{Source: SourceTefas, Symbol: "YAC"}},
+		Currency:  CurrencyTRY,
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string][]BatchItem{
+		"empty":     nil,
+		"no source": {{Symbol: "YAC"}},
+		"duplicate": {{Source: SourceTefas, Symbol: "YAC"}, {Source: SourceTefas, Symbol: "yac"}},
+		"bad item":  {{Source: SourceYahoo, Symbol: "AAPL", FundType: "EMK"}},
+	}
+	for name, items := range tests {
+		req := valid
+		req.Items = items
+		if err := req.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	bad := map[string]BatchPricesRequest{
+		"layout":         {Layout: "tall"},
+		"fill":           {Layout: LayoutWide, Fill: "bfill"},
+		"fill when long": {Fill: FillFFill},
+	}
+	for name, r := range bad {
+		r.Items, r.Currency, r.StartDate = valid.Items, valid.Currency, valid.StartDate
+		if err := r.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
 func TestGetPrices_ConvertUSDtoTRY(t *testing.T) {
 	priceRepo := &mockPriceRepo{}
 	jobRepo := &mockJobRepo{}
@@ -350,7 +1275,7 @@ func TestGetPrices_ConvertUSDtoTRY(t *testing.T) {
 			time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC): true,
 		},
 	}
-	rateSvc := rate.NewService(rateRepo)
+	rateSvc := rate.NewService(rateRepo, rate.WithHTTPOptions(httpx.WithAttempts(1)))
 
 	svc := NewService(priceRepo, jobRepo, reg, rateSvc)
 
@@ -403,3 +1328,205 @@ func TestGetPrices_ConvertUSDtoTRY(t *testing.T) {
 		t.Errorf("expected rate 30.0, got %f", resp.Prices[0].Rate)
 	}
 }
+
+func TestGetPrices_TriangulatedCurrency(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	priceRepo := &mockPriceRepo{
+		prices: []Price{{Source: SourceTefas, Symbol: "YAC", Date: day, ClosePrice: 66, Currency: CurrencyTRY}},
+		dates:  map[time.Time]bool{day: true},
+	}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{nativeCurrency: "TRY"})
+	rateSvc := rate.NewService(&mockRateRepo{rates: []rate.Rate{
+		{Pair: "EURUSD", Date: day, Rate: 1.1},
+		{Pair: rate.PairUSDTRY, Date: day, Rate: 30},
+	}})
+
+	svc := NewService(priceRepo, &mockJobRepo{}, reg, rateSvc)
+
+	resp, err := svc.GetPrices(context.Background(), GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "YAC",
+		Currency:  CurrencyEUR,
+		StartDate: day,
+		EndDate:   day,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 66 TRY / (1.1 EURUSD * 30 USDTRY) = 2 EUR
+	pp := resp.Prices[0]
+	if math.Abs(pp.ClosePrice-2) > 1e-9 {
+		t.Errorf("expected converted price 2.0, got %f", pp.ClosePrice)
+	}
+	if pp.RatePath != "EURUSD*USDTRY" {
+		t.Errorf("expected rate path EURUSD*USDTRY, got %q", pp.RatePath)
+	}
+}
+
+func TestGetPrices_ExactPrecision(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+	// More digits than a float64 holds, as stored by a source that
+	// publishes decimals.
+	stored, _ := decimal.Parse("0.123456789012345678")
+	priceRepo := &mockPriceRepo{
+		prices: []Price{
+			{Source: SourceTefas, Symbol: "XYZ", Date: day, ClosePrice: 0.1, Currency: CurrencyUSD},
+			{Source: SourceTefas, Symbol: "XYZ", Date: next, ClosePrice: stored.Float64(), Currency: CurrencyUSD,
+				Exact: &scraper.Exact{ClosePrice: stored}},
+		},
+		dates: map[time.Time]bool{day: true, next: true},
+	}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{nativeCurrency: "USD"})
+	rateSvc := rate.NewService(&mockRateRepo{rates: []rate.Rate{
+		{Pair: rate.PairUSDTRY, Date: day, Rate: 3},
+		{Pair: rate.PairUSDTRY, Date: next, Rate: 3},
+	}})
+
+	svc := NewService(priceRepo, &mockJobRepo{}, reg, rateSvc)
+
+	req := GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "XYZ",
+		Currency:  CurrencyTRY,
+		StartDate: day,
+		EndDate:   next,
+	}
+	resp, err := svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Prices[0].Exact != nil {
+		t.Fatal("expected no exact values by default")
+	}
+
+	req.Precision = PrecisionExact
+	resp, err = svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 0.1 * 3 is 0.30000000000000004 in float64
+	e := resp.Prices[0].Exact
+	if e == nil || e.ClosePrice.String() != "0.3" || e.NativePrice.String() != "0.1" || e.Rate.String() != "3" {
+		t.Fatalf("unexpected exact values: %+v", e)
+	}
+	if e := resp.Prices[1].Exact; e == nil || e.NativePrice.String() != "0.123456789012345678" ||
+		e.ClosePrice.String() != "0.370370367037037034" {
+		t.Fatalf("expected the stored decimals, got %+v", e)
+	}
+
+	b, err := json.Marshal(resp.Prices[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"closePrice":"0.3"`) || !strings.Contains(string(b), `"rate":"3"`) {
+		t.Errorf("expected decimal strings in JSON, got %s", b)
+	}
+}
+
+func TestGetPrices_Adjusted(t *testing.T) {
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	priceRepo := &mockPriceRepo{}
+	jobRepo := &mockJobRepo{}
+	ms := &mockActionScraper{
+		mockScraper: mockScraper{
+			prices: []scraper.ScrapedPrice{
+				{Date: day, Open: 90, High: 110, Low: 80, ClosePrice: 100, AdjClose: 50},
+			},
+		},
+		actions: []scraper.CorporateAction{
+			{Date: day, Type: scraper.ActionSplit, Numerator: 2, Denominator: 1},
+		},
+	}
+	reg := scraper.NewRegistry()
+	reg.Register(ms)
+
+	svc := NewService(priceRepo, jobRepo, reg, nil)
+
+	j := &job.Job{Source: "tefas", Symbol: "THYAO.IS", StartDate: day, EndDate: day, Status: job.StatusRunning}
+	_ = jobRepo.Create(context.Background(), j)
+	if err := svc.Process(context.Background(), j); err != nil {
+		t.Fatalf("process error: %v", err)
+	}
+	if len(priceRepo.actions) != 1 || priceRepo.actions[0].Type != ActionSplit {
+		t.Fatalf("expected split to be saved, got %+v", priceRepo.actions)
+	}
+
+	req := GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "THYAO.IS",
+		Currency:  CurrencyTRY,
+		StartDate: day,
+		EndDate:   day,
+	}
+
+	raw, err := svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if raw.Prices[0].ClosePrice != 100 || raw.Prices[0].AdjClose != 50 {
+		t.Errorf("expected raw close 100 / adj 50, got %+v", raw.Prices[0])
+	}
+	if len(raw.Actions) != 1 {
+		t.Errorf("expected 1 action in response, got %d", len(raw.Actions))
+	}
+
+	req.Adjusted = true
+	adj, err := svc.GetPrices(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p := adj.Prices[0]
+	if p.ClosePrice != 50 || p.Open != 45 || p.High != 55 || p.Low != 40 {
+		t.Errorf("expected bar scaled by 0.5, got %+v", p)
+	}
+}
+
+func TestGetPrices_FundTypeInJob(t *testing.T) {
+	jobRepo := &mockJobRepo{}
+	reg := scraper.NewRegistry()
+	reg.Register(&mockScraper{})
+
+	svc := NewService(&mockPriceRepo{}, jobRepo, reg, nil)
+
+	resp, err := svc.GetPrices(context.Background(), GetPricesRequest{
+		Source:    SourceTefas,
+		Symbol:    "AEA",
+		FundType:  "EMK",
+		Currency:  CurrencyTRY,
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Job == nil || resp.Job.Symbol != "EMK:AEA" {
+		t.Fatalf("expected job for EMK:AEA, got %+v", resp.Job)
+	}
+}
+
+func TestGetPricesRequest_FundTypeValidation(t *testing.T) {
+	base := GetPricesRequest{
+		Symbol:    "AEA",
+		Currency:  CurrencyTRY,
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	req := base
+	req.Source = SourceYahoo
+	req.FundType = "EMK"
+	if req.Validate() == nil {
+		t.Error("expected error for fundType on non-tefas source")
+	}
+
+	req = base
+	req.Source = SourceTefas
+	req.FundType = "XYZ"
+	if req.Validate() == nil {
+		t.Error("expected error for unknown fund type")
+	}
+}
@@ -1,10 +1,18 @@
 package price
 
 import (
+	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 )
 
 type GetPricesRequest struct {
@@ -14,8 +22,18 @@ type GetPricesRequest struct {
 	StartDate time.Time
 	EndDate   time.Time
 	Format    string // "json" or "csv"
+	Adjusted  bool   // return split/dividend-adjusted bars where available
+	FundType  string // TEFAS fund type (YAT, EMK, ...); empty means symbol prefix or YAT
+	Precision string // "float" (default) or "exact"
+
+	RateSource string // exchange rate provider: "yahoo" (default) or "tcmb"
 }
 
+const (
+	PrecisionFloat = "float"
+	PrecisionExact = "exact"
+)
+
 func (r GetPricesRequest) Validate() *apperror.AppError {
 	if len(r.Symbol) < 2 {
 		return apperror.New(apperror.BadRequest, "symbol must be at least 2 characters")
@@ -26,27 +44,310 @@ func (r GetPricesRequest) Validate() *apperror.AppError {
 	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
 		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
 	}
-	if r.Currency != CurrencyTRY && r.Currency != CurrencyUSD {
-		return apperror.New(apperror.BadRequest, "currency must be TRY or USD")
+	if !rate.Supported(string(r.Currency)) {
+		return apperror.New(apperror.BadRequest, "currency must be one of TRY, USD, EUR, GBP, CHF, JPY, XAU")
 	}
 	if r.Format != "" && r.Format != "json" && r.Format != "csv" {
 		return apperror.New(apperror.BadRequest, "format must be json or csv")
 	}
+	if r.Precision != "" && r.Precision != PrecisionFloat && r.Precision != PrecisionExact {
+		return apperror.New(apperror.BadRequest, "precision must be float or exact")
+	}
+	if appErr := rate.ValidateSource(r.RateSource); appErr != nil {
+		return appErr
+	}
+	if r.FundType != "" && r.Source != SourceTefas {
+		return apperror.New(apperror.BadRequest, "fundType is only supported for source tefas")
+	}
+	if r.Source == SourceTefas {
+		if _, err := fund.SeriesSymbol(r.FundType, r.Symbol); err != nil {
+			return apperror.New(apperror.BadRequest, err.Error())
+		}
+	}
+	return nil
+}
+
+// SeriesSymbol is the key the requested series is stored under. TEFAS funds
+// of a non-default type are prefixed with their fund type ("EMK:AEA").
+func (r GetPricesRequest) SeriesSymbol() string {
+	if r.Source != SourceTefas {
+		return r.Symbol
+	}
+	symbol, err := fund.SeriesSymbol(r.FundType, r.Symbol)
+	if err != nil {
+		return r.Symbol
+	}
+	return symbol
+}
+
+// MaxBatchItems caps the number of symbols in one batch request.
+const MaxBatchItems = 100
+
+// BatchItem is one series of a batch request.
+type BatchItem struct {
+	Source   Source `json:"source"`
+	Symbol   string `json:"symbol"`
+	FundType string `json:"fundType,omitempty"`
+}
+
+// BatchPricesRequest asks for several series over a shared date range and
+// currency. The remaining fields apply to every item as in GetPricesRequest.
+type BatchPricesRequest struct {
+	Items      []BatchItem
+	StartDate  time.Time
+	EndDate    time.Time
+	Currency   Currency
+	Format     string // "json" or "csv"
+	Adjusted   bool
+	Precision  string
+	RateSource string
+	Layout     string // "long" (default): series per symbol; "wide": one row per date
+	Fill       string // wide layout only: "none" (default) or "ffill"
+}
+
+const (
+	LayoutLong = "long"
+	LayoutWide = "wide"
+
+	FillNone  = "none"
+	FillFFill = "ffill"
+)
+
+// item returns the single-series request of the i-th item.
+func (r BatchPricesRequest) item(i int) GetPricesRequest {
+	it := r.Items[i]
+	return GetPricesRequest{
+		Source:     it.Source,
+		Symbol:     strings.ToUpper(it.Symbol),
+		Currency:   r.Currency,
+		StartDate:  r.StartDate,
+		EndDate:    r.EndDate,
+		Format:     r.Format,
+		Adjusted:   r.Adjusted,
+		FundType:   strings.ToUpper(it.FundType),
+		Precision:  r.Precision,
+		RateSource: r.RateSource,
+	}
+}
+
+func (r BatchPricesRequest) Validate() *apperror.AppError {
+	if len(r.Items) == 0 {
+		return apperror.New(apperror.BadRequest, "items must list at least one symbol")
+	}
+	if len(r.Items) > MaxBatchItems {
+		return apperror.New(apperror.BadRequest, fmt.Sprintf("at most %d items are allowed", MaxBatchItems))
+	}
+	if r.Layout != "" && r.Layout != LayoutLong && r.Layout != LayoutWide {
+		return apperror.New(apperror.BadRequest, "layout must be long or wide")
+	}
+	if r.Fill != "" && r.Fill != FillNone && r.Fill != FillFFill {
+		return apperror.New(apperror.BadRequest, "fill must be none or ffill")
+	}
+	if r.Fill == FillFFill && r.Layout != LayoutWide {
+		return apperror.New(apperror.BadRequest, "fill requires layout wide")
+	}
+	seen := make(map[string]bool, len(r.Items))
+	for i, it := range r.Items {
+		if it.Source == "" {
+			return apperror.New(apperror.BadRequest, fmt.Sprintf("items[%d]: source is required", i))
+		}
+		req := r.item(i)
+		if err := req.Validate(); err != nil {
+			return apperror.New(err.Code(), fmt.Sprintf("items[%d]: %s", i, err.Message()))
+		}
+		symbol := req.SeriesSymbol()
+		if seen[symbol] {
+			return apperror.New(apperror.BadRequest, fmt.Sprintf("items[%d]: symbol %s is requested more than once", i, symbol))
+		}
+		seen[symbol] = true
+	}
 	return nil
 }
 
 type PricePoint struct {
 	Symbol         string    `json:"symbol"`
 	Date           time.Time `json:"date"`
+	Open           float64   `json:"open,omitempty"`
+	High           float64   `json:"high,omitempty"`
+	Low            float64   `json:"low,omitempty"`
 	ClosePrice     float64   `json:"closePrice"`
+	AdjClose       float64   `json:"adjClose,omitempty"`
+	Volume         float64   `json:"volume,omitempty"`
 	Currency       Currency  `json:"currency"`
 	NativePrice    float64   `json:"nativePrice"`
 	NativeCurrency Currency  `json:"nativeCurrency"`
 	Rate           float64   `json:"rate"`
+	RatePath       string    `json:"ratePath,omitempty"` // pair(s) Rate was derived from, e.g. "EURUSD*USDTRY" or "GC=F*USDTRY"
 	Source         Source    `json:"source"`
+	// Reported lists the optional values the source published. They are
+	// emitted even when zero; the others are left out.
+	Reported scraper.Field `json:"-"`
+
+	Exact *ExactPrice `json:"-"` // set when precision=exact
+}
+
+// ExactPrice holds a point's prices and rate as exact decimals. Values are
+// computed from the stored prices and rates without float rounding.
+type ExactPrice struct {
+	Open        decimal.Decimal
+	High        decimal.Decimal
+	Low         decimal.Decimal
+	ClosePrice  decimal.Decimal
+	AdjClose    decimal.Decimal
+	NativePrice decimal.Decimal
+	Rate        decimal.Decimal
+}
+
+// MarshalJSON emits the exact values as decimal strings when present, and
+// the plain float fields otherwise. Optional values are emitted when the
+// source reported them.
+func (p PricePoint) MarshalJSON() ([]byte, error) {
+	type plain PricePoint
+	out := struct {
+		plain
+		Open        any `json:"open,omitempty"`
+		High        any `json:"high,omitempty"`
+		Low         any `json:"low,omitempty"`
+		ClosePrice  any `json:"closePrice"`
+		AdjClose    any `json:"adjClose,omitempty"`
+		Volume      any `json:"volume,omitempty"`
+		NativePrice any `json:"nativePrice"`
+		Rate        any `json:"rate"`
+	}{plain: plain(p), ClosePrice: p.ClosePrice, NativePrice: p.NativePrice, Rate: p.Rate}
+	open, high, low, adjClose := any(p.Open), any(p.High), any(p.Low), any(p.AdjClose)
+	if e := p.Exact; e != nil {
+		open, high, low, adjClose = e.Open, e.High, e.Low, e.AdjClose
+		out.ClosePrice, out.NativePrice, out.Rate = e.ClosePrice, e.NativePrice, e.Rate
+	}
+	for _, v := range []struct {
+		field scraper.Field
+		dst   *any
+		value any
+	}{
+		{scraper.FieldOpen, &out.Open, open},
+		{scraper.FieldHigh, &out.High, high},
+		{scraper.FieldLow, &out.Low, low},
+		{scraper.FieldAdjClose, &out.AdjClose, adjClose},
+		{scraper.FieldVolume, &out.Volume, p.Volume},
+	} {
+		if p.Reported.Has(v.field) {
+			*v.dst = v.value
+		}
+	}
+	return json.Marshal(out)
 }
 
 type GetPricesResponse struct {
-	Prices []PricePoint `json:"prices"`
-	Job    *job.Job     `json:"job,omitempty"`
+	Prices  []PricePoint      `json:"prices"`
+	Actions []CorporateAction `json:"actions,omitempty"`
+	// Quarantined lists the fetched prices a quality check held back. Prices
+	// has no price of their dates unless one was stored before, which a
+	// refresh whose value was quarantined leaves in place.
+	Quarantined []quality.Point `json:"quarantined,omitempty"`
+	Job         *job.Job        `json:"job,omitempty"`  // first of Jobs
+	Jobs        []*job.Job      `json:"jobs,omitempty"` // set when more than one sub-range is being fetched
+}
+
+func (r *GetPricesResponse) setJobs(jobs []*job.Job) {
+	r.Job, r.Jobs = firstJob(jobs)
+}
+
+type GetRevisionsRequest struct {
+	Source    Source
+	Symbol    string
+	FundType  string
+	StartDate time.Time // price dates, not revision times
+	EndDate   time.Time
+}
+
+func (r GetRevisionsRequest) Validate() *apperror.AppError {
+	if r.Source == "" {
+		return apperror.New(apperror.BadRequest, "source is required")
+	}
+	return r.prices().Validate()
+}
+
+// SeriesSymbol is the key the series is stored under.
+func (r GetRevisionsRequest) SeriesSymbol() string {
+	return r.prices().SeriesSymbol()
+}
+
+func (r GetRevisionsRequest) prices() GetPricesRequest {
+	return GetPricesRequest{
+		Source:    r.Source,
+		Symbol:    r.Symbol,
+		Currency:  CurrencyTRY,
+		StartDate: r.StartDate,
+		EndDate:   r.EndDate,
+		FundType:  r.FundType,
+	}
+}
+
+type GetRevisionsResponse struct {
+	Source    Source     `json:"source"`
+	Symbol    string     `json:"symbol"`
+	Revisions []Revision `json:"revisions"`
+}
+
+// SymbolPrices is one series of a batch response.
+type SymbolPrices struct {
+	Source      Source            `json:"source"`
+	Prices      []PricePoint      `json:"prices"`
+	Actions     []CorporateAction `json:"actions,omitempty"`
+	Quarantined []quality.Point   `json:"quarantined,omitempty"`
+	Job         *job.Job          `json:"job,omitempty"`  // pending or running scrape of the range
+	Jobs        []*job.Job        `json:"jobs,omitempty"` // set when more than one sub-range is being fetched
+}
+
+func (r *SymbolPrices) setJobs(jobs []*job.Job) {
+	r.Job, r.Jobs = firstJob(jobs)
+}
+
+// firstJob splits the jobs of a request into the single job reported under
+// "job" and the full list, which is only reported when there are several.
+func firstJob(jobs []*job.Job) (*job.Job, []*job.Job) {
+	switch len(jobs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return jobs[0], nil
+	default:
+		return jobs[0], jobs
+	}
+}
+
+type BatchPricesResponse struct {
+	Symbols map[string]*SymbolPrices `json:"symbols"` // keyed by series symbol
+}
+
+// WidePricesResponse is a batch response pivoted to one row per date and one
+// column per symbol, in request order.
+type WidePricesResponse struct {
+	Currency Currency            `json:"currency"`
+	Symbols  []string            `json:"symbols"`
+	Rows     []WideRow           `json:"rows"`
+	Jobs     map[string]*job.Job `json:"jobs,omitempty"` // pending or running scrapes by symbol
+}
+
+// WideRow holds the close of every symbol on one date, aligned with
+// WidePricesResponse.Symbols. A nil value means the symbol has no price that
+// day (a holiday in its market, or before its first price).
+type WideRow struct {
+	Date   time.Time  `json:"date"`
+	Values []*float64 `json:"values"`
+	Filled []string   `json:"filled,omitempty"` // symbols carried forward from an earlier date
+
+	Exact []*decimal.Decimal `json:"-"` // set when precision=exact
+}
+
+// MarshalJSON emits the exact values as decimal strings when present.
+func (r WideRow) MarshalJSON() ([]byte, error) {
+	type plain WideRow
+	if r.Exact == nil {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Values []*decimal.Decimal `json:"values"`
+	}{plain(r), r.Exact})
 }
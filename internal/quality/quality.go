@@ -0,0 +1,336 @@
+// Package quality checks scraped prices before they are stored. Points that
+// fail a check are quarantined rather than stored, so that a bad upstream
+// value never reaches a price response unnoticed.
+package quality
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
+
+// Rule names a quality check.
+type Rule string
+
+const (
+	RuleInvalid Rule = "invalid" // zero, negative, NaN or infinite value
+	RuleFuture  Rule = "future"  // dated after today
+	RuleMove    Rule = "move"    // close moved more than the asset class allows in a day
+	RuleStale   Rule = "stale"   // close unchanged for too many prices in a row
+)
+
+// Class is the asset class of a series, which sets how far its close may
+// move in a day.
+type Class string
+
+const (
+	ClassFund      Class = "fund"
+	ClassStock     Class = "stock"
+	ClassIndex     Class = "index"
+	ClassFX        Class = "fx"
+	ClassCommodity Class = "commodity"
+	ClassCrypto    Class = "crypto"
+)
+
+// Classes lists every asset class.
+var Classes = []Class{ClassFund, ClassStock, ClassIndex, ClassFX, ClassCommodity, ClassCrypto}
+
+// ClassOf returns the asset class of a source's symbol, or "" for an unknown
+// source.
+func ClassOf(source, symbol string) Class {
+	switch source {
+	case "tefas":
+		return ClassFund
+	case "isyatirim":
+		return ClassStock
+	case "yahoo":
+		return classOfYahoo(strings.ToUpper(symbol))
+	default:
+		return ""
+	}
+}
+
+func classOfYahoo(symbol string) Class {
+	switch {
+	case strings.HasPrefix(symbol, "^"):
+		return ClassIndex
+	case strings.HasSuffix(symbol, "=X"):
+		return ClassFX
+	case strings.HasSuffix(symbol, "=F"):
+		return ClassCommodity
+	}
+	for _, quote := range []string{"-USD", "-USDT", "-EUR", "-TRY", "-BTC"} {
+		if strings.HasSuffix(symbol, quote) {
+			return ClassCrypto
+		}
+	}
+	return ClassStock
+}
+
+// Rules configures the checks. A zero value disables a check.
+type Rules struct {
+	RejectInvalid bool
+	RejectFuture  bool
+	// MaxMove is the largest close-to-close move per asset class, as a
+	// fraction (0.2 is 20%).
+	MaxMove map[Class]float64
+	// ConfirmRun is how many closes in a row, each within MaxMove of the one
+	// before, accept a larger move as a new level, such as after a split or
+	// a crash. Below 2 such moves stay quarantined.
+	ConfirmRun int
+	// StaleRun is how many equal closes in a row flag the last of them.
+	StaleRun int
+}
+
+// Flag is a failed check.
+type Flag struct {
+	Rule   Rule   `json:"rule"`
+	Detail string `json:"detail"`
+}
+
+// Point is a quarantined price with the checks it failed.
+type Point struct {
+	Source     string    `json:"source"`
+	Symbol     string    `json:"symbol"`
+	Date       time.Time `json:"date"`
+	Open       float64   `json:"open,omitempty"`
+	High       float64   `json:"high,omitempty"`
+	Low        float64   `json:"low,omitempty"`
+	ClosePrice float64   `json:"closePrice"`
+	AdjClose   float64   `json:"adjClose,omitempty"`
+	Volume     float64   `json:"volume,omitempty"`
+	// Reported marks the optional values the source reported.
+	Reported scraper.Field `json:"-"`
+	// Exact holds the values as published; nil for sources that publish
+	// binary floats.
+	Exact     *scraper.Exact `json:"-"`
+	Flags     []Flag         `json:"flags"`
+	JobID     int64          `json:"jobId,omitempty"`
+	FlaggedAt time.Time      `json:"flaggedAt"`
+	// Fetches counts the fetches in a row that returned this close.
+	Fetches int `json:"fetches"`
+}
+
+// MarshalJSON emits the optional values the source reported, zero included,
+// and leaves out the others.
+func (p Point) MarshalJSON() ([]byte, error) {
+	type plain Point
+	out := struct {
+		plain
+		Open     any `json:"open,omitempty"`
+		High     any `json:"high,omitempty"`
+		Low      any `json:"low,omitempty"`
+		AdjClose any `json:"adjClose,omitempty"`
+		Volume   any `json:"volume,omitempty"`
+	}{plain: plain(p)}
+	for _, v := range []struct {
+		field scraper.Field
+		dst   *any
+		value float64
+	}{
+		{scraper.FieldOpen, &out.Open, p.Open},
+		{scraper.FieldHigh, &out.High, p.High},
+		{scraper.FieldLow, &out.Low, p.Low},
+		{scraper.FieldAdjClose, &out.AdjClose, p.AdjClose},
+		{scraper.FieldVolume, &out.Volume, p.Volume},
+	} {
+		if p.Reported.Has(v.field) {
+			*v.dst = v.value
+		}
+	}
+	return json.Marshal(out)
+}
+
+// Checker applies Rules to scraped prices.
+type Checker struct {
+	rules Rules
+	now   func() time.Time
+}
+
+func NewChecker(rules Rules) *Checker {
+	return &Checker{rules: rules, now: time.Now}
+}
+
+// Rules returns the checker's rules.
+func (c *Checker) Rules() Rules { return c.rules }
+
+// HistoryLen is how many stored closes preceding the checked prices Check
+// needs to compare them with.
+func (c *Checker) HistoryLen() int {
+	return max(1, c.rules.StaleRun-1)
+}
+
+// Check splits prices, in date order, into those that pass and those that
+// are flagged. history holds the stored closes before the first price,
+// oldest first, and pending the points quarantined since the last of them,
+// also oldest first.
+// Moves and runs are measured against the accepted prices only, so a single
+// bad value does not also flag the day after it. A move that the following
+// closes confirm, as set by Rules.ConfirmRun, is accepted as a new level
+// instead, together with the pending points that were part of it.
+func (c *Checker) Check(source, symbol string, history []float64, pending []Point, prices []scraper.ScrapedPrice) (accepted []scraper.ScrapedPrice, flagged []Point) {
+	maxMove := c.rules.MaxMove[ClassOf(source, symbol)]
+	now := c.now().UTC()
+	today := now.Truncate(24 * time.Hour)
+	closes := slices.Clone(history)
+
+	sorted := slices.SortedFunc(slices.Values(prices), func(a, b scraper.ScrapedPrice) int {
+		return cmp.Compare(a.Date.Unix(), b.Date.Unix())
+	})
+	// Points quarantined earlier for a move alone may start a new level.
+	var steps []step
+	for _, p := range pending {
+		if len(sorted) > 0 && !p.Date.Before(sorted[0].Date) {
+			break
+		}
+		if len(p.Flags) > 0 && !slices.ContainsFunc(p.Flags, func(f Flag) bool { return f.Rule != RuleMove }) {
+			steps = append(steps, step{price: p.Price(), pending: true})
+		}
+	}
+	for _, p := range sorted {
+		steps = append(steps, step{price: p})
+	}
+
+	var run []int // steps at a new level, flagged only for their move
+	for i := range steps {
+		st := &steps[i]
+		p := st.price
+		if !st.pending {
+			if c.rules.RejectInvalid {
+				if detail := invalid(p); detail != "" {
+					st.flags = append(st.flags, Flag{Rule: RuleInvalid, Detail: detail})
+				}
+			}
+			if c.rules.RejectFuture && p.Date.After(today) {
+				st.flags = append(st.flags, Flag{Rule: RuleFuture, Detail: "dated after " + today.Format("2006-01-02")})
+			}
+		}
+		moved := false
+		if len(st.flags) == 0 && len(closes) > 0 {
+			prev := closes[len(closes)-1]
+			if move := p.ClosePrice/prev - 1; exceeds(prev, p.ClosePrice, maxMove) {
+				moved = true
+				st.flags = append(st.flags, Flag{Rule: RuleMove,
+					Detail: fmt.Sprintf("close moved %+.1f%% from %g, over the %g%% allowed", move*100, prev, maxMove*100)})
+			}
+			if run := equalRun(closes, p.ClosePrice) + 1; c.rules.StaleRun > 1 && run >= c.rules.StaleRun {
+				st.flags = append(st.flags, Flag{Rule: RuleStale, Detail: fmt.Sprintf("close unchanged for %d prices", run)})
+			}
+		}
+
+		if st.pending && !moved {
+			// Still a move only while the accepted level is unchanged.
+			continue
+		}
+
+		switch {
+		case len(st.flags) == 0:
+			st.accepted = true
+			closes = append(closes, p.ClosePrice)
+			run = nil
+		case moved && len(st.flags) == 1 && c.rules.ConfirmRun > 1:
+			if len(run) > 0 && exceeds(steps[run[len(run)-1]].price.ClosePrice, p.ClosePrice, maxMove) {
+				run = nil
+			}
+			run = append(run, i)
+			if len(run) < c.rules.ConfirmRun {
+				continue
+			}
+			for _, k := range run {
+				steps[k].accepted = true
+				closes = append(closes, steps[k].price.ClosePrice)
+			}
+			run = nil
+		}
+	}
+
+	for _, st := range steps {
+		switch {
+		case st.accepted:
+			accepted = append(accepted, st.price)
+		case !st.pending:
+			flagged = append(flagged, Point{
+				Source:     source,
+				Symbol:     symbol,
+				Date:       st.price.Date,
+				Open:       st.price.Open,
+				High:       st.price.High,
+				Low:        st.price.Low,
+				ClosePrice: st.price.ClosePrice,
+				AdjClose:   st.price.AdjClose,
+				Volume:     st.price.Volume,
+				Reported:   st.price.Reported,
+				Exact:      st.price.Exact,
+				Flags:      st.flags,
+				FlaggedAt:  now,
+			})
+		}
+	}
+	return accepted, flagged
+}
+
+// step is a price being checked.
+type step struct {
+	price    scraper.ScrapedPrice
+	pending  bool // quarantined by an earlier check
+	flags    []Flag
+	accepted bool
+}
+
+// Price returns the scraped price a point was made from.
+func (p Point) Price() scraper.ScrapedPrice {
+	return scraper.ScrapedPrice{
+		Date:       p.Date,
+		Open:       p.Open,
+		High:       p.High,
+		Low:        p.Low,
+		ClosePrice: p.ClosePrice,
+		AdjClose:   p.AdjClose,
+		Volume:     p.Volume,
+		Reported:   p.Reported,
+		Exact:      p.Exact,
+	}
+}
+
+// exceeds reports whether the move from prev to next is over maxMove. A zero
+// maxMove allows any move.
+func exceeds(prev, next, maxMove float64) bool {
+	return maxMove > 0 && prev > 0 && math.Abs(next/prev-1) > maxMove
+}
+
+// invalid describes what is wrong with a price's values, or returns "".
+// Zero is allowed for the optional values.
+func invalid(p scraper.ScrapedPrice) string {
+	switch c := p.ClosePrice; {
+	case math.IsNaN(c) || math.IsInf(c, 0):
+		return "close price is not a number"
+	case c == 0:
+		return "close price is zero"
+	case c < 0:
+		return "close price is negative"
+	}
+	for _, v := range []struct {
+		name  string
+		value float64
+	}{{"open", p.Open}, {"high", p.High}, {"low", p.Low}, {"adjusted close", p.AdjClose}, {"volume", p.Volume}} {
+		if math.IsNaN(v.value) || math.IsInf(v.value, 0) || v.value < 0 {
+			return v.name + " is not a valid number"
+		}
+	}
+	return ""
+}
+
+// equalRun counts the closes at the end of closes equal to c.
+func equalRun(closes []float64, c float64) int {
+	n := 0
+	for i := len(closes) - 1; i >= 0 && closes[i] == c; i-- {
+		n++
+	}
+	return n
+}
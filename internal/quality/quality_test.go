@@ -0,0 +1,143 @@
+package quality
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
+
+func TestClassOf(t *testing.T) {
+	tests := []struct {
+		source, symbol string
+		want           Class
+	}{
+		{"tefas", "YAC", ClassFund},
+		{"isyatirim", "ALTINS1", ClassStock},
+		{"yahoo", "AAPL", ClassStock},
+		{"yahoo", "THYAO.IS", ClassStock},
+		{"yahoo", "^GSPC", ClassIndex},
+		{"yahoo", "usdtry=x", ClassFX},
+		{"yahoo", "GC=F", ClassCommodity},
+		{"yahoo", "BTC-USD", ClassCrypto},
+		{"nasdaq", "AAPL", ""},
+	}
+	for _, tt := range tests {
+		if got := ClassOf(tt.source, tt.symbol); got != tt.want {
+			t.Errorf("ClassOf(%q, %q) = %q, want %q", tt.source, tt.symbol, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	c := NewChecker(Rules{
+		RejectInvalid: true,
+		RejectFuture:  true,
+		MaxMove:       map[Class]float64{ClassFund: 0.2},
+		StaleRun:      3,
+	})
+	c.now = func() time.Time { return time.Date(2024, 1, 10, 15, 0, 0, 0, time.UTC) }
+
+	prices := []scraper.ScrapedPrice{
+		{Date: day(11), ClosePrice: 1.3},          // future
+		{Date: day(2), ClosePrice: 1.1},           // +10% from history: fine
+		{Date: day(3), ClosePrice: 11},            // spike
+		{Date: day(4), ClosePrice: 1.12},          // measured from Jan 2, not the spike
+		{Date: day(5), ClosePrice: 0},             // zero
+		{Date: day(8), ClosePrice: math.NaN()},    // NaN
+		{Date: day(9), ClosePrice: 1.12},          // second equal close
+		{Date: day(10), ClosePrice: 1.12},         // third: stale
+		{Date: day(1), ClosePrice: 1, Volume: -5}, // negative volume
+	}
+	accepted, flagged := c.Check("tefas", "YAC", []float64{1.0}, nil, prices)
+
+	var acceptedDays []int
+	for _, p := range accepted {
+		acceptedDays = append(acceptedDays, p.Date.Day())
+	}
+	if want := []int{2, 4, 9}; !slices.Equal(acceptedDays, want) {
+		t.Errorf("accepted days %v, want %v", acceptedDays, want)
+	}
+
+	want := map[int]Rule{1: RuleInvalid, 3: RuleMove, 5: RuleInvalid, 8: RuleInvalid, 10: RuleStale, 11: RuleFuture}
+	if len(flagged) != len(want) {
+		t.Fatalf("expected %d flagged points, got %+v", len(want), flagged)
+	}
+	for _, p := range flagged {
+		if len(p.Flags) != 1 || p.Flags[0].Rule != want[p.Date.Day()] || p.Flags[0].Detail == "" {
+			t.Errorf("day %d: unexpected flags %+v", p.Date.Day(), p.Flags)
+		}
+		if p.Source != "tefas" || p.Symbol != "YAC" || p.FlaggedAt.IsZero() {
+			t.Errorf("day %d: unexpected point %+v", p.Date.Day(), p)
+		}
+	}
+}
+
+func TestCheck_StepChange(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	c := NewChecker(Rules{MaxMove: map[Class]float64{ClassStock: 0.5}, ConfirmRun: 3})
+	days := func(prices []scraper.ScrapedPrice) []int {
+		var out []int
+		for _, p := range prices {
+			out = append(out, p.Date.Day())
+		}
+		return out
+	}
+
+	// A spike that returns is not a new level.
+	accepted, flagged := c.Check("isyatirim", "THYAO", []float64{100}, nil, []scraper.ScrapedPrice{
+		{Date: day(2), ClosePrice: 300},
+		{Date: day(3), ClosePrice: 101},
+		{Date: day(4), ClosePrice: 102},
+	})
+	if want := []int{3, 4}; !slices.Equal(days(accepted), want) || len(flagged) != 1 || flagged[0].Date.Day() != 2 {
+		t.Errorf("spike: accepted %v, flagged %+v", days(accepted), flagged)
+	}
+
+	// A 1:10 reverse split followed by normal days is accepted once
+	// three closes agree on the new level.
+	accepted, flagged = c.Check("isyatirim", "THYAO", []float64{100}, nil, []scraper.ScrapedPrice{
+		{Date: day(2), ClosePrice: 1000},
+		{Date: day(3), ClosePrice: 1010},
+		{Date: day(4), ClosePrice: 1005},
+		{Date: day(5), ClosePrice: 1020},
+	})
+	if want := []int{2, 3, 4, 5}; !slices.Equal(days(accepted), want) || len(flagged) != 0 {
+		t.Errorf("step: accepted %v, flagged %+v", days(accepted), flagged)
+	}
+
+	// The same split scraped one day at a time: the earlier days come from
+	// quarantine.
+	accepted, flagged = c.Check("isyatirim", "THYAO", []float64{100}, nil, []scraper.ScrapedPrice{{Date: day(2), ClosePrice: 1000}})
+	if len(accepted) != 0 || len(flagged) != 1 {
+		t.Fatalf("day 2: accepted %v, flagged %+v", days(accepted), flagged)
+	}
+	pending := flagged
+	accepted, flagged = c.Check("isyatirim", "THYAO", []float64{100}, pending, []scraper.ScrapedPrice{{Date: day(3), ClosePrice: 1010}})
+	if len(accepted) != 0 || len(flagged) != 1 {
+		t.Fatalf("day 3: accepted %v, flagged %+v", days(accepted), flagged)
+	}
+	pending = append(pending, flagged...)
+	accepted, flagged = c.Check("isyatirim", "THYAO", []float64{100}, pending, []scraper.ScrapedPrice{
+		{Date: day(4), ClosePrice: 1005},
+		{Date: day(5), ClosePrice: 1020},
+	})
+	if want := []int{2, 3, 4, 5}; !slices.Equal(days(accepted), want) || len(flagged) != 0 {
+		t.Errorf("day 4: accepted %v, flagged %+v", days(accepted), flagged)
+	}
+}
+
+func TestCheck_Disabled(t *testing.T) {
+	c := NewChecker(Rules{})
+	prices := []scraper.ScrapedPrice{
+		{Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), ClosePrice: 0},
+		{Date: time.Date(2999, 1, 2, 0, 0, 0, 0, time.UTC), ClosePrice: 100},
+	}
+	accepted, flagged := c.Check("yahoo", "AAPL", []float64{1, 1, 1}, nil, prices)
+	if len(accepted) != 2 || len(flagged) != 0 {
+		t.Errorf("expected every price accepted, got %d accepted and %+v", len(accepted), flagged)
+	}
+}
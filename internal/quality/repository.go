@@ -0,0 +1,19 @@
+package quality
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Quarantine stores flagged points, replacing earlier ones of the same
+	// dates. A point with the close of the one it replaces counts one more
+	// fetch; any other starts again at one.
+	Quarantine(ctx context.Context, points []Point) error
+	// Release removes the points of the given dates, which passed a later
+	// check.
+	Release(ctx context.Context, source, symbol string, dates []time.Time) error
+	// List returns the quarantined points of a symbol in [from, to], by date.
+	// A zero to means no upper bound.
+	List(ctx context.Context, source, symbol string, from, to time.Time) ([]Point, error)
+}
@@ -0,0 +1,53 @@
+package quality
+
+import (
+	"context"
+	"fmt"
+)
+
+type Service struct {
+	repo    Repository
+	checker *Checker
+}
+
+func NewService(repo Repository, checker *Checker) *Service {
+	return &Service{repo: repo, checker: checker}
+}
+
+// GetReport lists the quarantined points of a series with the rules they
+// were checked against.
+func (s *Service) GetReport(ctx context.Context, req GetReportRequest) (*Report, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	points, err := s.repo.List(ctx, req.Source, req.Symbol, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined prices: %w", err)
+	}
+	if points == nil {
+		points = []Point{}
+	}
+
+	class := ClassOf(req.Source, req.Symbol)
+	rules := s.checker.Rules()
+	report := &Report{
+		Source: req.Source,
+		Symbol: req.Symbol,
+		Class:  class,
+		Rules: ReportRules{
+			RejectInvalid: rules.RejectInvalid,
+			RejectFuture:  rules.RejectFuture,
+			MaxMove:       rules.MaxMove[class],
+			StaleRun:      rules.StaleRun,
+		},
+		Counts: make(map[Rule]int),
+		Points: points,
+	}
+	for _, p := range points {
+		for _, f := range p.Flags {
+			report.Counts[f.Rule]++
+		}
+	}
+	return report, nil
+}
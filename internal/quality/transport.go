@@ -0,0 +1,49 @@
+package quality
+
+import (
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
+
+type GetReportRequest struct {
+	Source    string
+	Symbol    string
+	StartDate time.Time // optional
+	EndDate   time.Time // optional
+}
+
+func (r GetReportRequest) Validate() *apperror.AppError {
+	if r.Source == "" {
+		return apperror.New(apperror.BadRequest, "source is required")
+	}
+	if ClassOf(r.Source, r.Symbol) == "" {
+		return apperror.New(apperror.BadRequest, "unknown source "+r.Source)
+	}
+	if len(r.Symbol) < 2 {
+		return apperror.New(apperror.BadRequest, "symbol must be at least 2 characters")
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
+	}
+	return nil
+}
+
+// Report is the quality state of a series: the rules it is checked against
+// and the points held back by them.
+type Report struct {
+	Source string       `json:"source"`
+	Symbol string       `json:"symbol"`
+	Class  Class        `json:"class"`
+	Rules  ReportRules  `json:"rules"`
+	Counts map[Rule]int `json:"counts"` // quarantined points per failed rule
+	Points []Point      `json:"quarantined"`
+}
+
+// ReportRules are the rules that apply to the series.
+type ReportRules struct {
+	RejectInvalid bool    `json:"rejectInvalid"`
+	RejectFuture  bool    `json:"rejectFuture"`
+	MaxMove       float64 `json:"maxMove,omitempty"` // fraction; omitted when unlimited
+	StaleRun      int     `json:"staleRun,omitempty"`
+}
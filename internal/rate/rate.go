@@ -1,13 +1,104 @@
 package rate
 
-import "time"
+import (
+	"slices"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+)
 
 const PairUSDTRY = "USDTRY"
 
+// Rate providers.
+const (
+	SourceYahoo = "yahoo"
+	SourceTCMB  = "tcmb" // Central Bank of the Republic of Türkiye
+
+	DefaultSource = SourceYahoo
+)
+
+// USD is the pivot currency of Yahoo rates.
+const USD = "USD"
+
+// currencies lists supported currencies in market quoting priority: a pair
+// is always written with the higher-priority currency as its base (EURUSD,
+// USDTRY, CHFTRY, TRYJPY). XAU is a troy ounce of gold.
+var currencies = []string{"XAU", "EUR", "GBP", "USD", "CHF", "TRY", "JPY"}
+
 type Rate struct {
 	ID        int64
+	Source    string
 	Pair      string
 	Date      time.Time
 	Rate      float64
+	Bid       float64 // buying rate, when the provider publishes one
+	Ask       float64 // selling rate, when the provider publishes one
+	Exact     *Quote  // Rate, Bid and Ask without float rounding; nil for float-only providers
 	CreatedAt time.Time
 }
+
+// Quote is a rate with its bid and ask as exact decimals.
+type Quote struct {
+	Rate decimal.Decimal
+	Bid  decimal.Decimal
+	Ask  decimal.Decimal
+}
+
+// Decimals returns the values of r as exact decimals: Exact when set, or the
+// shortest decimal of each float.
+func (r Rate) Decimals() Quote {
+	if r.Exact != nil {
+		return *r.Exact
+	}
+	return Quote{Rate: decimal.FromFloat(r.Rate), Bid: decimal.FromFloat(r.Bid), Ask: decimal.FromFloat(r.Ask)}
+}
+
+// PairCoverage summarises the stored rates of one pair.
+type PairCoverage struct {
+	Source    string    `json:"source"`
+	Pair      string    `json:"pair"`
+	FirstDate time.Time `json:"firstDate"`
+	LastDate  time.Time `json:"lastDate"`
+	Count     int64     `json:"count"`
+}
+
+// Supported reports whether conversions to or from currency are available.
+func Supported(currency string) bool {
+	return slices.Contains(currencies, currency)
+}
+
+// PairOf returns the conventional pair name for two currencies, base first.
+// The quote of that pair is the number of units of the second currency per
+// unit of the first.
+func PairOf(a, b string) string {
+	if slices.Index(currencies, b) < slices.Index(currencies, a) {
+		a, b = b, a
+	}
+	return a + b
+}
+
+// Conversion converts amounts between two currencies on each day.
+type Conversion struct {
+	Source string                        // rate provider, e.g. "yahoo"
+	Pair   string                        // conventional pair, e.g. "EURTRY"
+	Path   string                        // instruments the quote was derived from, e.g. "EURUSD*USDTRY" or "GC=F*USDTRY"
+	Invert bool                          // converting from the pair's quote currency into its base
+	Rates  map[time.Time]float64         // Pair quote per day
+	Exact  map[time.Time]decimal.Decimal // Rates without float rounding in the cross
+}
+
+// Apply converts amount using the pair quote r.
+func (c *Conversion) Apply(amount, r float64) float64 {
+	if c.Invert {
+		return amount / r
+	}
+	return amount * r
+}
+
+// ApplyExact is Apply in exact decimal arithmetic.
+func (c *Conversion) ApplyExact(amount, r decimal.Decimal) decimal.Decimal {
+	if c.Invert {
+		return amount.Quo(r)
+	}
+	return amount.Mul(r)
+}
@@ -7,6 +7,7 @@ import (
 
 type Repository interface {
 	SaveRates(ctx context.Context, rates []Rate) (int64, error)
-	ListRates(ctx context.Context, pair string, from, to time.Time) ([]Rate, error)
-	ExistingDates(ctx context.Context, pair string, from, to time.Time) (map[time.Time]bool, error)
+	ListRates(ctx context.Context, source, pair string, from, to time.Time) ([]Rate, error)
+	ExistingDates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]bool, error)
+	ListPairs(ctx context.Context) ([]PairCoverage, error)
 }
@@ -2,175 +2,371 @@ package rate
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"sort"
-	"strconv"
+	"strings"
 	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
-const defaultChartEndpoint = "https://query2.finance.yahoo.com/v8/finance/chart/%s?interval=1d&period1=%s&period2=%s"
+// Provider fetches daily exchange rates from an upstream source.
+type Provider interface {
+	// Pivot is the currency the provider quotes everything against; other
+	// pairs are triangulated through it.
+	Pivot() string
+	// Supports reports whether the provider quotes currency.
+	Supports(currency string) bool
+	// Calendar is the days the provider publishes rates on.
+	Calendar() *calendar.Calendar
+	// Instrument names what the provider actually quotes for pair, for rate
+	// paths; it is pair itself unless the quote comes from another instrument.
+	Instrument(pair string) string
+	// Fetch returns the daily rates of pair over the range. It may also
+	// return rates of other pairs that came with the same response.
+	Fetch(ctx context.Context, pair string, from, to time.Time) ([]Rate, error)
+}
 
 type Service struct {
-	repo          Repository
-	client        *http.Client
-	chartEndpoint string
+	repo      Repository
+	yahoo     *yahooProvider
+	yahooOpts []httpx.Option
+	providers map[string]Provider
 }
 
 func NewService(repo Repository, opts ...Option) *Service {
 	s := &Service{
-		repo:          repo,
-		client:        http.DefaultClient,
-		chartEndpoint: defaultChartEndpoint,
+		repo:      repo,
+		yahoo:     &yahooProvider{chartEndpoint: defaultChartEndpoint},
+		providers: make(map[string]Provider),
 	}
 	for _, o := range opts {
 		o(s)
 	}
+	s.yahoo.client = httpx.New(s.yahooOpts...)
+	if _, ok := s.providers[SourceYahoo]; !ok {
+		s.providers[SourceYahoo] = s.yahoo
+	}
+	if _, ok := s.providers[SourceTCMB]; !ok {
+		s.providers[SourceTCMB] = NewTCMB()
+	}
 	return s
 }
 
 type Option func(*Service)
 
+// WithClient sets the HTTP client of the built-in Yahoo provider.
 func WithClient(c *http.Client) Option {
-	return func(s *Service) { s.client = c }
+	return func(s *Service) { s.yahooOpts = append(s.yahooOpts, httpx.WithHTTPClient(c)) }
+}
+
+// WithHTTPOptions configures timeouts, retries and circuit breaking of the
+// built-in Yahoo provider's requests.
+func WithHTTPOptions(opts ...httpx.Option) Option {
+	return func(s *Service) { s.yahooOpts = append(s.yahooOpts, opts...) }
 }
 
+// WithArchive keeps every raw response of the built-in Yahoo provider with
+// rec.
+func WithArchive(rec archive.Recorder) Option {
+	return func(s *Service) { s.yahoo.archive = rec }
+}
+
+// WithChartEndpoint sets the chart URL template of the built-in Yahoo provider.
 func WithChartEndpoint(ep string) Option {
-	return func(s *Service) { s.chartEndpoint = ep }
+	return func(s *Service) { s.yahoo.chartEndpoint = ep }
+}
+
+// WithProvider registers p under source, replacing any built-in provider of
+// that name.
+func WithProvider(source string, p Provider) Option {
+	return func(s *Service) { s.providers[source] = p }
 }
 
-// GetRates returns exchange rates for the given pair and date range.
-// It checks the DB first and scrapes missing data if needed.
-func (s *Service) GetRates(ctx context.Context, pair string, from, to time.Time) (map[time.Time]float64, error) {
-	existing, err := s.repo.ExistingDates(ctx, pair, from, to)
+func (s *Service) provider(source string) (Provider, error) {
+	if source == "" {
+		source = DefaultSource
+	}
+	p, ok := s.providers[source]
+	if !ok {
+		return nil, apperror.New(apperror.BadRequest, fmt.Sprintf("unknown rate source %q", source))
+	}
+	return p, nil
+}
+
+// GetRates returns exchange rates of a source for the given pair and date
+// range. It checks the DB first and fetches missing data if needed.
+func (s *Service) GetRates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]float64, error) {
+	rates, err := s.rates(ctx, source, pair, from, to)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[time.Time]float64, len(rates))
+	for _, r := range rates {
+		result[r.Date] = r.Rate
+	}
+	return result, nil
+}
+
+// rates returns the stored rates of a pair, fetching them first when too
+// few are stored.
+func (s *Service) rates(ctx context.Context, source, pair string, from, to time.Time) ([]Rate, error) {
+	if source == "" {
+		source = DefaultSource
+	}
+	p, err := s.provider(source)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ExistingDates(ctx, source, pair, from, to)
 	if err != nil {
 		return nil, fmt.Errorf("check existing rates: %w", err)
 	}
 
-	totalDays := countWeekdays(from, to)
+	totalDays := p.Calendar().Count(from, to)
 	coverageRatio := float64(len(existing)) / float64(max(totalDays, 1))
 
 	if coverageRatio <= 0.8 || len(existing) == 0 {
-		scraped, scrapeErr := s.fetchChart(ctx, pairToSymbol(pair), from, to)
-		if scrapeErr != nil {
-			slog.Error("failed to fetch exchange rates", "pair", pair, "error", scrapeErr)
+		fetched, fetchErr := p.Fetch(ctx, pair, from, to)
+		if fetchErr != nil {
+			slog.Error("failed to fetch exchange rates", "source", source, "pair", pair, "error", fetchErr)
 			// Fall through — use whatever we have in DB
 		} else {
-			rates := make([]Rate, 0, len(scraped))
-			for d, v := range scraped {
-				if existing[d] {
+			rates := make([]Rate, 0, len(fetched))
+			for _, r := range fetched {
+				if r.Pair == pair && existing[r.Date] {
 					continue
 				}
-				rates = append(rates, Rate{Pair: pair, Date: d, Rate: v})
+				r.Source = source
+				rates = append(rates, r)
 			}
 			if len(rates) > 0 {
 				n, saveErr := s.repo.SaveRates(ctx, rates)
 				if saveErr != nil {
 					return nil, fmt.Errorf("save rates: %w", saveErr)
 				}
-				slog.Info("saved exchange rates", "pair", pair, "new", n)
+				slog.Info("saved exchange rates", "source", source, "pair", pair, "new", n)
 			}
 		}
 	}
 
 	// Fetch all rates from DB
-	dbRates, err := s.repo.ListRates(ctx, pair, from, to)
+	dbRates, err := s.repo.ListRates(ctx, source, pair, from, to)
 	if err != nil {
 		return nil, fmt.Errorf("list rates: %w", err)
 	}
+	return dbRates, nil
+}
 
-	result := make(map[time.Time]float64, len(dbRates))
-	for _, r := range dbRates {
-		result[r.Date] = r.Rate
+// ListRates returns the daily rate of a pair, fetching or triangulating it
+// like a price conversion would.
+func (s *Service) ListRates(ctx context.Context, req GetRatesRequest) (*GetRatesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
-	return result, nil
-}
 
-// chartResponse is the minimal Yahoo v8 chart API response structure.
-type chartResponse struct {
-	Chart struct {
-		Result []struct {
-			Timestamp  []int64 `json:"timestamp"`
-			Indicators struct {
-				Quote []struct {
-					Close []float64 `json:"close"`
-				} `json:"quote"`
-			} `json:"indicators"`
-		} `json:"result"`
-		Error *struct {
-			Code        string `json:"code"`
-			Description string `json:"description"`
-		} `json:"error"`
-	} `json:"chart"`
-}
-
-// fetchChart fetches daily close prices from the Yahoo v8 chart API.
-func (s *Service) fetchChart(ctx context.Context, symbol string, from, to time.Time) (map[time.Time]float64, error) {
-	url := fmt.Sprintf(s.chartEndpoint, symbol,
-		strconv.FormatInt(from.Unix(), 10),
-		strconv.FormatInt(to.Unix(), 10))
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	endDate := req.EndDate
+	if endDate.IsZero() {
+		endDate = time.Now().Truncate(24 * time.Hour)
+	}
+
+	conv, err := s.GetConversion(ctx, req.RateSource, req.Pair[:3], req.Pair[3:], req.StartDate, endDate)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("User-Agent", "Mozilla/5.0")
 
-	res, err := s.client.Do(req) //nolint:gosec // URL built from internal config
+	// Bid and ask are only meaningful for a stored pair, not a cross.
+	spreads := map[time.Time]Rate{}
+	if !strings.Contains(conv.Path, "*") {
+		stored, err := s.repo.ListRates(ctx, conv.Source, conv.Pair, req.StartDate, endDate)
+		if err != nil {
+			return nil, fmt.Errorf("list rates: %w", err)
+		}
+		for _, r := range stored {
+			if r.Bid > 0 && r.Ask > 0 {
+				spreads[r.Date] = r
+			}
+		}
+	}
+
+	dates := make([]time.Time, 0, len(conv.Rates))
+	quotes := conv.Rates
+	if req.Fill == FillFFill {
+		p, err := s.provider(conv.Source)
+		if err != nil {
+			return nil, err
+		}
+		dates = p.Calendar().TradingDays(req.StartDate, endDate)
+		quotes = ForwardFill(conv.Rates, dates)
+	} else {
+		for d := range conv.Rates {
+			dates = append(dates, d)
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	}
+
+	points := make([]RatePoint, 0, len(dates))
+	for _, d := range dates {
+		q, ok := quotes[d]
+		if !ok || q <= 0 {
+			continue
+		}
+		_, stored := conv.Rates[d]
+		pt := RatePoint{Date: d, Rate: conv.Apply(1, q), Filled: !stored}
+		if sp, ok := spreads[d]; ok && stored {
+			pt.Bid, pt.Ask = sp.Bid, sp.Ask
+			if conv.Invert {
+				// Selling the base of the inverse pair is buying this one.
+				pt.Bid, pt.Ask = 1/sp.Ask, 1/sp.Bid
+			}
+		}
+		points = append(points, pt)
+	}
+
+	return &GetRatesResponse{Source: conv.Source, Pair: req.Pair, Path: conv.Path, Rates: points}, nil
+}
+
+// ListPairs reports the pairs with stored rates and their date coverage.
+func (s *Service) ListPairs(ctx context.Context) ([]PairCoverage, error) {
+	pairs, err := s.repo.ListPairs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list pairs: %w", err)
+	}
+	return pairs, nil
+}
+
+// GetConversion returns daily quotes of a rate source for converting from one
+// currency into another over the range. A stored direct pair is used when
+// available; otherwise the quote is triangulated through the source's pivot
+// currency (EURTRY = EURUSD*USDTRY on Yahoo).
+func (s *Service) GetConversion(ctx context.Context, source, from, to string, start, end time.Time) (*Conversion, error) {
+	if !Supported(from) || !Supported(to) {
+		return nil, fmt.Errorf("unsupported conversion %s to %s", from, to)
+	}
+	if source == "" {
+		source = DefaultSource
+	}
+	p, err := s.provider(source)
 	if err != nil {
 		return nil, err
 	}
-	defer func() { _ = res.Body.Close() }()
+	for _, cur := range []string{from, to} {
+		if !p.Supports(cur) {
+			return nil, apperror.New(apperror.BadRequest, fmt.Sprintf("rate source %s does not publish %s", source, cur))
+		}
+	}
+
+	pair := PairOf(from, to)
+	c := &Conversion{Source: source, Pair: pair, Path: p.Instrument(pair), Invert: !strings.HasPrefix(pair, from)}
 
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("yahoo chart returned HTTP %d for %s", res.StatusCode, symbol)
+	base, quote := pair[:3], pair[3:]
+	pivot := p.Pivot()
+	direct := base == pivot || quote == pivot
+	if !direct {
+		existing, err := s.repo.ExistingDates(ctx, source, pair, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("check existing rates: %w", err)
+		}
+		direct = len(existing) > 0
 	}
 
-	body, err := io.ReadAll(res.Body)
+	if direct {
+		c.Exact, err = s.exactRates(ctx, source, pair, start, end)
+		if err != nil {
+			return nil, err
+		}
+		c.Rates = make(map[time.Time]float64, len(c.Exact))
+		for d, v := range c.Exact {
+			c.Rates[d] = v.Float64()
+		}
+		return c, nil
+	}
+
+	// base -> pivot -> quote
+	first, second := PairOf(base, pivot), PairOf(pivot, quote)
+	r1, err := s.exactRates(ctx, source, first, start, end)
 	if err != nil {
 		return nil, err
 	}
-
-	var cr chartResponse
-	if err := json.Unmarshal(body, &cr); err != nil {
-		return nil, fmt.Errorf("parse chart response: %w", err)
+	r2, err := s.exactRates(ctx, source, second, start, end)
+	if err != nil {
+		return nil, err
 	}
 
-	if cr.Chart.Error != nil {
-		return nil, fmt.Errorf("yahoo chart error: %s: %s", cr.Chart.Error.Code, cr.Chart.Error.Description)
+	c.Path = legName(p, first, base) + "*" + legName(p, second, pivot)
+	c.Exact = cross(r1, r2, strings.HasPrefix(first, base), strings.HasPrefix(second, pivot))
+	c.Rates = make(map[time.Time]float64, len(c.Exact))
+	for d, v := range c.Exact {
+		c.Rates[d] = v.Float64()
 	}
+	return c, nil
+}
 
-	if len(cr.Chart.Result) == 0 {
-		return nil, fmt.Errorf("yahoo chart returned no results for %s", symbol)
+// exactRates is GetRates without float rounding.
+func (s *Service) exactRates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]decimal.Decimal, error) {
+	rates, err := s.rates(ctx, source, pair, from, to)
+	if err != nil {
+		return nil, err
 	}
+	result := make(map[time.Time]decimal.Decimal, len(rates))
+	for _, r := range rates {
+		result[r.Date] = r.Decimals().Rate
+	}
+	return result, nil
+}
 
-	r := cr.Chart.Result[0]
-	if len(r.Indicators.Quote) == 0 {
-		return nil, fmt.Errorf("yahoo chart returned no quote data for %s", symbol)
+// legName formats a leg of a triangulated path; legs used against their
+// quoting direction are written as a reciprocal.
+func legName(p Provider, pair, from string) string {
+	if strings.HasPrefix(pair, from) {
+		return p.Instrument(pair)
 	}
+	return "1/" + p.Instrument(pair)
+}
 
-	timestamps := r.Timestamp
-	closes := r.Indicators.Quote[0].Close
+// cross multiplies two legs on every day either of them has a quote, carrying
+// the other leg's last known quote forward. The product is exact so that the
+// float quote is rounded once rather than per leg.
+func cross(r1, r2 map[time.Time]decimal.Decimal, direct1, direct2 bool) map[time.Time]decimal.Decimal {
+	dates := make([]time.Time, 0, len(r1)+len(r2))
+	for d := range r1 {
+		dates = append(dates, d)
+	}
+	for d := range r2 {
+		if _, ok := r1[d]; !ok {
+			dates = append(dates, d)
+		}
+	}
+	f1 := ForwardFill(r1, dates)
+	f2 := ForwardFill(r2, dates)
 
-	rates := make(map[time.Time]float64, len(timestamps))
-	for i, ts := range timestamps {
-		if i >= len(closes) || closes[i] == 0 {
+	result := make(map[time.Time]decimal.Decimal, len(dates))
+	for _, d := range dates {
+		v1, v2 := f1[d], f2[d]
+		if v1.Sign() <= 0 || v2.Sign() <= 0 {
 			continue
 		}
-		d := time.Unix(ts, 0).UTC().Truncate(24 * time.Hour)
-		rates[d] = closes[i]
+		if !direct1 {
+			v1 = v1.Inv()
+		}
+		if !direct2 {
+			v2 = v2.Inv()
+		}
+		result[d] = v1.Mul(v2)
 	}
-
-	slog.Info("fetched exchange rates from chart API", "symbol", symbol, "count", len(rates))
-	return rates, nil
+	return result
 }
 
 // ForwardFill returns a rate for each date in dates, using the nearest prior rate
 // when no exact match exists.
-func ForwardFill(rates map[time.Time]float64, dates []time.Time) map[time.Time]float64 {
+func ForwardFill[V any](rates map[time.Time]V, dates []time.Time) map[time.Time]V {
 	// Collect and sort all known rate dates
 	sortedDates := make([]time.Time, 0, len(rates))
 	for d := range rates {
@@ -178,7 +374,7 @@ func ForwardFill(rates map[time.Time]float64, dates []time.Time) map[time.Time]f
 	}
 	sort.Slice(sortedDates, func(i, j int) bool { return sortedDates[i].Before(sortedDates[j]) })
 
-	result := make(map[time.Time]float64, len(dates))
+	result := make(map[time.Time]V, len(dates))
 	for _, d := range dates {
 		if r, ok := rates[d]; ok {
 			result[d] = r
@@ -194,21 +390,3 @@ func ForwardFill(rates map[time.Time]float64, dates []time.Time) map[time.Time]f
 	}
 	return result
 }
-
-func pairToSymbol(pair string) string {
-	if pair == PairUSDTRY {
-		return "USDTRY=X"
-	}
-	return pair + "=X"
-}
-
-func countWeekdays(from, to time.Time) int {
-	count := 0
-	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
-		wd := d.Weekday()
-		if wd != time.Saturday && wd != time.Sunday {
-			count++
-		}
-	}
-	return count
-}
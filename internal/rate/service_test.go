@@ -0,0 +1,210 @@
+package rate
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+type mockRepo struct {
+	rates []Rate
+}
+
+func (m *mockRepo) SaveRates(_ context.Context, rates []Rate) (int64, error) {
+	m.rates = append(m.rates, rates...)
+	return int64(len(rates)), nil
+}
+
+func (m *mockRepo) ListRates(_ context.Context, source, pair string, _, _ time.Time) ([]Rate, error) {
+	var out []Rate
+	for _, r := range m.rates {
+		src := r.Source
+		if src == "" {
+			src = DefaultSource
+		}
+		if src == source && r.Pair == pair {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockRepo) ExistingDates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]bool, error) {
+	rates, _ := m.ListRates(ctx, source, pair, from, to)
+	dates := make(map[time.Time]bool, len(rates))
+	for _, r := range rates {
+		dates[r.Date] = true
+	}
+	return dates, nil
+}
+
+func (m *mockRepo) ListPairs(_ context.Context) ([]PairCoverage, error) {
+	return nil, nil
+}
+
+var (
+	day1 = time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	day2 = time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+)
+
+func seeded(rates ...Rate) *Service {
+	return NewService(&mockRepo{rates: rates})
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestPairOf(t *testing.T) {
+	tests := []struct{ a, b, want string }{
+		{"TRY", "USD", "USDTRY"},
+		{"EUR", "TRY", "EURTRY"},
+		{"TRY", "JPY", "TRYJPY"},
+		{"CHF", "USD", "USDCHF"},
+		{"USD", "XAU", "XAUUSD"},
+	}
+	for _, tt := range tests {
+		if got := PairOf(tt.a, tt.b); got != tt.want {
+			t.Errorf("PairOf(%s, %s) = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGetConversion_Triangulated(t *testing.T) {
+	svc := seeded(
+		Rate{Pair: "EURUSD", Date: day1, Rate: 1.10},
+		Rate{Pair: "EURUSD", Date: day2, Rate: 1.20},
+		Rate{Pair: "USDTRY", Date: day1, Rate: 30},
+		Rate{Pair: "USDTRY", Date: day2, Rate: 30},
+	)
+
+	c, err := svc.GetConversion(context.Background(), "", "TRY", "EUR", day1, day2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Pair != "EURTRY" || c.Path != "EURUSD*USDTRY" || !c.Invert {
+		t.Errorf("unexpected conversion: pair=%s path=%s invert=%v", c.Pair, c.Path, c.Invert)
+	}
+	if !approx(c.Rates[day1], 33) || !approx(c.Rates[day2], 36) {
+		t.Errorf("unexpected rates: %v", c.Rates)
+	}
+	if got := c.Apply(66, c.Rates[day1]); !approx(got, 2) {
+		t.Errorf("expected 66 TRY = 2 EUR, got %f", got)
+	}
+}
+
+func TestGetConversion_ReciprocalLeg(t *testing.T) {
+	svc := seeded(
+		Rate{Pair: "USDCHF", Date: day1, Rate: 0.8},
+		Rate{Pair: "USDCHF", Date: day2, Rate: 0.8},
+		Rate{Pair: "USDTRY", Date: day1, Rate: 32},
+		Rate{Pair: "USDTRY", Date: day2, Rate: 32},
+	)
+
+	c, err := svc.GetConversion(context.Background(), "", "CHF", "TRY", day1, day2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Path != "1/USDCHF*USDTRY" || c.Invert {
+		t.Errorf("unexpected conversion: path=%s invert=%v", c.Path, c.Invert)
+	}
+	if !approx(c.Rates[day1], 40) {
+		t.Errorf("expected CHFTRY 40, got %f", c.Rates[day1])
+	}
+}
+
+func TestGetConversion_PrefersStoredDirectPair(t *testing.T) {
+	svc := seeded(
+		Rate{Pair: "EURTRY", Date: day1, Rate: 35},
+		Rate{Pair: "EURTRY", Date: day2, Rate: 35},
+	)
+
+	c, err := svc.GetConversion(context.Background(), "", "EUR", "TRY", day1, day2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Path != "EURTRY" || c.Rates[day1] != 35 {
+		t.Errorf("expected direct EURTRY, got path=%s rates=%v", c.Path, c.Rates)
+	}
+}
+
+func TestGetConversion_GoldPathNamesFuture(t *testing.T) {
+	svc := seeded(
+		Rate{Pair: "XAUUSD", Date: day1, Rate: 2000},
+		Rate{Pair: "USDTRY", Date: day1, Rate: 30},
+	)
+
+	c, err := svc.GetConversion(context.Background(), "", "XAU", "TRY", day1, day1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Path != "GC=F*USDTRY" || !approx(c.Rates[day1], 60000) {
+		t.Errorf("unexpected conversion: path=%s rates=%v", c.Path, c.Rates)
+	}
+
+	c, err = svc.GetConversion(context.Background(), "", "USD", "XAU", day1, day1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Path != "GC=F" || !c.Invert {
+		t.Errorf("unexpected conversion: path=%s invert=%v", c.Path, c.Invert)
+	}
+}
+
+func TestGetConversion_Unsupported(t *testing.T) {
+	if _, err := seeded().GetConversion(context.Background(), "", "TRY", "AUD", day1, day2); err == nil {
+		t.Fatal("expected error for unsupported currency")
+	}
+}
+
+func TestListRates_ForwardFillAndInverse(t *testing.T) {
+	// Six weekdays with Jan 3 missing: coverage stays above the refetch threshold.
+	var rates []Rate
+	for _, d := range []int{2, 4, 5, 8, 9} {
+		rates = append(rates, Rate{Pair: PairUSDTRY, Date: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC), Rate: float64(30 + d)})
+	}
+	svc := seeded(rates...)
+	req := GetRatesRequest{Pair: "TRYUSD", StartDate: day1, EndDate: time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC), Fill: FillFFill}
+
+	resp, err := svc.ListRates(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Path != PairUSDTRY || len(resp.Rates) != 6 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	filled := resp.Rates[1]
+	if !filled.Date.Equal(day2) || !filled.Filled || filled.Rate != 1.0/32 {
+		t.Errorf("expected Jan 3 carried from Jan 2 as 1/32, got %+v", filled)
+	}
+	if resp.Rates[2].Filled || resp.Rates[2].Rate != 1.0/34 {
+		t.Errorf("unexpected stored rate: %+v", resp.Rates[2])
+	}
+
+	req.Fill = FillNone
+	resp, err = svc.ListRates(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Rates) != 5 {
+		t.Errorf("expected 5 stored rates without fill, got %d", len(resp.Rates))
+	}
+}
+
+func TestGetRatesRequest_Validate(t *testing.T) {
+	base := GetRatesRequest{Pair: "USDTRY", StartDate: day1}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, pair := range []string{"USD", "USDUSD", "USDAUD", "usdtry"} {
+		req := base
+		req.Pair = pair
+		if err := req.Validate(); err == nil {
+			t.Errorf("expected error for pair %q", pair)
+		}
+	}
+	req := base
+	req.Fill = "bfill"
+	if err := req.Validate(); err == nil {
+		t.Error("expected error for unknown fill")
+	}
+}
@@ -0,0 +1,243 @@
+package rate
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
+)
+
+const defaultTCMBBaseURL = "https://www.tcmb.gov.tr"
+
+// istanbul is the time zone bulletins are dated in. Türkiye has stayed on
+// UTC+3 all year since 2016.
+var istanbul = time.FixedZone("TRT", 3*60*60)
+
+// TCMB fetches the official indicative exchange rates published daily by the
+// Central Bank of the Republic of Türkiye. Every currency is quoted against
+// TRY; the forex buying and selling rates are kept as bid and ask, and the
+// bid is used as the rate, also for pairs quoted against TRY.
+//
+// Rates come from one XML bulletin per day: /kurlar/today.xml for the current
+// day and /kurlar/YYYYMM/DDMMYYYY.xml for earlier ones. TCMB has no range
+// endpoint, so only the bank's business days up to today are requested; a
+// day that still returns 404, such as an unlisted closure, is skipped.
+type TCMB struct {
+	workers  int
+	client   *httpx.Client
+	httpOpts []httpx.Option
+	archive  archive.Recorder
+	baseURL  string
+	now      func() time.Time
+}
+
+func NewTCMB(opts ...TCMBOption) *TCMB {
+	t := &TCMB{
+		workers: 5,
+		baseURL: defaultTCMBBaseURL,
+		now:     time.Now,
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	t.client = httpx.New(t.httpOpts...)
+	return t
+}
+
+type TCMBOption func(*TCMB)
+
+func WithTCMBClient(c *http.Client) TCMBOption {
+	return func(t *TCMB) { t.httpOpts = append(t.httpOpts, httpx.WithHTTPClient(c)) }
+}
+
+// WithTCMBHTTPOptions configures timeouts, retries and circuit breaking of
+// bulletin requests.
+func WithTCMBHTTPOptions(opts ...httpx.Option) TCMBOption {
+	return func(t *TCMB) { t.httpOpts = append(t.httpOpts, opts...) }
+}
+
+// WithTCMBArchive keeps every raw bulletin with rec.
+func WithTCMBArchive(rec archive.Recorder) TCMBOption {
+	return func(t *TCMB) { t.archive = rec }
+}
+
+func WithTCMBBaseURL(u string) TCMBOption {
+	return func(t *TCMB) { t.baseURL = strings.TrimRight(u, "/") }
+}
+
+func WithTCMBWorkers(n int) TCMBOption {
+	return func(t *TCMB) { t.workers = n }
+}
+
+func (t *TCMB) Pivot() string { return "TRY" }
+
+func (t *TCMB) Instrument(pair string) string { return pair }
+
+// Supports reports whether TCMB publishes a TRY rate for currency. It does
+// not publish gold.
+func (t *TCMB) Supports(currency string) bool {
+	return currency != "XAU" && Supported(currency)
+}
+
+// Calendar is the bank's business calendar; no bulletin is published on
+// Turkish public holidays.
+func (t *TCMB) Calendar() *calendar.Calendar { return calendar.TCMB }
+
+// tcmbBulletin is the subset of a TCMB daily rates XML that we use.
+type tcmbBulletin struct {
+	Date       string `xml:"Tarih,attr"` // "02.01.2025"
+	Currencies []struct {
+		Code         string `xml:"CurrencyCode,attr"`
+		Unit         string `xml:"Unit"`
+		ForexBuying  string `xml:"ForexBuying"`
+		ForexSelling string `xml:"ForexSelling"`
+	} `xml:"Currency"`
+}
+
+// Fetch returns the rates of every supported currency against TRY on each
+// published day in the range, not only those of pair, since each bulletin
+// carries all of them.
+func (t *TCMB) Fetch(ctx context.Context, pair string, from, to time.Time) ([]Rate, error) {
+	if len(pair) != 6 || (pair[:3] != "TRY" && pair[3:] != "TRY") {
+		return nil, fmt.Errorf("tcmb only publishes rates against TRY, not %s", pair)
+	}
+	if !t.Supports(pair[:3]) || !t.Supports(pair[3:]) {
+		return nil, fmt.Errorf("tcmb does not publish %s", pair)
+	}
+
+	// Bulletins are not published ahead.
+	today := t.today()
+	if to.After(today) {
+		to = today
+	}
+
+	var (
+		mu  sync.Mutex
+		all []Rate
+		// Until today's bulletin is out, today.xml carries the one before.
+		seen = make(map[time.Time]bool)
+	)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(t.workers)
+
+	for _, d := range t.Calendar().TradingDays(from, to) {
+		g.Go(func() error {
+			rates, err := t.fetchDay(ctx, d, d.Equal(today))
+			if err != nil || len(rates) == 0 {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if date := rates[0].Date; !seen[date] {
+				seen[date] = true
+				all = append(all, rates...)
+			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	slog.Info("fetched exchange rates from tcmb", "pair", pair, "count", len(all))
+	return all, nil
+}
+
+// today returns the current date in Istanbul, at midnight UTC like the dates
+// rates are stored under.
+func (t *TCMB) today() time.Time {
+	now := t.now().In(istanbul)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// fetchDay returns the rates of one bulletin, or nothing when no bulletin was
+// published that day. today.xml serves the current day's bulletin.
+func (t *TCMB) fetchDay(ctx context.Context, day time.Time, today bool) ([]Rate, error) {
+	url := t.baseURL + "/kurlar/" + day.Format("200601") + "/" + day.Format("02012006") + ".xml"
+	if today {
+		url = t.baseURL + "/kurlar/today.xml"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		var statusErr *httpx.StatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("tcmb bulletin %s: %w", day.Format(time.DateOnly), err)
+	}
+	archive.Record(ctx, t.archive, &archive.Entry{
+		Source:     SourceTCMB,
+		Dataset:    archive.DatasetRates,
+		Method:     req.Method,
+		URL:        url,
+		StatusCode: res.StatusCode,
+		Body:       res.Body,
+	})
+	rates, err := parseBulletin(res.Body)
+	if err != nil {
+		return nil, &httpx.ParseError{Host: req.URL.Host, Err: err}
+	}
+	return rates, nil
+}
+
+func parseBulletin(body []byte) ([]Rate, error) {
+	var b tcmbBulletin
+	if err := xml.Unmarshal(body, &b); err != nil {
+		return nil, fmt.Errorf("parse tcmb bulletin: %w", err)
+	}
+	date, err := time.Parse("02.01.2006", b.Date)
+	if err != nil {
+		return nil, fmt.Errorf("parse tcmb bulletin date %q: %w", b.Date, err)
+	}
+
+	var rates []Rate
+	for _, c := range b.Currencies {
+		if c.Code == "TRY" || !Supported(c.Code) || c.Code == "XAU" {
+			continue
+		}
+		unit, err1 := decimal.Parse(c.Unit)
+		buying, err2 := decimal.Parse(c.ForexBuying)
+		selling, err3 := decimal.Parse(c.ForexSelling)
+		if err1 != nil || err2 != nil || err3 != nil || unit.Sign() <= 0 || buying.Sign() <= 0 || selling.Sign() <= 0 {
+			continue
+		}
+
+		var q Quote
+		pair := PairOf(c.Code, "TRY")
+		if strings.HasPrefix(pair, "TRY") {
+			// Quoted the other way round (TRYJPY): selling one TRY buys
+			// Unit/selling JPY, buying one back costs Unit/buying.
+			q = Quote{Rate: unit.Quo(selling), Bid: unit.Quo(selling), Ask: unit.Quo(buying)}
+		} else {
+			q = Quote{Rate: buying.Quo(unit), Bid: buying.Quo(unit), Ask: selling.Quo(unit)}
+		}
+		rates = append(rates, Rate{
+			Source: SourceTCMB,
+			Pair:   pair,
+			Date:   date,
+			Rate:   q.Rate.Float64(),
+			Bid:    q.Bid.Float64(),
+			Ask:    q.Ask.Float64(),
+			Exact:  &q,
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,208 @@
+package rate
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+const tcmbBulletinXML = `<?xml version="1.0" encoding="UTF-8"?>
+<Tarih_Date Tarih="%s" Date="%s" Bulten_No="2025/1">
+	<Currency CrossOrder="0" Kod="USD" CurrencyCode="USD">
+		<Unit>1</Unit>
+		<Isim>ABD DOLARI</Isim>
+		<CurrencyName>US DOLLAR</CurrencyName>
+		<ForexBuying>%s</ForexBuying>
+		<ForexSelling>35.3438</ForexSelling>
+	</Currency>
+	<Currency CrossOrder="9" Kod="EUR" CurrencyCode="EUR">
+		<Unit>1</Unit>
+		<ForexBuying>36.5000</ForexBuying>
+		<ForexSelling>36.6000</ForexSelling>
+	</Currency>
+	<Currency CrossOrder="11" Kod="JPY" CurrencyCode="JPY">
+		<Unit>100</Unit>
+		<ForexBuying>22.4000</ForexBuying>
+		<ForexSelling>22.5000</ForexSelling>
+	</Currency>
+	<Currency CrossOrder="12" Kod="XDR" CurrencyCode="XDR">
+		<Unit>1</Unit>
+		<ForexBuying>46.0000</ForexBuying>
+		<ForexSelling></ForexSelling>
+	</Currency>
+</Tarih_Date>`
+
+// newTCMBServer serves a bulletin for 2025-01-02 and today.xml, and 404 for
+// every other day as TCMB does on holidays.
+func newTCMBServer(t *testing.T, today time.Time) *httptest.Server {
+	t.Helper()
+	bulletin := func(d time.Time, usd string) string {
+		return fmt.Sprintf(tcmbBulletinXML, d.Format("02.01.2006"), d.Format("01/02/2006"), usd)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/kurlar/202501/02012025.xml":
+			_, _ = w.Write([]byte(bulletin(time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), "35.2802")))
+		case "/kurlar/today.xml":
+			_, _ = w.Write([]byte(bulletin(today, "36.0000")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTCMB_Fetch(t *testing.T) {
+	today := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC) // Monday
+	srv := newTCMBServer(t, today)
+	p := NewTCMB(WithTCMBBaseURL(srv.URL))
+	p.now = func() time.Time { return today.Add(16 * time.Hour) }
+
+	// Jan 1 is a holiday (404), Jan 3 is missing, Jan 4-5 are a weekend.
+	rates, err := p.Fetch(context.Background(), "USDTRY", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), today)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]Rate)
+	for _, r := range rates {
+		got[r.Pair+" "+r.Date.Format(time.DateOnly)] = r
+	}
+	if len(got) != 6 {
+		t.Fatalf("expected USD, EUR and JPY on two days, got %v", got)
+	}
+
+	usd := got["USDTRY 2025-01-02"]
+	if usd.Source != SourceTCMB || usd.Rate != 35.2802 || usd.Bid != 35.2802 || usd.Ask != 35.3438 {
+		t.Errorf("USDTRY = %+v", usd)
+	}
+	if q := usd.Exact; q == nil || q.Rate.String() != "35.2802" || q.Ask.String() != "35.3438" {
+		t.Errorf("USDTRY exact = %+v, want the published decimals", q)
+	}
+	if r := got["USDTRY 2025-01-06"]; r.Rate != 36 {
+		t.Errorf("today's USDTRY = %v, want 36 from today.xml", r.Rate)
+	}
+
+	jpy := got["TRYJPY 2025-01-02"]
+	if !approx(jpy.Rate, 100/22.5) || !approx(jpy.Bid, 100/22.5) || !approx(jpy.Ask, 100/22.4) {
+		t.Errorf("TRYJPY = %+v, want JPY per TRY from a 100-unit quote", jpy)
+	}
+}
+
+func TestTCMB_FetchRequests(t *testing.T) {
+	var paths []string
+	var mu sync.Mutex
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		if r.URL.Path == "/kurlar/today.xml" {
+			// Not out yet: still the previous bulletin.
+			d := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+			_, _ = fmt.Fprintf(w, tcmbBulletinXML, d.Format("02.01.2006"), d.Format("01/02/2006"), "36.0000")
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	p := NewTCMB(WithTCMBBaseURL(srv.URL))
+	// Still Jan 6 in UTC, already Jan 7 in Istanbul.
+	p.now = func() time.Time { return time.Date(2025, 1, 6, 22, 30, 0, 0, time.UTC) }
+
+	// Jan 1 is a holiday, Jan 4-5 a weekend and Jan 8-10 not published yet.
+	rates, err := p.Fetch(context.Background(), "USDTRY",
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(paths)
+	want := []string{
+		"/kurlar/202501/02012025.xml",
+		"/kurlar/202501/03012025.xml",
+		"/kurlar/202501/06012025.xml",
+		"/kurlar/today.xml",
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("requested %v, want %v", paths, want)
+	}
+	if len(rates) != 3 || !rates[0].Date.Equal(time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the Jan 6 bulletin from today.xml, got %+v", rates)
+	}
+}
+
+func TestTCMB_Unsupported(t *testing.T) {
+	p := NewTCMB(WithTCMBBaseURL("http://invalid.test"))
+	if _, err := p.Fetch(context.Background(), "XAUUSD", day1, day2); err == nil {
+		t.Error("expected error for a pair TCMB does not publish")
+	}
+
+	svc := NewService(&mockRepo{}, WithProvider(SourceTCMB, p))
+	if _, err := svc.GetConversion(context.Background(), SourceTCMB, "XAU", "TRY", day1, day2); err == nil {
+		t.Error("expected error converting gold with tcmb rates")
+	}
+}
+
+func TestListRates_TCMBSource(t *testing.T) {
+	today := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	srv := newTCMBServer(t, today)
+	p := NewTCMB(WithTCMBBaseURL(srv.URL))
+	p.now = func() time.Time { return today }
+
+	repo := &mockRepo{}
+	svc := NewService(repo, WithProvider(SourceTCMB, p))
+	from := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	resp, err := svc.ListRates(context.Background(), GetRatesRequest{
+		Pair: "TRYUSD", StartDate: from, EndDate: from, RateSource: SourceTCMB,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Source != SourceTCMB || resp.Path != "USDTRY" || len(resp.Rates) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	pt := resp.Rates[0]
+	if !approx(pt.Rate, 1/35.2802) || !approx(pt.Bid, 1/35.3438) || !approx(pt.Ask, 1/35.2802) {
+		t.Errorf("TRYUSD = %+v, want inverse of USDTRY with bid and ask swapped", pt)
+	}
+
+	// A cross is triangulated through TRY from the same bulletin.
+	resp, err = svc.ListRates(context.Background(), GetRatesRequest{
+		Pair: "EURUSD", StartDate: from, EndDate: from, RateSource: SourceTCMB,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Path != "EURTRY*1/USDTRY" || len(resp.Rates) != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+	if pt := resp.Rates[0]; !approx(pt.Rate, 36.5/35.2802) || pt.Bid != 0 {
+		t.Errorf("EURUSD = %+v", pt)
+	}
+
+	// Forward filling follows the bank's calendar: New Year's Day is not
+	// filled, Jan 3 is.
+	resp, err = svc.ListRates(context.Background(), GetRatesRequest{
+		Pair: "USDTRY", StartDate: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), EndDate: from.AddDate(0, 0, 1),
+		RateSource: SourceTCMB, Fill: FillFFill,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rates) != 2 || !resp.Rates[0].Date.Equal(from) || !resp.Rates[1].Filled {
+		t.Errorf("expected Jan 2 and a filled Jan 3, got %+v", resp.Rates)
+	}
+
+	for _, r := range repo.rates {
+		if r.Source != SourceTCMB {
+			t.Errorf("rate stored under source %q", r.Source)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package rate
+
+import (
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
+
+const (
+	FillNone  = "none"
+	FillFFill = "ffill"
+)
+
+type GetRatesRequest struct {
+	Pair      string // e.g. "USDTRY": units of the second currency per one of the first
+	StartDate time.Time
+	EndDate   time.Time
+	Format    string // "json" or "csv"
+	Fill      string // "none" (default) or "ffill": carry the last rate over missing weekdays
+
+	RateSource string // "yahoo" (default) or "tcmb"
+}
+
+func (r GetRatesRequest) Validate() *apperror.AppError {
+	if len(r.Pair) != 6 || !Supported(r.Pair[:3]) || !Supported(r.Pair[3:]) || r.Pair[:3] == r.Pair[3:] {
+		return apperror.New(apperror.BadRequest, "pair must be two different currencies of TRY, USD, EUR, GBP, CHF, JPY, XAU, e.g. USDTRY")
+	}
+	if r.StartDate.IsZero() {
+		return apperror.New(apperror.BadRequest, "startDate is required")
+	}
+	if !r.EndDate.IsZero() && r.EndDate.Before(r.StartDate) {
+		return apperror.New(apperror.BadRequest, "endDate must be after startDate")
+	}
+	if r.Format != "" && r.Format != "json" && r.Format != "csv" {
+		return apperror.New(apperror.BadRequest, "format must be json or csv")
+	}
+	if r.Fill != "" && r.Fill != FillNone && r.Fill != FillFFill {
+		return apperror.New(apperror.BadRequest, "fill must be none or ffill")
+	}
+	return ValidateSource(r.RateSource)
+}
+
+// ValidateSource checks a rateSource parameter; empty selects DefaultSource.
+func ValidateSource(source string) *apperror.AppError {
+	if source != "" && source != SourceYahoo && source != SourceTCMB {
+		return apperror.New(apperror.BadRequest, "rateSource must be yahoo or tcmb")
+	}
+	return nil
+}
+
+type RatePoint struct {
+	Date   time.Time `json:"date"`
+	Rate   float64   `json:"rate"`
+	Bid    float64   `json:"bid,omitempty"`    // buying rate, when the source publishes one
+	Ask    float64   `json:"ask,omitempty"`    // selling rate, when the source publishes one
+	Filled bool      `json:"filled,omitempty"` // carried forward from an earlier day
+}
+
+type GetRatesResponse struct {
+	Source string      `json:"source"`
+	Pair   string      `json:"pair"`
+	Path   string      `json:"path"` // stored pair(s) the rates came from, e.g. "EURUSD*USDTRY" or "GC=F*USDTRY"
+	Rates  []RatePoint `json:"rates"`
+}
@@ -0,0 +1,132 @@
+package rate
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
+)
+
+const defaultChartEndpoint = "https://query2.finance.yahoo.com/v8/finance/chart/%s?interval=1d&period1=%s&period2=%s"
+
+// yahooProvider quotes every currency against USD (EURUSD=X, USDTRY=X, ...).
+type yahooProvider struct {
+	client        *httpx.Client
+	archive       archive.Recorder
+	chartEndpoint string
+}
+
+func (p *yahooProvider) Pivot() string { return USD }
+
+func (p *yahooProvider) Supports(currency string) bool { return Supported(currency) }
+
+func (p *yahooProvider) Calendar() *calendar.Calendar { return calendar.Weekdays }
+
+// chartResponse is the minimal Yahoo v8 chart API response structure.
+type chartResponse struct {
+	Chart struct {
+		Result []struct {
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					Close []float64 `json:"close"`
+				} `json:"quote"`
+			} `json:"indicators"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// Fetch implements Provider using daily closes from the Yahoo v8 chart API.
+func (p *yahooProvider) Fetch(ctx context.Context, pair string, from, to time.Time) ([]Rate, error) {
+	symbol := pairToSymbol(pair)
+	url := fmt.Sprintf(p.chartEndpoint, symbol,
+		strconv.FormatInt(from.Unix(), 10),
+		strconv.FormatInt(to.Unix(), 10))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("yahoo chart %s: %w", symbol, err)
+	}
+	archive.Record(ctx, p.archive, &archive.Entry{
+		Source:     SourceYahoo,
+		Symbol:     symbol,
+		Dataset:    archive.DatasetRates,
+		Method:     req.Method,
+		URL:        url,
+		StatusCode: res.StatusCode,
+		Body:       res.Body,
+	})
+
+	var cr chartResponse
+	if err := res.JSON(&cr); err != nil {
+		return nil, fmt.Errorf("yahoo chart %s: %w", symbol, err)
+	}
+
+	if cr.Chart.Error != nil {
+		return nil, fmt.Errorf("yahoo chart error: %s: %s", cr.Chart.Error.Code, cr.Chart.Error.Description)
+	}
+
+	if len(cr.Chart.Result) == 0 {
+		return nil, fmt.Errorf("yahoo chart returned no results for %s", symbol)
+	}
+
+	r := cr.Chart.Result[0]
+	if len(r.Indicators.Quote) == 0 {
+		return nil, fmt.Errorf("yahoo chart returned no quote data for %s", symbol)
+	}
+
+	timestamps := r.Timestamp
+	closes := r.Indicators.Quote[0].Close
+
+	rates := make([]Rate, 0, len(timestamps))
+	for i, ts := range timestamps {
+		if i >= len(closes) || closes[i] == 0 {
+			continue
+		}
+		d := time.Unix(ts, 0).UTC().Truncate(24 * time.Hour)
+		rates = append(rates, Rate{Source: SourceYahoo, Pair: pair, Date: d, Rate: closes[i]})
+	}
+
+	slog.Info("fetched exchange rates from chart API", "symbol", symbol, "count", len(rates))
+	return rates, nil
+}
+
+// pairXAUUSD is fetched as the COMEX front-month gold future (GC=F): Yahoo
+// no longer serves spot gold, and the future trades at a small premium to it
+// that grows toward expiry and jumps at each roll.
+const pairXAUUSD = "XAUUSD"
+
+// Instrument names XAUUSD after the gold future it is fetched from, so that
+// paths through it show the quote is not spot gold.
+func (p *yahooProvider) Instrument(pair string) string {
+	if pair == pairXAUUSD {
+		return pairToSymbol(pair)
+	}
+	return pair
+}
+
+func pairToSymbol(pair string) string {
+	switch pair {
+	case PairUSDTRY:
+		return "USDTRY=X"
+	case pairXAUUSD:
+		return "GC=F"
+	}
+	return pair + "=X"
+}
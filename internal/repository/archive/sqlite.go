@@ -0,0 +1,109 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	domain "github.com/ahmethakanbesel/finance-api/internal/archive"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Save(ctx context.Context, e *domain.Entry) error {
+	sum := sha256.Sum256(e.Body)
+	e.BodyHash = hex.EncodeToString(sum[:])
+
+	compressed, err := compress(e.Body)
+	if err != nil {
+		return fmt.Errorf("compress response body: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO response_bodies (hash, size, body) VALUES (?, ?, ?)",
+		e.BodyHash, len(e.Body), compressed); err != nil {
+		return fmt.Errorf("save response body: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO responses
+		(source, symbol, dataset, method, url, request_body, status, body_hash, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Source, e.Symbol, e.Dataset, e.Method, e.URL, e.RequestBody, e.StatusCode, e.BodyHash,
+		e.FetchedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("save response: %w", err)
+	}
+	e.ID, _ = res.LastInsertId()
+
+	return tx.Commit()
+}
+
+func (r *Repository) List(ctx context.Context, source, symbol, dataset string) ([]domain.Entry, error) {
+	const query = `SELECT r.id, r.source, r.symbol, r.dataset, r.method, r.url, r.request_body,
+			r.status, r.body_hash, r.fetched_at, b.body
+		FROM responses r JOIN response_bodies b ON b.hash = r.body_hash
+		WHERE r.source = ? AND r.symbol = ? AND r.dataset = ? AND r.status BETWEEN 200 AND 299
+		ORDER BY r.fetched_at, r.id`
+
+	rows, err := r.db.QueryContext(ctx, query, source, symbol, dataset)
+	if err != nil {
+		return nil, fmt.Errorf("list responses: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []domain.Entry
+	for rows.Next() {
+		var e domain.Entry
+		var fetchedStr string
+		var compressed []byte
+		if err := rows.Scan(&e.ID, &e.Source, &e.Symbol, &e.Dataset, &e.Method, &e.URL, &e.RequestBody,
+			&e.StatusCode, &e.BodyHash, &fetchedStr, &compressed); err != nil {
+			return nil, fmt.Errorf("scan response: %w", err)
+		}
+		e.FetchedAt, _ = time.Parse(time.RFC3339, fetchedStr)
+		if e.Body, err = decompress(compressed); err != nil {
+			return nil, fmt.Errorf("decompress response %d: %w", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func compress(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,68 @@
+package archive
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	domain "github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
+)
+
+func setupTestDB(t *testing.T) *sqlite.DB {
+	t.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSaveAndList(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	body := []byte(`{"data":[` + strings.Repeat(`{"FIYAT":1.23},`, 100) + `{}]}`)
+	t1 := time.Date(2024, 1, 5, 18, 0, 0, 0, time.UTC)
+	entries := []*domain.Entry{
+		{Source: "tefas", Symbol: "YAC", Dataset: domain.DatasetPrices, Method: "POST", URL: "https://tefas.test/history",
+			RequestBody: []byte("fonkod=YAC"), StatusCode: 200, Body: body, FetchedAt: t1},
+		// Fetched again unchanged: the body is stored once.
+		{Source: "tefas", Symbol: "YAC", Dataset: domain.DatasetPrices, Method: "POST", URL: "https://tefas.test/history",
+			RequestBody: []byte("fonkod=YAC"), StatusCode: 200, Body: body, FetchedAt: t1.Add(time.Hour)},
+		{Source: "tefas", Symbol: "YAC", Dataset: domain.DatasetAllocation, StatusCode: 200, Body: []byte("{}"), FetchedAt: t1},
+		{Source: "tefas", Symbol: "TTE", Dataset: domain.DatasetPrices, StatusCode: 200, Body: []byte("{}"), FetchedAt: t1},
+	}
+	for _, e := range entries {
+		if err := repo.Save(ctx, e); err != nil {
+			t.Fatalf("save: %v", err)
+		}
+	}
+	if entries[0].ID == 0 || entries[0].BodyHash == "" || entries[0].BodyHash != entries[1].BodyHash {
+		t.Errorf("unexpected saved entries %+v, %+v", entries[0], entries[1])
+	}
+
+	var bodies int
+	var size int64
+	if err := db.DB.QueryRow("SELECT COUNT(*), SUM(LENGTH(body)) FROM response_bodies").Scan(&bodies, &size); err != nil {
+		t.Fatal(err)
+	}
+	if bodies != 2 || size >= int64(len(body)) {
+		t.Errorf("expected 2 compressed bodies, got %d totalling %d bytes", bodies, size)
+	}
+
+	got, err := repo.List(ctx, "tefas", "YAC", domain.DatasetPrices)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(got))
+	}
+	if string(got[0].Body) != string(body) || string(got[0].RequestBody) != "fonkod=YAC" ||
+		!got[0].FetchedAt.Equal(t1) || !got[1].FetchedAt.After(t1) {
+		t.Errorf("unexpected responses %+v", got)
+	}
+}
@@ -0,0 +1,235 @@
+package fund
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	domain "github.com/ahmethakanbesel/finance-api/internal/fund"
+)
+
+const dateFormat = "2006-01-02"
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) SaveStats(ctx context.Context, stats []domain.Stats) (int64, error) {
+	if len(stats) == 0 {
+		return 0, nil
+	}
+
+	const batchSize = 500
+	var total int64
+
+	for i := 0; i < len(stats); i += batchSize {
+		end := i + batchSize
+		if end > len(stats) {
+			end = len(stats)
+		}
+		batch := stats[i:end]
+
+		placeholders := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*5)
+		for j, st := range batch {
+			placeholders[j] = "(?, ?, ?, ?, ?)"
+			args = append(args, st.Code, st.Date.Format(dateFormat), st.SharesOutstanding, st.Investors, st.TotalValue)
+		}
+
+		query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
+			"INSERT OR IGNORE INTO fund_stats (code, date, shares_outstanding, investors, total_value) VALUES %s",
+			strings.Join(placeholders, ", "),
+		)
+
+		res, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("save fund stats: %w", err)
+		}
+
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	return total, nil
+}
+
+func (r *Repository) ListStats(ctx context.Context, code string, from, to time.Time) ([]domain.Stats, error) {
+	const query = `SELECT id, code, date, shares_outstanding, investors, total_value, created_at
+		FROM fund_stats
+		WHERE code = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, code, from.Format(dateFormat), to.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("list fund stats: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var stats []domain.Stats
+	for rows.Next() {
+		var st domain.Stats
+		var dateStr, createdStr string
+		if err := rows.Scan(&st.ID, &st.Code, &dateStr, &st.SharesOutstanding, &st.Investors, &st.TotalValue, &createdStr); err != nil {
+			return nil, fmt.Errorf("scan fund stats: %w", err)
+		}
+		st.Date, _ = time.Parse(dateFormat, dateStr)
+		st.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
+		stats = append(stats, st)
+	}
+
+	return stats, rows.Err()
+}
+
+// SaveAllocations stores one row per asset class; the returned count is rows,
+// not days.
+func (r *Repository) SaveAllocations(ctx context.Context, allocs []domain.Allocation) (int64, error) {
+	type row struct {
+		code, date, asset string
+		weight            float64
+	}
+	var rows []row
+	for _, a := range allocs {
+		date := a.Date.Format(dateFormat)
+		for asset, w := range a.Weights {
+			rows = append(rows, row{a.Code, date, asset, w})
+		}
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	const batchSize = 500
+	var total int64
+
+	for i := 0; i < len(rows); i += batchSize {
+		end := i + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		batch := rows[i:end]
+
+		placeholders := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*4)
+		for j, rw := range batch {
+			placeholders[j] = "(?, ?, ?, ?)"
+			args = append(args, rw.code, rw.date, rw.asset, rw.weight)
+		}
+
+		query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
+			"INSERT OR IGNORE INTO fund_allocations (code, date, asset, weight) VALUES %s",
+			strings.Join(placeholders, ", "),
+		)
+
+		res, err := r.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("save fund allocations: %w", err)
+		}
+
+		n, _ := res.RowsAffected()
+		total += n
+	}
+
+	return total, nil
+}
+
+// ListAllocations groups stored rows back into one Allocation per day.
+func (r *Repository) ListAllocations(ctx context.Context, code string, from, to time.Time) ([]domain.Allocation, error) {
+	const query = `SELECT date, asset, weight FROM fund_allocations
+		WHERE code = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC, asset ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, code, from.Format(dateFormat), to.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("list fund allocations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var allocs []domain.Allocation
+	for rows.Next() {
+		var dateStr, asset string
+		var weight float64
+		if err := rows.Scan(&dateStr, &asset, &weight); err != nil {
+			return nil, fmt.Errorf("scan fund allocation: %w", err)
+		}
+		d, _ := time.Parse(dateFormat, dateStr)
+		if n := len(allocs); n == 0 || !allocs[n-1].Date.Equal(d) {
+			allocs = append(allocs, domain.Allocation{Code: code, Date: d, Weights: make(map[string]float64)})
+		}
+		allocs[len(allocs)-1].Weights[asset] = weight
+	}
+
+	return allocs, rows.Err()
+}
+
+func (r *Repository) ExistingStatsDates(ctx context.Context, code string, from, to time.Time) (map[time.Time]bool, error) {
+	const query = `SELECT date FROM fund_stats
+		WHERE code = ? AND date >= ? AND date <= ?`
+
+	rows, err := r.db.QueryContext(ctx, query, code, from.Format(dateFormat), to.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("existing stats dates: %w", err)
+	}
+	return scanDates(rows)
+}
+
+func (r *Repository) ExistingAllocationDates(ctx context.Context, code string, from, to time.Time) (map[time.Time]bool, error) {
+	const query = `SELECT DISTINCT date FROM fund_allocations
+		WHERE code = ? AND date >= ? AND date <= ?`
+
+	rows, err := r.db.QueryContext(ctx, query, code, from.Format(dateFormat), to.Format(dateFormat))
+	if err != nil {
+		return nil, fmt.Errorf("existing allocation dates: %w", err)
+	}
+	return scanDates(rows)
+}
+
+func scanDates(rows *sql.Rows) (map[time.Time]bool, error) {
+	defer func() { _ = rows.Close() }()
+
+	dates := make(map[time.Time]bool)
+	for rows.Next() {
+		var dateStr string
+		if err := rows.Scan(&dateStr); err != nil {
+			return nil, fmt.Errorf("scan date: %w", err)
+		}
+		d, _ := time.Parse(dateFormat, dateStr)
+		dates[d] = true
+	}
+
+	return dates, rows.Err()
+}
+
+// SaveSymbol inserts or renames a symbol; funds occasionally change title.
+func (r *Repository) SaveSymbol(ctx context.Context, info domain.SymbolInfo) error {
+	const query = `INSERT INTO symbols (source, symbol, name) VALUES (?, ?, ?)
+		ON CONFLICT (source, symbol) DO UPDATE SET name = excluded.name,
+			updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE name != excluded.name`
+
+	if _, err := r.db.ExecContext(ctx, query, info.Source, info.Symbol, info.Name); err != nil {
+		return fmt.Errorf("save symbol: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) GetSymbol(ctx context.Context, source, symbol string) (*domain.SymbolInfo, error) {
+	const query = `SELECT source, symbol, name, updated_at FROM symbols WHERE source = ? AND symbol = ?`
+
+	info := &domain.SymbolInfo{}
+	var updatedStr string
+	err := r.db.QueryRowContext(ctx, query, source, symbol).Scan(&info.Source, &info.Symbol, &info.Name, &updatedStr)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get symbol: %w", err)
+	}
+	info.UpdatedAt, _ = time.Parse(time.RFC3339, updatedStr)
+	return info, nil
+}
@@ -0,0 +1,152 @@
+package fund
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	domain "github.com/ahmethakanbesel/finance-api/internal/fund"
+	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
+)
+
+func setupTestDB(t *testing.T) *sqlite.DB {
+	t.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestSaveStats_And_ListStats(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	stats := []domain.Stats{
+		{Code: "YAC", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), SharesOutstanding: 1000, Investors: 10, TotalValue: 1230},
+		{Code: "YAC", Date: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), SharesOutstanding: 1100, Investors: 12, TotalValue: 1364},
+	}
+
+	n, err := repo.SaveStats(ctx, stats)
+	if err != nil {
+		t.Fatalf("save stats: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows, got %d", n)
+	}
+
+	n, err = repo.SaveStats(ctx, stats)
+	if err != nil {
+		t.Fatalf("save stats again: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected 0 rows (idempotent), got %d", n)
+	}
+
+	got, err := repo.ListStats(ctx, "YAC",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	)
+	if err != nil {
+		t.Fatalf("list stats: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 stats, got %d", len(got))
+	}
+	if got[1].Investors != 12 || got[1].TotalValue != 1364 {
+		t.Errorf("unexpected stats: %+v", got[1])
+	}
+
+	dates, err := repo.ExistingStatsDates(ctx, "YAC",
+		time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	)
+	if err != nil {
+		t.Fatalf("existing stats dates: %v", err)
+	}
+	if len(dates) != 1 || !dates[time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)] {
+		t.Errorf("expected only Jan 3, got %v", dates)
+	}
+}
+
+func TestSaveAllocations_And_ListAllocations(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	d1 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	d2 := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+	allocs := []domain.Allocation{
+		{Code: "YAC", Date: d1, Weights: map[string]float64{"HS": 90, "R": 10}},
+		{Code: "YAC", Date: d2, Weights: map[string]float64{"HS": 85, "R": 10, "TR": 5}},
+	}
+
+	n, err := repo.SaveAllocations(ctx, allocs)
+	if err != nil {
+		t.Fatalf("save allocations: %v", err)
+	}
+	if n != 5 {
+		t.Errorf("expected 5 rows, got %d", n)
+	}
+
+	n, err = repo.SaveAllocations(ctx, allocs)
+	if err != nil {
+		t.Fatalf("save allocations again: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected 0 rows (idempotent), got %d", n)
+	}
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	got, err := repo.ListAllocations(ctx, "YAC", from, to)
+	if err != nil {
+		t.Fatalf("list allocations: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 days, got %d", len(got))
+	}
+	if !got[1].Date.Equal(d2) || len(got[1].Weights) != 3 || got[1].Weights["TR"] != 5 {
+		t.Errorf("unexpected allocation: %+v", got[1])
+	}
+
+	dates, err := repo.ExistingAllocationDates(ctx, "YAC", from, to)
+	if err != nil {
+		t.Fatalf("existing dates: %v", err)
+	}
+	if len(dates) != 2 || !dates[d1] || !dates[d2] {
+		t.Errorf("unexpected dates: %v", dates)
+	}
+}
+
+func TestSaveSymbol_Upsert(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	got, err := repo.GetSymbol(ctx, "tefas", "YAC")
+	if err != nil {
+		t.Fatalf("get symbol: %v", err)
+	}
+	if got != nil {
+		t.Fatal("expected nil for unknown symbol")
+	}
+
+	if err := repo.SaveSymbol(ctx, domain.SymbolInfo{Source: "tefas", Symbol: "YAC", Name: "OLD NAME"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveSymbol(ctx, domain.SymbolInfo{Source: "tefas", Symbol: "YAC", Name: "NEW NAME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = repo.GetSymbol(ctx, "tefas", "YAC")
+	if err != nil {
+		t.Fatalf("get symbol: %v", err)
+	}
+	if got == nil || got.Name != "NEW NAME" {
+		t.Errorf("expected renamed symbol, got %+v", got)
+	}
+}
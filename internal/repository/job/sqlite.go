@@ -3,7 +3,9 @@ package job
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ahmethakanbesel/finance-api/internal/apperror"
@@ -20,17 +22,66 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
+const insertJob = `INSERT INTO jobs (kind, source, symbol, start_date, end_date, status, max_attempts, parent_id)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+const jobColumns = `id, kind, source, symbol, start_date, end_date,
+		status, error, records_count, attempts, max_attempts, next_run_at,
+		failed_ranges, parent_id, created_at, updated_at`
+
+// execer is satisfied by *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 func (r *Repository) Create(ctx context.Context, j *domain.Job) error {
-	const query = `INSERT INTO jobs (source, symbol, start_date, end_date, status)
-		VALUES (?, ?, ?, ?, ?)`
+	if err := insert(ctx, r.db, j); err != nil {
+		return fmt.Errorf("create job: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) CreateBatch(ctx context.Context, jobs []*domain.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
 
-	res, err := r.db.ExecContext(ctx, query,
-		j.Source, j.Symbol,
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("create jobs: begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, j := range jobs {
+		if err := insert(ctx, tx, j); err != nil {
+			return fmt.Errorf("create jobs: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("create jobs: commit: %w", err)
+	}
+	return nil
+}
+
+func insert(ctx context.Context, db execer, j *domain.Job) error {
+	if j.Kind == "" {
+		j.Kind = domain.KindPrices
+	}
+	if j.MaxAttempts <= 0 {
+		j.MaxAttempts = domain.DefaultMaxAttempts
+	}
+	var parent sql.NullInt64
+	if j.ParentID != 0 {
+		parent = sql.NullInt64{Int64: j.ParentID, Valid: true}
+	}
+	res, err := db.ExecContext(ctx, insertJob,
+		string(j.Kind), j.Source, j.Symbol,
 		j.StartDate.Format(dateFormat), j.EndDate.Format(dateFormat),
-		string(j.Status),
+		string(j.Status), j.MaxAttempts, parent,
 	)
 	if err != nil {
-		return fmt.Errorf("create job: %w", err)
+		return err
 	}
 
 	j.ID, _ = res.LastInsertId()
@@ -40,11 +91,22 @@ func (r *Repository) Create(ctx context.Context, j *domain.Job) error {
 }
 
 func (r *Repository) Update(ctx context.Context, j *domain.Job) error {
-	const query = `UPDATE jobs SET status = ?, error = ?, records_count = ?,
-		updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
-		WHERE id = ?`
+	const query = `UPDATE jobs SET status = ?, error = ?, records_count = ?, next_run_at = ?,
+		failed_ranges = ?, updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ? AND status != 'cancelled'`
 
-	_, err := r.db.ExecContext(ctx, query, string(j.Status), j.Error, j.RecordsCount, j.ID)
+	var next, failed sql.NullString
+	if j.NextRunAt != nil {
+		next = sql.NullString{String: j.NextRunAt.UTC().Format(time.RFC3339), Valid: true}
+	}
+	if len(j.FailedRanges) > 0 {
+		b, err := json.Marshal(j.FailedRanges)
+		if err != nil {
+			return fmt.Errorf("update job: encode failed ranges: %w", err)
+		}
+		failed = sql.NullString{String: string(b), Valid: true}
+	}
+	_, err := r.db.ExecContext(ctx, query, string(j.Status), j.Error, j.RecordsCount, next, failed, j.ID)
 	if err != nil {
 		return fmt.Errorf("update job: %w", err)
 	}
@@ -53,42 +115,20 @@ func (r *Repository) Update(ctx context.Context, j *domain.Job) error {
 }
 
 func (r *Repository) Get(ctx context.Context, id int64) (*domain.Job, error) {
-	const query = `SELECT id, source, symbol, start_date, end_date,
-		status, error, records_count, created_at, updated_at
-		FROM jobs WHERE id = ?`
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE id = ?`
 
-	j := &domain.Job{}
-	var startStr, endStr, status string
-	var createdStr, updatedStr string
-	var dbErr sql.NullString
-
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
-		&j.ID, &j.Source, &j.Symbol,
-		&startStr, &endStr, &status, &dbErr,
-		&j.RecordsCount, &createdStr, &updatedStr,
-	)
+	j, err := scanJob(r.db.QueryRowContext(ctx, query, id))
 	if err == sql.ErrNoRows {
 		return nil, apperror.New(apperror.NotFound, "job not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("get job: %w", err)
 	}
-
-	j.Status = domain.Status(status)
-	if dbErr.Valid {
-		j.Error = dbErr.String
-	}
-	j.StartDate, _ = time.Parse(dateFormat, startStr)
-	j.EndDate, _ = time.Parse(dateFormat, endStr)
-	j.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
-	j.UpdatedAt, _ = time.Parse(time.RFC3339, updatedStr)
 	return j, nil
 }
 
 func (r *Repository) List(ctx context.Context, source, symbol string) ([]domain.Job, error) {
-	query := `SELECT id, source, symbol, start_date, end_date,
-		status, error, records_count, created_at, updated_at
-		FROM jobs WHERE 1=1`
+	query := `SELECT ` + jobColumns + ` FROM jobs WHERE 1=1`
 
 	var args []any
 	if source != "" {
@@ -109,79 +149,53 @@ func (r *Repository) List(ctx context.Context, source, symbol string) ([]domain.
 
 	var jobs []domain.Job
 	for rows.Next() {
-		var j domain.Job
-		var startStr, endStr, status, createdStr, updatedStr string
-		var dbErr sql.NullString
-
-		if err := rows.Scan(
-			&j.ID, &j.Source, &j.Symbol,
-			&startStr, &endStr, &status, &dbErr,
-			&j.RecordsCount, &createdStr, &updatedStr,
-		); err != nil {
+		j, err := scanJob(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan job: %w", err)
 		}
-
-		j.Status = domain.Status(status)
-		if dbErr.Valid {
-			j.Error = dbErr.String
-		}
-		j.StartDate, _ = time.Parse(dateFormat, startStr)
-		j.EndDate, _ = time.Parse(dateFormat, endStr)
-		j.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
-		j.UpdatedAt, _ = time.Parse(time.RFC3339, updatedStr)
-		jobs = append(jobs, j)
+		jobs = append(jobs, *j)
 	}
 
 	return jobs, rows.Err()
 }
 
-func (r *Repository) FindActive(ctx context.Context, source, symbol string, from, to string) (*domain.Job, error) {
-	const query = `SELECT id, source, symbol, start_date, end_date,
-		status, error, records_count, created_at, updated_at
-		FROM jobs
-		WHERE source = ? AND symbol = ?
+func (r *Repository) FindActive(ctx context.Context, kind domain.Kind, source, symbol string, from, to string) (*domain.Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM jobs
+		WHERE kind = ? AND source = ? AND symbol = ?
 		  AND start_date = ? AND end_date = ?
 		  AND status IN ('pending', 'running')
 		LIMIT 1`
 
-	j := &domain.Job{}
-	var startStr, endStr, status, createdStr, updatedStr string
-	var dbErr sql.NullString
-
-	err := r.db.QueryRowContext(ctx, query, source, symbol, from, to).Scan(
-		&j.ID, &j.Source, &j.Symbol,
-		&startStr, &endStr, &status, &dbErr,
-		&j.RecordsCount, &createdStr, &updatedStr,
-	)
+	j, err := scanJob(r.db.QueryRowContext(ctx, query, string(kind), source, symbol, from, to))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
 		return nil, fmt.Errorf("find active job: %w", err)
 	}
-
-	j.Status = domain.Status(status)
-	if dbErr.Valid {
-		j.Error = dbErr.String
-	}
-	j.StartDate, _ = time.Parse(dateFormat, startStr)
-	j.EndDate, _ = time.Parse(dateFormat, endStr)
-	j.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
-	j.UpdatedAt, _ = time.Parse(time.RFC3339, updatedStr)
 	return j, nil
 }
 
-func (r *Repository) ClaimPending(ctx context.Context) (*domain.Job, error) {
+func (r *Repository) ClaimPending(ctx context.Context, skipSources []string) (*domain.Job, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("claim pending: begin tx: %w", err)
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	query := `SELECT id FROM jobs WHERE status = 'pending'
+		  AND (next_run_at IS NULL OR next_run_at <= strftime('%Y-%m-%dT%H:%M:%SZ', 'now'))`
+	args := make([]any, len(skipSources))
+	if len(skipSources) > 0 {
+		query += " AND source NOT IN (?" + strings.Repeat(", ?", len(skipSources)-1) + ")"
+		for i, src := range skipSources {
+			args[i] = src
+		}
+	}
+	query += " ORDER BY id ASC LIMIT 1"
+
 	var id int64
-	err = tx.QueryRowContext(ctx,
-		`SELECT id FROM jobs WHERE status = 'pending' ORDER BY id ASC LIMIT 1`,
-	).Scan(&id)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&id)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -190,7 +204,8 @@ func (r *Repository) ClaimPending(ctx context.Context) (*domain.Job, error) {
 	}
 
 	_, err = tx.ExecContext(ctx,
-		`UPDATE jobs SET status = 'running', updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = ?`,
+		`UPDATE jobs SET status = 'running', attempts = attempts + 1, next_run_at = NULL,
+		updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now') WHERE id = ?`,
 		id,
 	)
 	if err != nil {
@@ -216,3 +231,73 @@ func (r *Repository) RecoverStale(ctx context.Context) (int64, error) {
 
 	return res.RowsAffected()
 }
+
+func (r *Repository) Cancel(ctx context.Context, id int64) (bool, error) {
+	const query = `UPDATE jobs SET status = 'cancelled', next_run_at = NULL,
+		updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ? AND status IN ('pending', 'running')`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("cancel job: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *Repository) Requeue(ctx context.Context, id int64) (bool, error) {
+	const query = `UPDATE jobs SET status = 'pending', error = NULL, records_count = 0,
+		attempts = 0, next_run_at = NULL,
+		updated_at = strftime('%Y-%m-%dT%H:%M:%SZ', 'now')
+		WHERE id = ? AND status IN ('failed', 'cancelled')`
+
+	res, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("requeue job: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanJob reads a row selected with jobColumns.
+func scanJob(row scanner) (*domain.Job, error) {
+	j := &domain.Job{}
+	var startStr, endStr, status, createdStr, updatedStr string
+	var dbErr, nextStr, failedStr sql.NullString
+	var parent sql.NullInt64
+
+	if err := row.Scan(
+		&j.ID, &j.Kind, &j.Source, &j.Symbol,
+		&startStr, &endStr, &status, &dbErr,
+		&j.RecordsCount, &j.Attempts, &j.MaxAttempts, &nextStr,
+		&failedStr, &parent, &createdStr, &updatedStr,
+	); err != nil {
+		return nil, err
+	}
+
+	j.Status = domain.Status(status)
+	if dbErr.Valid {
+		j.Error = dbErr.String
+	}
+	if nextStr.Valid {
+		if next, err := time.Parse(time.RFC3339, nextStr.String); err == nil {
+			j.NextRunAt = &next
+		}
+	}
+	if failedStr.Valid {
+		if err := json.Unmarshal([]byte(failedStr.String), &j.FailedRanges); err != nil {
+			return nil, fmt.Errorf("decode failed ranges: %w", err)
+		}
+	}
+	j.ParentID = parent.Int64
+	j.StartDate, _ = time.Parse(dateFormat, startStr)
+	j.EndDate, _ = time.Parse(dateFormat, endStr)
+	j.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
+	j.UpdatedAt, _ = time.Parse(time.RFC3339, updatedStr)
+	return j, nil
+}
@@ -2,6 +2,7 @@ package job
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -51,6 +52,38 @@ func TestCreate_And_Get(t *testing.T) {
 	}
 }
 
+func TestCreateBatch(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	jobs := []*domain.Job{
+		{Source: "tefas", Symbol: "YAC", StartDate: from, EndDate: to, Status: domain.StatusPending},
+		{Source: "yahoo", Symbol: "AAPL", StartDate: from, EndDate: to, Status: domain.StatusPending},
+	}
+	if err := repo.CreateBatch(ctx, jobs); err != nil {
+		t.Fatalf("create batch: %v", err)
+	}
+
+	for _, j := range jobs {
+		if j.ID == 0 {
+			t.Fatalf("expected non-zero ID for %s", j.Symbol)
+		}
+		got, err := repo.Get(ctx, j.ID)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if got.Symbol != j.Symbol || got.Kind != domain.KindPrices {
+			t.Errorf("unexpected job %+v", got)
+		}
+	}
+	if jobs[0].ID == jobs[1].ID {
+		t.Error("expected distinct IDs")
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db.DB)
@@ -187,16 +220,28 @@ func TestFindActive(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := repo.FindActive(ctx, "tefas", "YAC", "2024-01-01", "2024-01-31")
+	got, err := repo.FindActive(ctx, domain.KindPrices, "tefas", "YAC", "2024-01-01", "2024-01-31")
 	if err != nil {
 		t.Fatalf("find active: %v", err)
 	}
 	if got == nil {
 		t.Fatal("expected active job")
 	}
+	if got.Kind != domain.KindPrices {
+		t.Errorf("kind = %q, want %q", got.Kind, domain.KindPrices)
+	}
+
+	// Same range, different dataset
+	got, err = repo.FindActive(ctx, domain.KindAllocation, "tefas", "YAC", "2024-01-01", "2024-01-31")
+	if err != nil {
+		t.Fatalf("find active: %v", err)
+	}
+	if got != nil {
+		t.Error("expected nil for different kind")
+	}
 
 	// No match
-	got, err = repo.FindActive(ctx, "yahoo", "AAPL", "2024-01-01", "2024-01-31")
+	got, err = repo.FindActive(ctx, domain.KindPrices, "yahoo", "AAPL", "2024-01-01", "2024-01-31")
 	if err != nil {
 		t.Fatalf("find active: %v", err)
 	}
@@ -213,3 +258,198 @@ func TestGet_NotFound(t *testing.T) {
 		t.Fatal("expected error for missing job")
 	}
 }
+
+func TestCancel_And_Requeue(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	j := &domain.Job{
+		Source:    "tefas",
+		Symbol:    "YAC",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Status:    domain.StatusPending,
+	}
+	if err := repo.Create(ctx, j); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if ok, err := repo.Requeue(ctx, j.ID); err != nil || ok {
+		t.Fatalf("requeue pending job: ok=%v err=%v, want false", ok, err)
+	}
+	if ok, err := repo.Cancel(ctx, j.ID); err != nil || !ok {
+		t.Fatalf("cancel: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := repo.Cancel(ctx, j.ID); ok {
+		t.Error("expected second cancel to report false")
+	}
+
+	// A processor finishing after the cancel does not overwrite it
+	j.Status = domain.StatusCompleted
+	if err := repo.Update(ctx, j); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got, _ := repo.Get(ctx, j.ID); got.Status != domain.StatusCancelled {
+		t.Errorf("status = %q after update, want %q", got.Status, domain.StatusCancelled)
+	}
+
+	// Cancelled jobs are never claimed
+	claimed, err := repo.ClaimPending(ctx, nil)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if claimed != nil {
+		t.Fatalf("claimed cancelled job %d", claimed.ID)
+	}
+
+	if ok, err := repo.Requeue(ctx, j.ID); err != nil || !ok {
+		t.Fatalf("requeue: ok=%v err=%v", ok, err)
+	}
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != domain.StatusPending {
+		t.Errorf("expected pending, got %s", got.Status)
+	}
+}
+
+func TestClaimPending_SkipsSources(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	for _, src := range []string{"tefas", "isyatirim", "yahoo"} {
+		j := &domain.Job{
+			Source:    src,
+			Symbol:    "YAC",
+			StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+			Status:    domain.StatusPending,
+		}
+		if err := repo.Create(ctx, j); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	claimed, err := repo.ClaimPending(ctx, []string{"tefas", "isyatirim"})
+	if err != nil || claimed == nil {
+		t.Fatalf("claim: %v %v", claimed, err)
+	}
+	if claimed.Source != "yahoo" {
+		t.Errorf("expected the yahoo job, got %s", claimed.Source)
+	}
+	if next, err := repo.ClaimPending(ctx, []string{"tefas", "isyatirim"}); err != nil || next != nil {
+		t.Errorf("expected no claimable job, got %v %v", next, err)
+	}
+	if next, err := repo.ClaimPending(ctx, []string{"isyatirim"}); err != nil || next == nil || next.Source != "tefas" {
+		t.Errorf("expected the tefas job, got %v %v", next, err)
+	}
+}
+
+func TestClaimPending_SkipsScheduledRetries(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	j := &domain.Job{
+		Source:    "yahoo",
+		Symbol:    "AAPL",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		Status:    domain.StatusPending,
+	}
+	if err := repo.Create(ctx, j); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	claimed, err := repo.ClaimPending(ctx, nil)
+	if err != nil || claimed == nil {
+		t.Fatalf("claim: %v %v", claimed, err)
+	}
+	if claimed.Attempts != 1 || claimed.MaxAttempts != domain.DefaultMaxAttempts {
+		t.Errorf("expected attempt 1 of %d, got %d of %d", domain.DefaultMaxAttempts, claimed.Attempts, claimed.MaxAttempts)
+	}
+
+	claimed.Fail(errors.New("yahoo returned HTTP 429"), time.Now())
+	if err := repo.Update(ctx, claimed); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, _ := repo.Get(ctx, j.ID)
+	if got.Status != domain.StatusPending || got.NextRunAt == nil {
+		t.Fatalf("expected pending with next run time, got %s %v", got.Status, got.NextRunAt)
+	}
+
+	if next, err := repo.ClaimPending(ctx, nil); err != nil || next != nil {
+		t.Fatalf("expected scheduled job to be skipped, got %v %v", next, err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	got.NextRunAt = &past
+	if err := repo.Update(ctx, got); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	claimed, err = repo.ClaimPending(ctx, nil)
+	if err != nil || claimed == nil {
+		t.Fatalf("expected due job to be claimed: %v %v", claimed, err)
+	}
+	if claimed.Attempts != 2 || claimed.NextRunAt != nil {
+		t.Errorf("expected attempt 2 without next run time, got %d %v", claimed.Attempts, claimed.NextRunAt)
+	}
+
+	claimed.Status = domain.StatusFailed
+	claimed.NextRunAt = nil
+	_ = repo.Update(ctx, claimed)
+	if ok, err := repo.Requeue(ctx, j.ID); err != nil || !ok {
+		t.Fatalf("requeue: %v %v", ok, err)
+	}
+	got, _ = repo.Get(ctx, j.ID)
+	if got.Attempts != 0 || got.NextRunAt != nil {
+		t.Errorf("expected requeue to reset attempts, got %d %v", got.Attempts, got.NextRunAt)
+	}
+}
+
+func TestUpdate_PartialWithFollowUps(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	j := &domain.Job{
+		Source:    "tefas",
+		Symbol:    "YAC",
+		StartDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		Status:    domain.StatusRunning,
+	}
+	if err := repo.Create(ctx, j); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	failed := []domain.Range{{
+		StartDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC),
+		Error:     "timeout",
+	}}
+	followUps := j.Partial(errors.New("1 of 3 chunks failed"), failed)
+	if err := repo.CreateBatch(ctx, followUps); err != nil {
+		t.Fatalf("create follow-ups: %v", err)
+	}
+	if err := repo.Update(ctx, j); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, err := repo.Get(ctx, j.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Status != domain.StatusPartial || len(got.FailedRanges) != 1 ||
+		!got.FailedRanges[0].StartDate.Equal(failed[0].StartDate) || got.FailedRanges[0].Error != "timeout" {
+		t.Errorf("unexpected partial job: %+v", got)
+	}
+
+	child, err := repo.Get(ctx, followUps[0].ID)
+	if err != nil {
+		t.Fatalf("get follow-up: %v", err)
+	}
+	if child.ParentID != j.ID || child.Status != domain.StatusPending {
+		t.Errorf("unexpected follow-up: %+v", child)
+	}
+}
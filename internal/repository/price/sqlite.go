@@ -3,11 +3,14 @@ package price
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	domain "github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 )
 
 const dateFormat = "2006-01-02"
@@ -36,14 +39,17 @@ func (r *Repository) SavePrices(ctx context.Context, prices []domain.Price) (int
 		batch := prices[i:end]
 
 		placeholders := make([]string, len(batch))
-		args := make([]any, 0, len(batch)*5)
+		args := make([]any, 0, len(batch)*10)
 		for j, p := range batch {
-			placeholders[j] = "(?, ?, ?, ?, ?)"
-			args = append(args, string(p.Source), p.Symbol, p.Date.Format(dateFormat), p.ClosePrice, string(p.Currency))
+			v := p.Decimals()
+			placeholders[j] = "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+			args = append(args, string(p.Source), p.Symbol, p.Date.Format(dateFormat))
+			args = append(args, values(p, v)...)
+			args = append(args, string(p.Currency))
 		}
 
 		query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
-			"INSERT OR IGNORE INTO prices (source, symbol, date, close_price, currency) VALUES %s",
+			"INSERT OR IGNORE INTO prices (source, symbol, date, open_price, high_price, low_price, close_price, adj_close, volume, currency) VALUES %s",
 			strings.Join(placeholders, ", "),
 		)
 
@@ -59,8 +65,113 @@ func (r *Repository) SavePrices(ctx context.Context, prices []domain.Price) (int
 	return total, nil
 }
 
+func (r *Repository) UpsertPrices(ctx context.Context, prices []domain.Price, jobID int64) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var total int64
+	for _, p := range prices {
+		date := p.Date.Format(dateFormat)
+		v := p.Decimals()
+		var b storedBar
+		var cur string
+		err := tx.QueryRowContext(ctx, `SELECT open_price, high_price, low_price, close_price, adj_close, volume, currency
+			FROM prices WHERE source = ? AND symbol = ? AND date = ?`,
+			string(p.Source), p.Symbol, date,
+		).Scan(append(b.dest(), &cur)...)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			if _, err := tx.ExecContext(ctx, `INSERT INTO prices
+				(source, symbol, date, open_price, high_price, low_price, close_price, adj_close, volume, currency)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				append(append([]any{string(p.Source), p.Symbol, date}, values(p, v)...), string(p.Currency))...); err != nil {
+				return 0, fmt.Errorf("insert price: %w", err)
+			}
+			total++
+			continue
+		case err != nil:
+			return 0, fmt.Errorf("get price: %w", err)
+		}
+		old := b.price(domain.Price{Source: p.Source, Symbol: p.Symbol, Date: p.Date})
+
+		revs := domain.Revisions(old, p, jobID)
+		if len(revs) == 0 && cur == string(p.Currency) {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE prices SET open_price = ?, high_price = ?, low_price = ?,
+			close_price = ?, adj_close = ?, volume = ?, currency = ?
+			WHERE source = ? AND symbol = ? AND date = ?`,
+			append(values(p, v), string(p.Currency), string(p.Source), p.Symbol, date)...); err != nil {
+			return 0, fmt.Errorf("update price: %w", err)
+		}
+		for _, rev := range revs {
+			if _, err := tx.ExecContext(ctx, `INSERT INTO price_revisions
+				(source, symbol, date, field, old_value, new_value, job_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				string(rev.Source), rev.Symbol, date, rev.Field, rev.OldValue, rev.NewValue,
+				sql.NullInt64{Int64: jobID, Valid: jobID != 0}); err != nil {
+				return 0, fmt.Errorf("save price revision: %w", err)
+			}
+		}
+		total++
+	}
+	return total, tx.Commit()
+}
+
+func (r *Repository) LatestPrices(ctx context.Context, source domain.Source, symbol string, before time.Time, n int) ([]domain.Price, error) {
+	const query = `SELECT id, source, symbol, date, open_price, high_price, low_price,
+		close_price, adj_close, volume, currency, created_at
+		FROM (SELECT * FROM prices WHERE source = ? AND symbol = ? AND date < ? ORDER BY date DESC LIMIT ?)
+		ORDER BY date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query, string(source), symbol, before.Format(dateFormat), n)
+	if err != nil {
+		return nil, fmt.Errorf("list latest prices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	return scanPrices(rows)
+}
+
+func (r *Repository) ListRevisions(ctx context.Context, source domain.Source, symbol string, from, to time.Time) ([]domain.Revision, error) {
+	const query = `SELECT id, source, symbol, date, field, old_value, new_value, job_id, revised_at
+		FROM price_revisions
+		WHERE source = ? AND symbol = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC, id ASC`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		string(source), symbol,
+		from.Format(dateFormat), to.Format(dateFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list price revisions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var revs []domain.Revision
+	for rows.Next() {
+		var rev domain.Revision
+		var src, dateStr, revisedStr string
+		var jobID sql.NullInt64
+		if err := rows.Scan(&rev.ID, &src, &rev.Symbol, &dateStr, &rev.Field, &rev.OldValue, &rev.NewValue,
+			&jobID, &revisedStr); err != nil {
+			return nil, fmt.Errorf("scan price revision: %w", err)
+		}
+		rev.Source = domain.Source(src)
+		rev.JobID = jobID.Int64
+		rev.Date, _ = time.Parse(dateFormat, dateStr)
+		rev.RevisedAt, _ = time.Parse(time.RFC3339, revisedStr)
+		revs = append(revs, rev)
+	}
+
+	return revs, rows.Err()
+}
+
 func (r *Repository) ListPrices(ctx context.Context, source domain.Source, symbol string, from, to time.Time) ([]domain.Price, error) {
-	const query = `SELECT id, source, symbol, date, close_price, currency, created_at
+	const query = `SELECT id, source, symbol, date, open_price, high_price, low_price,
+		close_price, adj_close, volume, currency, created_at
 		FROM prices
 		WHERE source = ? AND symbol = ? AND date >= ? AND date <= ?
 		ORDER BY date ASC`
@@ -74,13 +185,20 @@ func (r *Repository) ListPrices(ctx context.Context, source domain.Source, symbo
 	}
 	defer func() { _ = rows.Close() }()
 
+	return scanPrices(rows)
+}
+
+func scanPrices(rows *sql.Rows) ([]domain.Price, error) {
 	var prices []domain.Price
 	for rows.Next() {
 		var p domain.Price
+		var b storedBar
 		var src, cur, dateStr, createdStr string
-		if err := rows.Scan(&p.ID, &src, &p.Symbol, &dateStr, &p.ClosePrice, &cur, &createdStr); err != nil {
+		dest := append([]any{&p.ID, &src, &p.Symbol, &dateStr}, b.dest()...)
+		if err := rows.Scan(append(dest, &cur, &createdStr)...); err != nil {
 			return nil, fmt.Errorf("scan price: %w", err)
 		}
+		p = b.price(p)
 		p.Source = domain.Source(src)
 		p.Currency = domain.Currency(cur)
 		p.Date, _ = time.Parse(dateFormat, dateStr)
@@ -116,3 +234,194 @@ func (r *Repository) ExistingDates(ctx context.Context, source domain.Source, sy
 
 	return dates, rows.Err()
 }
+
+func (r *Repository) SaveActions(ctx context.Context, actions []domain.CorporateAction) (int64, error) {
+	if len(actions) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(actions))
+	args := make([]any, 0, len(actions)*8)
+	for i, a := range actions {
+		placeholders[i] = "(?, ?, ?, ?, ?, ?, ?, ?)"
+		args = append(args, string(a.Source), a.Symbol, a.Date.Format(dateFormat), string(a.Type),
+			nullFloat(a.Amount), nullFloat(a.Numerator), nullFloat(a.Denominator), string(a.Currency))
+	}
+
+	query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
+		"INSERT OR IGNORE INTO corporate_actions (source, symbol, date, type, amount, numerator, denominator, currency) VALUES %s",
+		strings.Join(placeholders, ", "),
+	)
+
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("save corporate actions: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func (r *Repository) ListActions(ctx context.Context, source domain.Source, symbol string, from, to time.Time) ([]domain.CorporateAction, error) {
+	const query = `SELECT id, source, symbol, date, type, amount, numerator, denominator, currency, created_at
+		FROM corporate_actions
+		WHERE source = ? AND symbol = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		string(source), symbol,
+		from.Format(dateFormat), to.Format(dateFormat),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list corporate actions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var actions []domain.CorporateAction
+	for rows.Next() {
+		var a domain.CorporateAction
+		var src, typ, cur, dateStr, createdStr string
+		var amount, num, den sql.NullFloat64
+		if err := rows.Scan(&a.ID, &src, &a.Symbol, &dateStr, &typ, &amount, &num, &den, &cur, &createdStr); err != nil {
+			return nil, fmt.Errorf("scan corporate action: %w", err)
+		}
+		a.Source = domain.Source(src)
+		a.Type = domain.ActionType(typ)
+		a.Currency = domain.Currency(cur)
+		a.Amount = amount.Float64
+		a.Numerator = num.Float64
+		a.Denominator = den.Float64
+		a.Date, _ = time.Parse(dateFormat, dateStr)
+		a.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
+		actions = append(actions, a)
+	}
+
+	return actions, rows.Err()
+}
+
+// SaveEmptyRanges records ranges without prices. A range starting on the
+// same day as a stored one replaces it.
+func (r *Repository) SaveEmptyRanges(ctx context.Context, ranges []domain.EmptyRange) error {
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ranges))
+	args := make([]any, 0, len(ranges)*6)
+	for i, e := range ranges {
+		var expires sql.NullString
+		if e.ExpiresAt != nil {
+			expires = sql.NullString{String: e.ExpiresAt.UTC().Format(time.RFC3339), Valid: true}
+		}
+		placeholders[i] = "(?, ?, ?, ?, ?, ?)"
+		args = append(args, string(e.Source), e.Symbol, e.StartDate.Format(dateFormat), e.EndDate.Format(dateFormat),
+			e.CheckedAt.UTC().Format(time.RFC3339), expires)
+	}
+
+	query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
+		`INSERT INTO empty_ranges (source, symbol, start_date, end_date, checked_at, expires_at) VALUES %s
+		ON CONFLICT (source, symbol, start_date) DO UPDATE SET
+			end_date = excluded.end_date, checked_at = excluded.checked_at, expires_at = excluded.expires_at`,
+		strings.Join(placeholders, ", "),
+	)
+	if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("save empty ranges: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) EmptyRanges(ctx context.Context, source domain.Source, symbol string, from, to, now time.Time) ([]domain.EmptyRange, error) {
+	const query = `SELECT start_date, end_date, checked_at, expires_at FROM empty_ranges
+		WHERE source = ? AND symbol = ? AND start_date <= ? AND end_date >= ?
+		  AND (expires_at IS NULL OR expires_at > ?)
+		ORDER BY start_date ASC`
+
+	rows, err := r.db.QueryContext(ctx, query,
+		string(source), symbol,
+		to.Format(dateFormat), from.Format(dateFormat),
+		now.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("empty ranges: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ranges []domain.EmptyRange
+	for rows.Next() {
+		e := domain.EmptyRange{Source: source, Symbol: symbol}
+		var startStr, endStr, checkedStr string
+		var expiresStr sql.NullString
+		if err := rows.Scan(&startStr, &endStr, &checkedStr, &expiresStr); err != nil {
+			return nil, fmt.Errorf("scan empty range: %w", err)
+		}
+		e.StartDate, _ = time.Parse(dateFormat, startStr)
+		e.EndDate, _ = time.Parse(dateFormat, endStr)
+		e.CheckedAt, _ = time.Parse(time.RFC3339, checkedStr)
+		if expiresStr.Valid {
+			if t, err := time.Parse(time.RFC3339, expiresStr.String); err == nil {
+				e.ExpiresAt = &t
+			}
+		}
+		ranges = append(ranges, e)
+	}
+
+	return ranges, rows.Err()
+}
+
+// values returns the price columns of p, open to volume, with v holding its
+// decimals. Values the source did not report are stored as NULL.
+func values(p domain.Price, v scraper.Exact) []any {
+	optional := func(d decimal.Decimal, f scraper.Field) any {
+		if !p.Reported.Has(f) {
+			return nil
+		}
+		return d
+	}
+	return []any{optional(v.Open, scraper.FieldOpen), optional(v.High, scraper.FieldHigh),
+		optional(v.Low, scraper.FieldLow), v.ClosePrice, optional(v.AdjClose, scraper.FieldAdjClose),
+		optional(v.Volume, scraper.FieldVolume)}
+}
+
+// storedBar is the values of a prices row; a NULL optional value was not
+// reported.
+type storedBar struct {
+	open, high, low, adjClose, volume sql.Null[decimal.Decimal]
+	closePrice                        decimal.Decimal
+}
+
+// dest returns the scan destinations of the columns open_price to volume.
+func (b *storedBar) dest() []any {
+	return []any{&b.open, &b.high, &b.low, &b.closePrice, &b.adjClose, &b.volume}
+}
+
+// price sets the values of p to the stored ones.
+func (b *storedBar) price(p domain.Price) domain.Price {
+	e := scraper.Exact{Open: b.open.V, High: b.high.V, Low: b.low.V, ClosePrice: b.closePrice,
+		AdjClose: b.adjClose.V, Volume: b.volume.V}
+	for _, v := range []struct {
+		field scraper.Field
+		value sql.Null[decimal.Decimal]
+	}{
+		{scraper.FieldOpen, b.open},
+		{scraper.FieldHigh, b.high},
+		{scraper.FieldLow, b.low},
+		{scraper.FieldAdjClose, b.adjClose},
+		{scraper.FieldVolume, b.volume},
+	} {
+		if v.value.Valid {
+			p.Reported |= v.field
+		}
+	}
+	p.Open = e.Open.Float64()
+	p.High = e.High.Float64()
+	p.Low = e.Low.Float64()
+	p.ClosePrice = e.ClosePrice.Float64()
+	p.AdjClose = e.AdjClose.Float64()
+	p.Volume = e.Volume.Float64()
+	p.Exact = &e
+	return p
+}
+
+// nullFloat stores zero as NULL; a dividend leaves the split ratio unused
+// and a split the amount.
+func nullFloat(v float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: v, Valid: v != 0}
+}
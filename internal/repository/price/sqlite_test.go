@@ -5,8 +5,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
 	domain "github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 )
 
 func setupTestDB(t *testing.T) *sqlite.DB {
@@ -54,6 +56,43 @@ func TestSavePrices_And_ListPrices(t *testing.T) {
 	}
 }
 
+func TestSavePrices_Exact(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	// More digits than a float64 holds.
+	closePrice, _ := decimal.Parse("0.36759812345678901")
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	prices := []domain.Price{
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: day, ClosePrice: closePrice.Float64(),
+			Currency: domain.CurrencyTRY, Exact: &scraper.Exact{ClosePrice: closePrice}},
+		{Source: domain.SourceYahoo, Symbol: "AAPL", Date: day, ClosePrice: 185.63999938964844,
+			Volume: 82488700, Reported: scraper.FieldVolume, Currency: domain.CurrencyUSD},
+	}
+	if _, err := repo.SavePrices(ctx, prices); err != nil {
+		t.Fatalf("save prices: %v", err)
+	}
+
+	got, err := repo.ListPrices(ctx, domain.SourceTefas, "YAC", day, day)
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 1 || got[0].Exact == nil || got[0].Exact.ClosePrice.String() != "0.36759812345678901" ||
+		got[0].ClosePrice != closePrice.Float64() || !got[0].Exact.Open.IsZero() {
+		t.Errorf("unexpected prices %+v", got)
+	}
+
+	// Float-only sources keep the exact float.
+	got, err = repo.ListPrices(ctx, domain.SourceYahoo, "AAPL", day, day)
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 1 || got[0].ClosePrice != 185.63999938964844 || got[0].Volume != 82488700 {
+		t.Errorf("unexpected prices %+v", got)
+	}
+}
+
 func TestSavePrices_Idempotent(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db.DB)
@@ -81,6 +120,81 @@ func TestSavePrices_Idempotent(t *testing.T) {
 	}
 }
 
+func TestUpsertPrices(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jan2 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.SavePrices(ctx, []domain.Price{
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: jan1, ClosePrice: 123, Currency: domain.CurrencyTRY},
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: jan2, ClosePrice: 1.24, Currency: domain.CurrencyTRY},
+	}); err != nil {
+		t.Fatalf("save prices: %v", err)
+	}
+
+	// Jan 1 is corrected, Jan 2 is unchanged and Jan 3 is new.
+	n, err := repo.UpsertPrices(ctx, []domain.Price{
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: jan1, ClosePrice: 1.23, Volume: 500,
+			Reported: scraper.FieldVolume, Currency: domain.CurrencyTRY},
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: jan2, ClosePrice: 1.24, Currency: domain.CurrencyTRY},
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: jan2.AddDate(0, 0, 1), ClosePrice: 1.25, Currency: domain.CurrencyTRY},
+	}, 7)
+	if err != nil {
+		t.Fatalf("upsert prices: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows changed, got %d", n)
+	}
+
+	got, err := repo.ListPrices(ctx, domain.SourceTefas, "YAC", jan1, jan2.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 3 || got[0].ClosePrice != 1.23 || got[0].Volume != 500 {
+		t.Errorf("unexpected prices %+v", got)
+	}
+
+	revs, err := repo.ListRevisions(ctx, domain.SourceTefas, "YAC", jan1, jan2)
+	if err != nil {
+		t.Fatalf("list revisions: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("expected 2 revisions, got %+v", revs)
+	}
+	if r := revs[0]; r.Field != "closePrice" || r.OldValue.String() != "123" || r.NewValue.String() != "1.23" || r.JobID != 7 ||
+		!r.Date.Equal(jan1) || r.RevisedAt.IsZero() {
+		t.Errorf("unexpected revision %+v", r)
+	}
+	if r := revs[1]; r.Field != "volume" || !r.OldValue.IsZero() || r.NewValue.String() != "500" {
+		t.Errorf("unexpected revision %+v", r)
+	}
+}
+
+func TestLatestPrices(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	var prices []domain.Price
+	for d := 1; d <= 5; d++ {
+		prices = append(prices, domain.Price{Source: domain.SourceTefas, Symbol: "YAC",
+			Date: time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC), ClosePrice: float64(d), Currency: domain.CurrencyTRY})
+	}
+	if _, err := repo.SavePrices(ctx, prices); err != nil {
+		t.Fatalf("save prices: %v", err)
+	}
+
+	got, err := repo.LatestPrices(ctx, domain.SourceTefas, "YAC", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), 3)
+	if err != nil {
+		t.Fatalf("latest prices: %v", err)
+	}
+	if len(got) != 3 || got[0].ClosePrice != 2 || got[2].ClosePrice != 4 {
+		t.Errorf("expected Jan 2-4 oldest first, got %+v", got)
+	}
+}
+
 func TestExistingDates(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db.DB)
@@ -112,6 +226,53 @@ func TestExistingDates(t *testing.T) {
 	}
 }
 
+func TestEmptyRanges(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	now := day(3, 1).Add(12 * time.Hour)
+	expires := now.Add(time.Hour)
+	ranges := []domain.EmptyRange{
+		{Source: domain.SourceTefas, Symbol: "YAC", StartDate: day(1, 1), EndDate: day(1, 31), CheckedAt: now},
+		{Source: domain.SourceTefas, Symbol: "YAC", StartDate: day(2, 26), EndDate: day(3, 1), CheckedAt: now, ExpiresAt: &expires},
+		{Source: domain.SourceTefas, Symbol: "AFT", StartDate: day(1, 1), EndDate: day(3, 1), CheckedAt: now},
+	}
+	if err := repo.SaveEmptyRanges(ctx, ranges); err != nil {
+		t.Fatalf("save empty ranges: %v", err)
+	}
+
+	got, err := repo.EmptyRanges(ctx, domain.SourceTefas, "YAC", day(1, 15), day(3, 1), now)
+	if err != nil {
+		t.Fatalf("empty ranges: %v", err)
+	}
+	if len(got) != 2 || !got[0].StartDate.Equal(day(1, 1)) || got[0].ExpiresAt != nil || got[1].ExpiresAt == nil {
+		t.Fatalf("expected the permanent and the recent range, got %+v", got)
+	}
+
+	got, err = repo.EmptyRanges(ctx, domain.SourceTefas, "YAC", day(1, 15), day(3, 1), expires.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("empty ranges: %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected the recent range to have expired, got %+v", got)
+	}
+
+	// Checking a range again replaces it.
+	ranges[0].EndDate = day(1, 10)
+	if err := repo.SaveEmptyRanges(ctx, ranges[:1]); err != nil {
+		t.Fatalf("save empty ranges: %v", err)
+	}
+	got, err = repo.EmptyRanges(ctx, domain.SourceTefas, "YAC", day(1, 15), day(1, 31), now)
+	if err != nil {
+		t.Fatalf("empty ranges: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected the shortened range to no longer overlap, got %+v", got)
+	}
+}
+
 func TestSavePrices_Empty(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db.DB)
@@ -123,3 +284,95 @@ func TestSavePrices_Empty(t *testing.T) {
 		t.Errorf("expected 0, got %d", n)
 	}
 }
+
+func TestSavePrices_OHLCV(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	prices := []domain.Price{
+		{Source: domain.SourceYahoo, Symbol: "AAPL", Date: day, Open: 187.15, High: 188.44, Low: 183.89, ClosePrice: 185.64, Volume: 82488700,
+			Reported: scraper.FieldOpen | scraper.FieldHigh | scraper.FieldLow | scraper.FieldVolume, Currency: domain.CurrencyUSD},
+		{Source: domain.SourceTefas, Symbol: "YAC", Date: day, ClosePrice: 1.23, Currency: domain.CurrencyTRY},
+		// A reported zero volume, such as a day without trades.
+		{Source: domain.SourceYahoo, Symbol: "THYAO.IS", Date: day, ClosePrice: 290, Reported: scraper.FieldVolume, Currency: domain.CurrencyTRY},
+	}
+	if _, err := repo.SavePrices(ctx, prices); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.ListPrices(ctx, domain.SourceYahoo, "AAPL", day, day)
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 price, got %d", len(got))
+	}
+	p := got[0]
+	if p.Open != 187.15 || p.High != 188.44 || p.Low != 183.89 || p.ClosePrice != 185.64 || p.Volume != 82488700 {
+		t.Errorf("unexpected bar: %+v", p)
+	}
+
+	// Close-only sources round-trip with empty OHLV.
+	got, err = repo.ListPrices(ctx, domain.SourceTefas, "YAC", day, day)
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 1 || got[0].Open != 0 || got[0].Volume != 0 || got[0].Reported != 0 {
+		t.Errorf("expected close-only bar, got %+v", got)
+	}
+
+	got, err = repo.ListPrices(ctx, domain.SourceYahoo, "THYAO.IS", day, day)
+	if err != nil {
+		t.Fatalf("list prices: %v", err)
+	}
+	if len(got) != 1 || got[0].Volume != 0 || got[0].Reported != scraper.FieldVolume {
+		t.Errorf("expected a reported zero volume, got %+v", got)
+	}
+}
+
+func TestSaveActions_And_ListActions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	actions := []domain.CorporateAction{
+		{Source: domain.SourceYahoo, Symbol: "THYAO.IS", Date: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), Type: domain.ActionDividend, Amount: 3.5, Currency: domain.CurrencyTRY},
+		{Source: domain.SourceYahoo, Symbol: "THYAO.IS", Date: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Type: domain.ActionSplit, Numerator: 2, Denominator: 1, Currency: domain.CurrencyTRY},
+	}
+
+	n, err := repo.SaveActions(ctx, actions)
+	if err != nil {
+		t.Fatalf("save actions: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 rows, got %d", n)
+	}
+
+	// Duplicates are ignored.
+	n, err = repo.SaveActions(ctx, actions)
+	if err != nil {
+		t.Fatalf("save actions again: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("expected 0 rows (idempotent), got %d", n)
+	}
+
+	got, err := repo.ListActions(ctx, domain.SourceYahoo, "THYAO.IS",
+		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+	)
+	if err != nil {
+		t.Fatalf("list actions: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(got))
+	}
+	if got[0].Type != domain.ActionDividend || got[0].Amount != 3.5 {
+		t.Errorf("unexpected dividend: %+v", got[0])
+	}
+	if got[1].Type != domain.ActionSplit || got[1].Numerator != 2 || got[1].Denominator != 1 {
+		t.Errorf("unexpected split: %+v", got[1])
+	}
+}
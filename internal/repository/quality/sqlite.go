@@ -0,0 +1,171 @@
+package quality
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	domain "github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
+
+const dateFormat = "2006-01-02"
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Quarantine(ctx context.Context, points []domain.Point) error {
+	if len(points) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO quarantined_prices
+		(source, symbol, date, open_price, high_price, low_price, close_price, adj_close, volume, flags, job_id, flagged_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (source, symbol, date) DO UPDATE SET
+			fetches = CASE WHEN close_price IS excluded.close_price THEN fetches + 1 ELSE 1 END,
+			open_price = excluded.open_price, high_price = excluded.high_price, low_price = excluded.low_price,
+			close_price = excluded.close_price, adj_close = excluded.adj_close, volume = excluded.volume,
+			flags = excluded.flags, job_id = excluded.job_id, flagged_at = excluded.flagged_at`)
+	if err != nil {
+		return fmt.Errorf("prepare quarantine: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, p := range points {
+		flags, err := json.Marshal(p.Flags)
+		if err != nil {
+			return fmt.Errorf("marshal flags: %w", err)
+		}
+		args := append([]any{p.Source, p.Symbol, p.Date.Format(dateFormat)}, values(p)...)
+		if _, err := stmt.ExecContext(ctx, append(args, string(flags), sql.NullInt64{Int64: p.JobID, Valid: p.JobID != 0},
+			p.FlaggedAt.UTC().Format(time.RFC3339))...); err != nil {
+			return fmt.Errorf("quarantine price: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) Release(ctx context.Context, source, symbol string, dates []time.Time) error {
+	if len(dates) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.PrepareContext(ctx,
+		"DELETE FROM quarantined_prices WHERE source = ? AND symbol = ? AND date = ?")
+	if err != nil {
+		return fmt.Errorf("prepare release: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for _, d := range dates {
+		if _, err := stmt.ExecContext(ctx, source, symbol, d.Format(dateFormat)); err != nil {
+			return fmt.Errorf("release price: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *Repository) List(ctx context.Context, source, symbol string, from, to time.Time) ([]domain.Point, error) {
+	const query = `SELECT source, symbol, date, open_price, high_price, low_price, close_price, adj_close,
+		volume, flags, job_id, flagged_at, fetches
+		FROM quarantined_prices
+		WHERE source = ? AND symbol = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC`
+
+	toStr := "9999-12-31"
+	if !to.IsZero() {
+		toStr = to.Format(dateFormat)
+	}
+	rows, err := r.db.QueryContext(ctx, query, source, symbol, from.Format(dateFormat), toStr)
+	if err != nil {
+		return nil, fmt.Errorf("list quarantined prices: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var points []domain.Point
+	for rows.Next() {
+		var p domain.Point
+		var dateStr, flags, flaggedStr string
+		var open, high, low, closePrice, adjClose, volume sql.Null[decimal.Decimal]
+		var jobID sql.NullInt64
+		if err := rows.Scan(&p.Source, &p.Symbol, &dateStr, &open, &high, &low, &closePrice, &adjClose,
+			&volume, &flags, &jobID, &flaggedStr, &p.Fetches); err != nil {
+			return nil, fmt.Errorf("scan quarantined price: %w", err)
+		}
+		if err := json.Unmarshal([]byte(flags), &p.Flags); err != nil {
+			return nil, fmt.Errorf("unmarshal flags: %w", err)
+		}
+		e := scraper.Exact{Open: open.V, High: high.V, Low: low.V, ClosePrice: closePrice.V,
+			AdjClose: adjClose.V, Volume: volume.V}
+		for _, v := range []struct {
+			field scraper.Field
+			dst   *float64
+			value sql.Null[decimal.Decimal]
+		}{
+			{scraper.FieldOpen, &p.Open, open},
+			{scraper.FieldHigh, &p.High, high},
+			{scraper.FieldLow, &p.Low, low},
+			{scraper.FieldAdjClose, &p.AdjClose, adjClose},
+			{scraper.FieldVolume, &p.Volume, volume},
+		} {
+			*v.dst = v.value.V.Float64()
+			if v.value.Valid {
+				p.Reported |= v.field
+			}
+		}
+		p.ClosePrice = e.ClosePrice.Float64()
+		p.Exact = &e
+		p.JobID = jobID.Int64
+		p.Date, _ = time.Parse(dateFormat, dateStr)
+		p.FlaggedAt, _ = time.Parse(time.RFC3339, flaggedStr)
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// values returns the price columns of p, open to volume, as decimals: the
+// published ones when p has them. Values the source did not report and
+// values that are not finite numbers are stored as NULL.
+func values(p domain.Point) []any {
+	var e scraper.Exact
+	if p.Exact != nil {
+		e = *p.Exact
+	}
+	value := func(v float64, d decimal.Decimal, f scraper.Field) any {
+		switch {
+		case !p.Reported.Has(f), math.IsNaN(v), math.IsInf(v, 0):
+			return nil
+		case p.Exact != nil:
+			return d
+		default:
+			return decimal.FromFloat(v)
+		}
+	}
+	// The close is not optional; Has of no field is always true.
+	return []any{value(p.Open, e.Open, scraper.FieldOpen), value(p.High, e.High, scraper.FieldHigh),
+		value(p.Low, e.Low, scraper.FieldLow), value(p.ClosePrice, e.ClosePrice, 0),
+		value(p.AdjClose, e.AdjClose, scraper.FieldAdjClose), value(p.Volume, e.Volume, scraper.FieldVolume)}
+}
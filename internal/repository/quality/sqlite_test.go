@@ -0,0 +1,115 @@
+package quality
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
+	domain "github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+)
+
+func setupTestDB(t *testing.T) *sqlite.DB {
+	t.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestQuarantine_Exact(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	// More digits than a float64 holds.
+	closePrice, _ := decimal.Parse("0.36759812345678901")
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if err := repo.Quarantine(ctx, []domain.Point{{Source: "tefas", Symbol: "YAC", Date: day,
+		ClosePrice: closePrice.Float64(), Exact: &scraper.Exact{ClosePrice: closePrice}, FlaggedAt: day,
+		Flags: []domain.Flag{{Rule: domain.RuleMove}}}}); err != nil {
+		t.Fatalf("quarantine: %v", err)
+	}
+
+	got, err := repo.List(ctx, "tefas", "YAC", day, day)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 point, got %+v", got)
+	}
+	if e := got[0].Price().Exact; e == nil || e.ClosePrice.String() != "0.36759812345678901" {
+		t.Errorf("expected the published close, got %+v", e)
+	}
+}
+
+func TestQuarantineListRelease(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	jan2 := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	jan3 := jan2.AddDate(0, 0, 1)
+	flaggedAt := time.Date(2024, 1, 5, 18, 0, 0, 0, time.UTC)
+	points := []domain.Point{
+		{Source: "yahoo", Symbol: "AAPL", Date: jan2, ClosePrice: math.NaN(), Volume: 100, Reported: scraper.FieldVolume, JobID: 4, FlaggedAt: flaggedAt,
+			Flags: []domain.Flag{{Rule: domain.RuleInvalid, Detail: "close price is not a number"}}},
+		{Source: "yahoo", Symbol: "AAPL", Date: jan3, ClosePrice: 1850, Reported: scraper.FieldVolume, FlaggedAt: flaggedAt,
+			Flags: []domain.Flag{{Rule: domain.RuleMove, Detail: "close moved +900.0%"}}},
+		{Source: "yahoo", Symbol: "MSFT", Date: jan3, ClosePrice: 1, FlaggedAt: flaggedAt,
+			Flags: []domain.Flag{{Rule: domain.RuleStale}}},
+	}
+	if err := repo.Quarantine(ctx, points); err != nil {
+		t.Fatalf("quarantine: %v", err)
+	}
+	// Flagged again: replaces the earlier point, and counts a fetch when
+	// the close is the same.
+	points[1].Flags = append(points[1].Flags, domain.Flag{Rule: domain.RuleStale})
+	if err := repo.Quarantine(ctx, points[1:2]); err != nil {
+		t.Fatalf("quarantine again: %v", err)
+	}
+	points[2].ClosePrice = 2
+	if err := repo.Quarantine(ctx, points[2:3]); err != nil {
+		t.Fatalf("quarantine again: %v", err)
+	}
+
+	got, err := repo.List(ctx, "yahoo", "AAPL", jan2, time.Time{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 points, got %+v", got)
+	}
+	if p := got[0]; p.ClosePrice != 0 || p.Volume != 100 || p.Reported != scraper.FieldVolume || p.JobID != 4 || !p.FlaggedAt.Equal(flaggedAt) ||
+		len(p.Flags) != 1 || p.Flags[0].Rule != domain.RuleInvalid {
+		t.Errorf("unexpected point %+v", p)
+	}
+	if p := got[1]; !p.Date.Equal(jan3) || p.ClosePrice != 1850 || p.Volume != 0 ||
+		p.Reported != scraper.FieldVolume || len(p.Flags) != 2 || p.Fetches != 2 {
+		t.Errorf("unexpected point %+v", p)
+	}
+
+	got, err = repo.List(ctx, "yahoo", "MSFT", jan3, jan3)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 || got[0].ClosePrice != 2 || got[0].Fetches != 1 {
+		t.Errorf("expected a changed close to start again at one fetch, got %+v", got)
+	}
+
+	if err := repo.Release(ctx, "yahoo", "AAPL", []time.Time{jan2}); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	got, err = repo.List(ctx, "yahoo", "AAPL", jan2, jan3)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 1 || !got[0].Date.Equal(jan3) {
+		t.Errorf("expected only Jan 3 left, got %+v", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	domain "github.com/ahmethakanbesel/finance-api/internal/rate"
 )
 
@@ -36,14 +37,19 @@ func (r *Repository) SaveRates(ctx context.Context, rates []domain.Rate) (int64,
 		batch := rates[i:end]
 
 		placeholders := make([]string, len(batch))
-		args := make([]any, 0, len(batch)*3)
+		args := make([]any, 0, len(batch)*6)
 		for j, rate := range batch {
-			placeholders[j] = "(?, ?, ?)"
-			args = append(args, rate.Pair, rate.Date.Format(dateFormat), rate.Rate)
+			source := rate.Source
+			if source == "" {
+				source = domain.DefaultSource
+			}
+			q := rate.Decimals()
+			placeholders[j] = "(?, ?, ?, ?, ?, ?)"
+			args = append(args, source, rate.Pair, rate.Date.Format(dateFormat), q.Rate, nullDecimal(q.Bid), nullDecimal(q.Ask))
 		}
 
 		query := fmt.Sprintf( //nolint:gosec // placeholders are not user input
-			"INSERT OR IGNORE INTO exchange_rates (pair, date, rate) VALUES %s",
+			"INSERT OR IGNORE INTO exchange_rates (source, pair, date, rate, bid, ask) VALUES %s",
 			strings.Join(placeholders, ", "),
 		)
 
@@ -59,13 +65,13 @@ func (r *Repository) SaveRates(ctx context.Context, rates []domain.Rate) (int64,
 	return total, nil
 }
 
-func (r *Repository) ListRates(ctx context.Context, pair string, from, to time.Time) ([]domain.Rate, error) {
-	const query = `SELECT id, pair, date, rate, created_at
+func (r *Repository) ListRates(ctx context.Context, source, pair string, from, to time.Time) ([]domain.Rate, error) {
+	const query = `SELECT id, source, pair, date, rate, bid, ask, created_at
 		FROM exchange_rates
-		WHERE pair = ? AND date >= ? AND date <= ?
+		WHERE source = ? AND pair = ? AND date >= ? AND date <= ?
 		ORDER BY date ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, pair, from.Format(dateFormat), to.Format(dateFormat))
+	rows, err := r.db.QueryContext(ctx, query, source, pair, from.Format(dateFormat), to.Format(dateFormat))
 	if err != nil {
 		return nil, fmt.Errorf("list rates: %w", err)
 	}
@@ -74,10 +80,13 @@ func (r *Repository) ListRates(ctx context.Context, pair string, from, to time.T
 	var rates []domain.Rate
 	for rows.Next() {
 		var rate domain.Rate
+		var q domain.Quote
 		var dateStr, createdStr string
-		if err := rows.Scan(&rate.ID, &rate.Pair, &dateStr, &rate.Rate, &createdStr); err != nil {
+		if err := rows.Scan(&rate.ID, &rate.Source, &rate.Pair, &dateStr, &q.Rate, &q.Bid, &q.Ask, &createdStr); err != nil {
 			return nil, fmt.Errorf("scan rate: %w", err)
 		}
+		rate.Rate, rate.Bid, rate.Ask = q.Rate.Float64(), q.Bid.Float64(), q.Ask.Float64()
+		rate.Exact = &q
 		rate.Date, _ = time.Parse(dateFormat, dateStr)
 		rate.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
 		rates = append(rates, rate)
@@ -86,11 +95,11 @@ func (r *Repository) ListRates(ctx context.Context, pair string, from, to time.T
 	return rates, rows.Err()
 }
 
-func (r *Repository) ExistingDates(ctx context.Context, pair string, from, to time.Time) (map[time.Time]bool, error) {
+func (r *Repository) ExistingDates(ctx context.Context, source, pair string, from, to time.Time) (map[time.Time]bool, error) {
 	const query = `SELECT date FROM exchange_rates
-		WHERE pair = ? AND date >= ? AND date <= ?`
+		WHERE source = ? AND pair = ? AND date >= ? AND date <= ?`
 
-	rows, err := r.db.QueryContext(ctx, query, pair, from.Format(dateFormat), to.Format(dateFormat))
+	rows, err := r.db.QueryContext(ctx, query, source, pair, from.Format(dateFormat), to.Format(dateFormat))
 	if err != nil {
 		return nil, fmt.Errorf("existing rate dates: %w", err)
 	}
@@ -108,3 +117,38 @@ func (r *Repository) ExistingDates(ctx context.Context, pair string, from, to ti
 
 	return dates, rows.Err()
 }
+
+func (r *Repository) ListPairs(ctx context.Context) ([]domain.PairCoverage, error) {
+	const query = `SELECT source, pair, MIN(date), MAX(date), COUNT(*)
+		FROM exchange_rates
+		GROUP BY source, pair
+		ORDER BY source ASC, pair ASC`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list pairs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var pairs []domain.PairCoverage
+	for rows.Next() {
+		var pc domain.PairCoverage
+		var firstStr, lastStr string
+		if err := rows.Scan(&pc.Source, &pc.Pair, &firstStr, &lastStr, &pc.Count); err != nil {
+			return nil, fmt.Errorf("scan pair: %w", err)
+		}
+		pc.FirstDate, _ = time.Parse(dateFormat, firstStr)
+		pc.LastDate, _ = time.Parse(dateFormat, lastStr)
+		pairs = append(pairs, pc)
+	}
+
+	return pairs, rows.Err()
+}
+
+// nullDecimal stores 0 (not reported) as NULL.
+func nullDecimal(d decimal.Decimal) any {
+	if d.IsZero() {
+		return nil
+	}
+	return d
+}
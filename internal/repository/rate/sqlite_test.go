@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
 	domain "github.com/ahmethakanbesel/finance-api/internal/rate"
 )
@@ -38,7 +39,7 @@ func TestSaveRates_And_ListRates(t *testing.T) {
 		t.Errorf("expected 3 rows inserted, got %d", n)
 	}
 
-	got, err := repo.ListRates(ctx, domain.PairUSDTRY,
+	got, err := repo.ListRates(ctx, domain.SourceYahoo, domain.PairUSDTRY,
 		time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
 		time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC),
 	)
@@ -53,6 +54,32 @@ func TestSaveRates_And_ListRates(t *testing.T) {
 	}
 }
 
+func TestSaveRates_Exact(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	// 100/22.4 JPY per TRY has no finite decimal expansion.
+	rate, _ := decimal.Parse("100")
+	buying, _ := decimal.Parse("22.4")
+	q := domain.Quote{Rate: rate.Quo(buying), Bid: rate.Quo(buying), Ask: rate.Quo(buying)}
+	day := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.SaveRates(ctx, []domain.Rate{
+		{Source: domain.SourceTCMB, Pair: "TRYJPY", Date: day, Rate: q.Rate.Float64(), Exact: &q},
+	}); err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+
+	got, err := repo.ListRates(ctx, domain.SourceTCMB, "TRYJPY", day, day)
+	if err != nil {
+		t.Fatalf("list rates: %v", err)
+	}
+	if len(got) != 1 || got[0].Exact == nil || got[0].Exact.Rate.String() != "4.464285714285714286" ||
+		got[0].Rate != q.Rate.Float64() {
+		t.Errorf("unexpected rates %+v", got)
+	}
+}
+
 func TestSaveRates_Idempotent(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewRepository(db.DB)
@@ -92,7 +119,7 @@ func TestExistingDates(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	dates, err := repo.ExistingDates(ctx, domain.PairUSDTRY,
+	dates, err := repo.ExistingDates(ctx, domain.SourceYahoo, domain.PairUSDTRY,
 		time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
 		time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
 	)
@@ -121,3 +148,65 @@ func TestSaveRates_Empty(t *testing.T) {
 		t.Errorf("expected 0, got %d", n)
 	}
 }
+
+func TestListPairs(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	first := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	if _, err := repo.SaveRates(ctx, []domain.Rate{
+		{Pair: domain.PairUSDTRY, Date: first, Rate: 29.50},
+		{Pair: domain.PairUSDTRY, Date: last, Rate: 29.60},
+		{Pair: "EURUSD", Date: first, Rate: 1.09},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := repo.ListPairs(ctx)
+	if err != nil {
+		t.Fatalf("list pairs: %v", err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(pairs))
+	}
+	usd := pairs[1]
+	if usd.Pair != domain.PairUSDTRY || usd.Count != 2 || !usd.FirstDate.Equal(first) || !usd.LastDate.Equal(last) {
+		t.Errorf("unexpected coverage: %+v", usd)
+	}
+}
+
+func TestSaveRates_PerSource(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	n, err := repo.SaveRates(ctx, []domain.Rate{
+		{Pair: domain.PairUSDTRY, Date: day, Rate: 29.50},
+		{Source: domain.SourceTCMB, Pair: domain.PairUSDTRY, Date: day, Rate: 29.45, Bid: 29.45, Ask: 29.50},
+	})
+	if err != nil {
+		t.Fatalf("save rates: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected the same day to be stored once per source, got %d rows", n)
+	}
+
+	got, err := repo.ListRates(ctx, domain.SourceTCMB, domain.PairUSDTRY, day, day)
+	if err != nil {
+		t.Fatalf("list rates: %v", err)
+	}
+	if len(got) != 1 || got[0].Source != domain.SourceTCMB || got[0].Bid != 29.45 || got[0].Ask != 29.50 {
+		t.Fatalf("unexpected tcmb rates: %+v", got)
+	}
+
+	got, err = repo.ListRates(ctx, domain.SourceYahoo, domain.PairUSDTRY, day, day)
+	if err != nil {
+		t.Fatalf("list rates: %v", err)
+	}
+	if len(got) != 1 || got[0].Rate != 29.50 || got[0].Bid != 0 {
+		t.Fatalf("unexpected yahoo rates: %+v", got)
+	}
+}
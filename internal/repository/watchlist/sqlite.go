@@ -0,0 +1,97 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	domain "github.com/ahmethakanbesel/finance-api/internal/watchlist"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Add(ctx context.Context, e *domain.Entry) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		"INSERT OR IGNORE INTO watchlist (source, symbol) VALUES (?, ?)", e.Source, e.Symbol)
+	if err != nil {
+		return false, fmt.Errorf("add watchlist entry: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	const query = `SELECT id, source, symbol, created_at, refreshed_at
+		FROM watchlist WHERE source = ? AND symbol = ?`
+	if err := scanEntry(r.db.QueryRowContext(ctx, query, e.Source, e.Symbol), e); err != nil {
+		return false, fmt.Errorf("get watchlist entry: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *Repository) List(ctx context.Context) ([]domain.Entry, error) {
+	const query = `SELECT id, source, symbol, created_at, refreshed_at
+		FROM watchlist ORDER BY source, symbol`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list watchlist: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var entries []domain.Entry
+	for rows.Next() {
+		var e domain.Entry
+		if err := scanEntry(rows, &e); err != nil {
+			return nil, fmt.Errorf("scan watchlist entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (r *Repository) Remove(ctx context.Context, source, symbol string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM watchlist WHERE source = ? AND symbol = ?", source, symbol)
+	if err != nil {
+		return false, fmt.Errorf("remove watchlist entry: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *Repository) MarkRefreshed(ctx context.Context, id int64, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE watchlist SET refreshed_at = ? WHERE id = ?",
+		at.UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return fmt.Errorf("mark watchlist entry refreshed: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEntry(s scanner, e *domain.Entry) error {
+	var createdStr string
+	var refreshedStr sql.NullString
+	if err := s.Scan(&e.ID, &e.Source, &e.Symbol, &createdStr, &refreshedStr); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("watchlist entry %s/%s not found", e.Source, e.Symbol)
+		}
+		return err
+	}
+	e.CreatedAt, _ = time.Parse(time.RFC3339, createdStr)
+	e.RefreshedAt = nil
+	if refreshedStr.Valid {
+		if t, err := time.Parse(time.RFC3339, refreshedStr.String); err == nil {
+			e.RefreshedAt = &t
+		}
+	}
+	return nil
+}
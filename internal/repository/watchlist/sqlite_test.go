@@ -0,0 +1,76 @@
+package watchlist
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
+	domain "github.com/ahmethakanbesel/finance-api/internal/watchlist"
+)
+
+func setupTestDB(t *testing.T) *sqlite.DB {
+	t.Helper()
+	db, err := sqlite.Open(":memory:")
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func TestWatchlist(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRepository(db.DB)
+	ctx := context.Background()
+
+	e := &domain.Entry{Source: "tefas", Symbol: "YAC"}
+	created, err := repo.Add(ctx, e)
+	if err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if !created || e.ID == 0 || e.CreatedAt.IsZero() {
+		t.Fatalf("expected a new entry, got created=%v %+v", created, e)
+	}
+
+	dup := &domain.Entry{Source: "tefas", Symbol: "YAC"}
+	created, err = repo.Add(ctx, dup)
+	if err != nil {
+		t.Fatalf("add again: %v", err)
+	}
+	if created || dup.ID != e.ID {
+		t.Errorf("expected the existing entry %d, got created=%v %+v", e.ID, created, dup)
+	}
+
+	if _, err := repo.Add(ctx, &domain.Entry{Source: "yahoo", Symbol: "AAPL"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	at := time.Date(2024, 1, 5, 17, 0, 0, 0, time.UTC)
+	if err := repo.MarkRefreshed(ctx, e.ID, at); err != nil {
+		t.Fatalf("mark refreshed: %v", err)
+	}
+
+	entries, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Symbol != "YAC" || entries[1].Symbol != "AAPL" {
+		t.Fatalf("expected tefas/YAC and yahoo/AAPL, got %+v", entries)
+	}
+	if entries[0].RefreshedAt == nil || !entries[0].RefreshedAt.Equal(at) {
+		t.Errorf("expected refreshedAt %s, got %v", at, entries[0].RefreshedAt)
+	}
+	if entries[1].RefreshedAt != nil {
+		t.Errorf("expected no refreshedAt, got %v", entries[1].RefreshedAt)
+	}
+
+	ok, err := repo.Remove(ctx, "tefas", "YAC")
+	if err != nil || !ok {
+		t.Fatalf("remove: ok=%v err=%v", ok, err)
+	}
+	ok, err = repo.Remove(ctx, "tefas", "YAC")
+	if err != nil || ok {
+		t.Errorf("remove again: expected false, got ok=%v err=%v", ok, err)
+	}
+}
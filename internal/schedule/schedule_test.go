@@ -0,0 +1,58 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 17 * * 1-5", "2024-01-05 16:59", "2024-01-05 17:00"},
+		{"0 17 * * 1-5", "2024-01-05 17:00", "2024-01-08 17:00"}, // Friday to Monday
+		{"*/15 * * * *", "2024-01-01 10:07", "2024-01-01 10:15"},
+		{"30 9 1 * *", "2024-01-31 12:00", "2024-02-01 09:30"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		{"0 12 * * 0", "2024-01-01 00:00", "2024-01-07 12:00"},
+		{"0 12 * * 7", "2024-01-01 00:00", "2024-01-07 12:00"},
+		{"0 8 15 * 1", "2024-01-02 00:00", "2024-01-08 08:00"}, // day of month or Monday
+		{"0 0 31 12 *", "2024-12-31 00:00", "2025-12-31 00:00"},
+		{"0 0 30 2 *", "2024-01-01 00:00", "0001-01-01 00:00"}, // never
+	}
+	for _, tt := range tests {
+		got := MustParse(tt.expr).Next(at(tt.from))
+		if !got.Equal(at(tt.want)) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.expr, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q): expected error", expr)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+// Package cassette records upstream HTTP responses to fixture files and
+// replays them, so that scrapers can be tested against real payloads and the
+// server can run without network access.
+//
+// A Transport is an http.RoundTripper, installed with a scraper's WithClient
+// option. Each request is stored as one JSON file under
+// <dir>/<host>/<method>-<hash>.json, where the hash covers the method, the
+// redacted URL and the request body. Secrets are redacted before anything is
+// written: query parameters such as the Yahoo crumb, cookies, and the bodies
+// of token endpoints. Since the URL is redacted before hashing, a replay
+// matches the recording whatever crumb the scraper sends.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
+)
+
+// Mode selects what a Transport does with requests.
+type Mode string
+
+const (
+	// Live sends requests upstream and stores nothing.
+	Live Mode = "live"
+	// Record sends requests upstream and saves their responses, overwriting
+	// earlier recordings of the same request. Only successful responses and
+	// 404s are saved, so an outage while recording does not replace a good
+	// recording with an error.
+	Record Mode = "record"
+	// Replay serves saved responses and never touches the network.
+	Replay Mode = "replay"
+)
+
+// ParseMode parses a mode name; the empty string is Live.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(strings.ToLower(s)); m {
+	case "":
+		return Live, nil
+	case Live, Record, Replay:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown scraper mode %q (want live, record or replay)", s)
+	}
+}
+
+// Redacted replaces secret values in recordings.
+const Redacted = "REDACTED"
+
+var (
+	defaultParams = httpx.SecretParams
+	// Yahoo's crumb endpoint returns the crumb as the whole body.
+	defaultBodies = []string{"getcrumb"}
+	// Headers dropped from recorded responses.
+	secretHeaders = []string{"Set-Cookie", "Authorization", "Cookie"}
+)
+
+// Transport records or replays requests depending on its mode.
+type Transport struct {
+	mode   Mode
+	dir    string
+	base   http.RoundTripper
+	params []string // lower-case query parameters to redact
+	bodies []string // URL path fragments whose response bodies are redacted
+}
+
+// New creates a Transport that keeps fixtures in dir and sends upstream
+// requests with base (http.DefaultTransport when nil).
+func New(mode Mode, dir string, base http.RoundTripper, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		mode:   mode,
+		dir:    dir,
+		base:   base,
+		params: defaultParams,
+		bodies: defaultBodies,
+	}
+	for _, o := range opts {
+		o(t)
+	}
+	return t
+}
+
+type Option func(*Transport)
+
+// WithRedactedParams adds query parameters whose values are redacted.
+func WithRedactedParams(names ...string) Option {
+	return func(t *Transport) {
+		for _, n := range names {
+			t.params = append(slices.Clip(t.params), strings.ToLower(n))
+		}
+	}
+}
+
+// WithRedactedBodies adds URL path fragments whose response bodies are
+// replaced by Redacted, for endpoints that return a token.
+func WithRedactedBodies(pathFragments ...string) Option {
+	return func(t *Transport) { t.bodies = append(slices.Clip(t.bodies), pathFragments...) }
+}
+
+// fixture is the file format of one recorded exchange.
+type fixture struct {
+	Request struct {
+		Method string `json:"method"`
+		URL    string `json:"url"`
+		Body   string `json:"body,omitempty"`
+	} `json:"request"`
+	Response struct {
+		Status     int         `json:"status"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+		BodyBase64 string      `json:"bodyBase64,omitempty"` // for bodies that are not UTF-8
+	} `json:"response"`
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.mode == Live || t.mode == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	u := t.redactURL(req.URL)
+	path := t.path(req.Method, u, body)
+
+	if t.mode == Replay {
+		return t.replay(req, path)
+	}
+
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	res, err := t.base.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	_ = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	// The caller gets the real body; only the recording is redacted.
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	if !recordable(res.StatusCode) {
+		slog.Warn("cassette: not recording error response", "method", req.Method, "url", u, "status", res.StatusCode)
+		return res, nil
+	}
+
+	var f fixture
+	f.Request.Method = req.Method
+	f.Request.URL = u
+	f.Request.Body = string(body)
+	f.Response.Status = res.StatusCode
+	f.Response.Header = res.Header.Clone()
+	for _, h := range secretHeaders {
+		f.Response.Header.Del(h)
+	}
+	f.Response.Header.Del("Content-Length") // the body may be redacted
+	stored := resBody
+	if t.redactBody(req.URL.Path) {
+		stored = []byte(Redacted)
+	}
+	if utf8.Valid(stored) {
+		f.Response.Body = string(stored)
+	} else {
+		f.Response.BodyBase64 = base64.StdEncoding.EncodeToString(stored)
+	}
+	if err := save(path, &f); err != nil {
+		return nil, fmt.Errorf("cassette: save %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// recordable reports whether a response is worth keeping: a success, or a
+// 404 that a scraper treats as "no data". Rate limits, outages and other
+// errors are transient and are not recorded.
+func recordable(status int) bool {
+	return status >= 200 && status < 300 || status == http.StatusNotFound
+}
+
+// replay serves the recording at path. Requests that were never recorded get
+// a 404, so a scraper sees "not found" instead of an outage it would retry.
+func (t *Transport) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		slog.Warn("cassette: no recording", "method", req.Method, "url", t.redactURL(req.URL), "file", path)
+		return response(req, http.StatusNotFound, nil, []byte("no recording for "+req.Method+" "+t.redactURL(req.URL))), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassette: read %s: %w", path, err)
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cassette: parse %s: %w", path, err)
+	}
+	body := []byte(f.Response.Body)
+	if f.Response.BodyBase64 != "" {
+		if body, err = base64.StdEncoding.DecodeString(f.Response.BodyBase64); err != nil {
+			return nil, fmt.Errorf("cassette: decode body of %s: %w", path, err)
+		}
+	}
+	return response(req, f.Response.Status, f.Response.Header, body), nil
+}
+
+func response(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// redactURL returns u with the values of secret query parameters replaced.
+func (t *Transport) redactURL(u *url.URL) string {
+	q := u.Query()
+	for name := range q {
+		if slices.Contains(t.params, strings.ToLower(name)) {
+			q.Set(name, Redacted)
+		}
+	}
+	r := *u
+	r.User = nil
+	r.RawQuery = q.Encode()
+	return r.String()
+}
+
+func (t *Transport) redactBody(path string) bool {
+	return slices.ContainsFunc(t.bodies, func(frag string) bool { return strings.Contains(path, frag) })
+}
+
+// path returns the fixture file of a request.
+func (t *Transport) path(method, redactedURL string, body []byte) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%s %s\n", method, redactedURL)
+	_, _ = h.Write(body)
+	name := strings.ToLower(method) + "-" + hex.EncodeToString(h.Sum(nil))[:16] + ".json"
+
+	host := "unknown"
+	if u, err := url.Parse(redactedURL); err == nil && u.Host != "" {
+		host = strings.ReplaceAll(u.Host, ":", "_")
+	}
+	return filepath.Join(t.dir, host, name)
+}
+
+// save writes f to path through a temporary file, so that concurrent
+// recordings of the same request never leave a torn fixture.
+func save(path string, f *fixture) error {
+	// Bodies are mostly JSON and HTML; keep them readable in diffs.
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(f); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
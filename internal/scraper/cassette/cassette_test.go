@@ -0,0 +1,157 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newUpstream(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var hits atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		switch r.URL.Path {
+		case "/getcrumb":
+			http.SetCookie(w, &http.Cookie{Name: "A3", Value: "secret-session"})
+			_, _ = w.Write([]byte("secret-crumb"))
+		case "/form":
+			b, _ := io.ReadAll(r.Body)
+			_, _ = w.Write([]byte("form:" + string(b)))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"symbol":"` + r.URL.Query().Get("symbol") + `"}`))
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &hits
+}
+
+func do(t *testing.T, c *http.Client, method, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer func() { _ = res.Body.Close() }()
+	b, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(b)
+}
+
+func TestRecordReplay(t *testing.T) {
+	ts, hits := newUpstream(t)
+	dir := t.TempDir()
+
+	rec := &http.Client{Transport: New(Record, dir, nil)}
+	if _, body := do(t, rec, "GET", ts.URL+"/chart?symbol=AAPL&crumb=abc", ""); body != `{"symbol":"AAPL"}` {
+		t.Errorf("record returned %q", body)
+	}
+	if _, body := do(t, rec, "GET", ts.URL+"/getcrumb", ""); body != "secret-crumb" {
+		t.Errorf("recording must not alter the live response, got %q", body)
+	}
+	do(t, rec, "POST", ts.URL+"/form", "fonkod=YAC")
+	do(t, rec, "POST", ts.URL+"/form", "fonkod=TTE")
+	if hits.Load() != 4 {
+		t.Fatalf("expected 4 upstream requests while recording, got %d", hits.Load())
+	}
+
+	// Secrets never reach the fixture files.
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, _ := os.ReadFile(path)
+		for _, secret := range []string{"abc", "secret-crumb", "secret-session"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q:\n%s", path, secret, data)
+			}
+		}
+		return nil
+	})
+
+	rep := &http.Client{Transport: New(Replay, dir, nil)}
+	tests := []struct {
+		method, url, body string
+		status            int
+		want              string
+	}{
+		// A different crumb still matches the recording.
+		{"GET", ts.URL + "/chart?symbol=AAPL&crumb=xyz", "", 200, `{"symbol":"AAPL"}`},
+		{"GET", ts.URL + "/getcrumb", "", 200, Redacted},
+		{"POST", ts.URL + "/form", "fonkod=TTE", 200, "form:fonkod=TTE"},
+		{"POST", ts.URL + "/form", "fonkod=YAC", 200, "form:fonkod=YAC"},
+		{"GET", ts.URL + "/chart?symbol=MSFT", "", 404, ""},
+	}
+	for _, tt := range tests {
+		status, body := do(t, rep, tt.method, tt.url, tt.body)
+		if status != tt.status || (tt.want != "" && body != tt.want) {
+			t.Errorf("%s %s %q: got %d %q, want %d %q", tt.method, tt.url, tt.body, status, body, tt.status, tt.want)
+		}
+	}
+	if hits.Load() != 4 {
+		t.Errorf("replay reached the upstream (%d requests)", hits.Load())
+	}
+}
+
+func TestRecord_KeepsGoodRecordingOnError(t *testing.T) {
+	var status atomic.Int64
+	status.Store(http.StatusOK)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+		_, _ = w.Write([]byte("status " + http.StatusText(int(status.Load()))))
+	}))
+	t.Cleanup(ts.Close)
+	dir := t.TempDir()
+
+	rec := &http.Client{Transport: New(Record, dir, nil)}
+	do(t, rec, "GET", ts.URL+"/chart?symbol=AAPL", "")
+	for _, s := range []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadRequest} {
+		status.Store(int64(s))
+		if got, _ := do(t, rec, "GET", ts.URL+"/chart?symbol=AAPL", ""); got != s {
+			t.Errorf("record must pass the live status through, got %d want %d", got, s)
+		}
+	}
+	do(t, rec, "GET", ts.URL+"/chart?symbol=MSFT", "") // 400: never recorded
+
+	rep := &http.Client{Transport: New(Replay, dir, nil)}
+	if got, body := do(t, rep, "GET", ts.URL+"/chart?symbol=AAPL", ""); got != http.StatusOK || body != "status OK" {
+		t.Errorf("expected the successful recording, got %d %q", got, body)
+	}
+	if got, body := do(t, rep, "GET", ts.URL+"/chart?symbol=MSFT", ""); got != http.StatusNotFound || strings.Contains(body, "Bad Request") {
+		t.Errorf("expected no recording, got %d %q", got, body)
+	}
+}
+
+func TestLive(t *testing.T) {
+	ts, hits := newUpstream(t)
+	dir := t.TempDir()
+
+	c := &http.Client{Transport: New(Live, dir, nil)}
+	do(t, c, "GET", ts.URL+"/chart?symbol=AAPL", "")
+	if hits.Load() != 1 {
+		t.Errorf("expected the request upstream")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("live mode wrote %d fixtures", len(entries))
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]Mode{"": Live, "live": Live, "RECORD": Record, "replay": Replay} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseMode("offline"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
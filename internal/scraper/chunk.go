@@ -1,6 +1,11 @@
 package scraper
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
 type DateRange struct {
 	From time.Time
@@ -22,3 +27,65 @@ func SplitDateRange(from, to time.Time, chunkDays int) []DateRange {
 	}
 	return chunks
 }
+
+// ChunkError is a sub-range of a scrape whose request failed.
+type ChunkError struct {
+	DateRange
+	Err error
+}
+
+// PartialError is returned together with the data of the chunks that
+// succeeded when only some chunks of a scrape failed. Callers that can store
+// partial results check for it with errors.As; the others treat it as any
+// other error.
+type PartialError struct {
+	Chunks int // number of chunks the range was split into
+	Failed []ChunkError
+}
+
+func (e *PartialError) Error() string {
+	f := e.Failed[0]
+	return fmt.Sprintf("%d of %d chunks failed, first %s..%s: %v",
+		len(e.Failed), e.Chunks, f.From.Format("2006-01-02"), f.To.Format("2006-01-02"), f.Err)
+}
+
+func (e *PartialError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, f := range e.Failed {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// IsPartial reports whether err only means that some chunks failed, in which
+// case the data of the other chunks was returned with it.
+func IsPartial(err error) bool {
+	var partial *PartialError
+	return errors.As(err, &partial)
+}
+
+// ChunkErrors combines the outcome of fetching chunks[i] into errs[i] (nil on
+// success). It returns nil when every chunk succeeded, the first error when
+// all of them failed, and a *PartialError otherwise. If ctx was cancelled the
+// scrape as a whole is abandoned and ctx's error is returned.
+func ChunkErrors(ctx context.Context, chunks []DateRange, errs []error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed []ChunkError
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, ChunkError{DateRange: chunks[i], Err: err})
+		}
+	}
+	switch {
+	case len(failed) == 0:
+		return nil
+	case len(failed) == len(chunks):
+		f := failed[0]
+		return fmt.Errorf("%s..%s: %w", f.From.Format("2006-01-02"), f.To.Format("2006-01-02"), f.Err)
+	default:
+		return &PartialError{Chunks: len(chunks), Failed: failed}
+	}
+}
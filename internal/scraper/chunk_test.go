@@ -1,6 +1,8 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -89,3 +91,40 @@ func TestSplitDateRange(t *testing.T) {
 		})
 	}
 }
+
+func TestChunkErrors(t *testing.T) {
+	ctx := context.Background()
+	chunks := []DateRange{
+		{From: date(1, 1), To: date(2, 29)},
+		{From: date(3, 1), To: date(4, 29)},
+		{From: date(4, 30), To: date(5, 31)},
+	}
+	boom := errors.New("boom")
+
+	if err := ChunkErrors(ctx, chunks, make([]error, 3)); err != nil {
+		t.Errorf("expected nil when every chunk succeeded, got %v", err)
+	}
+
+	err := ChunkErrors(ctx, chunks, []error{nil, boom, nil})
+	var partial *PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected *PartialError, got %v", err)
+	}
+	if partial.Chunks != 3 || len(partial.Failed) != 1 || !partial.Failed[0].From.Equal(date(3, 1)) {
+		t.Errorf("unexpected partial error: %+v", partial)
+	}
+	if !errors.Is(err, boom) {
+		t.Error("expected partial error to wrap the chunk error")
+	}
+
+	err = ChunkErrors(ctx, chunks, []error{boom, boom, boom})
+	if errors.As(err, &partial) || !errors.Is(err, boom) {
+		t.Errorf("expected plain error when every chunk failed, got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := ChunkErrors(cancelled, chunks, []error{nil, boom, nil}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context error, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package httpx
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// Breakers holds a circuit breaker per host. A breaker opens after threshold
+// consecutive failed attempts and rejects requests for the cooldown; then a
+// single trial request is let through, which closes it on success and opens
+// it again on failure. Only outages count as failures: connection errors,
+// timeouts and server errors.
+//
+// Clients talking to the same hosts should share one Breakers.
+type Breakers struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time // zero while closed
+	trial     bool      // a half-open trial request is in flight
+}
+
+// NewBreakers creates breakers that open after threshold consecutive
+// failures for cooldown. A threshold of zero or less disables them.
+func NewBreakers(threshold int, cooldown time.Duration) *Breakers {
+	return &Breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		hosts:     make(map[string]*breaker),
+	}
+}
+
+// allow reports whether a request to host may be sent.
+func (b *Breakers) allow(host string) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.hosts[host]
+	if br == nil || br.openUntil.IsZero() {
+		return true
+	}
+	if b.now().Before(br.openUntil) || br.trial {
+		return false
+	}
+	br.trial = true
+	return true
+}
+
+// record reports the outcome of an attempt allowed for host.
+func (b *Breakers) record(host string, ok bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	br := b.hosts[host]
+	if br == nil {
+		if ok {
+			return
+		}
+		br = &breaker{}
+		b.hosts[host] = br
+	}
+	if ok {
+		*br = breaker{}
+		return
+	}
+	br.failures++
+	if br.trial || br.failures >= b.threshold {
+		br.openUntil = b.now().Add(b.cooldown)
+		br.trial = false
+	}
+}
+
+// abort releases a half-open trial whose request was cancelled before the
+// host answered.
+func (b *Breakers) abort(host string) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if br := b.hosts[host]; br != nil {
+		br.trial = false
+	}
+}
@@ -0,0 +1,58 @@
+package httpx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakers(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreakers(2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// Successes reset the failure count.
+	b.record("a", false)
+	b.record("a", true)
+	b.record("a", false)
+	if !b.allow("a") {
+		t.Fatal("breaker opened before the threshold")
+	}
+
+	b.record("a", false)
+	if b.allow("a") {
+		t.Fatal("expected breaker to open after 2 consecutive failures")
+	}
+	if !b.allow("b") {
+		t.Error("breaker of another host opened")
+	}
+
+	// After the cooldown, one trial request is let through.
+	now = now.Add(time.Minute)
+	if !b.allow("a") {
+		t.Fatal("expected a trial request after the cooldown")
+	}
+	if b.allow("a") {
+		t.Error("expected a single trial request")
+	}
+
+	// A failed trial opens the breaker again.
+	b.record("a", false)
+	if b.allow("a") {
+		t.Fatal("expected breaker to reopen after a failed trial")
+	}
+
+	// A cancelled trial lets the next request try, and a successful one
+	// closes the breaker.
+	now = now.Add(time.Minute)
+	if !b.allow("a") {
+		t.Fatal("expected a trial request")
+	}
+	b.abort("a")
+	if !b.allow("a") {
+		t.Fatal("expected another trial after the first was cancelled")
+	}
+	b.record("a", true)
+	if !b.allow("a") || !b.allow("a") {
+		t.Error("expected breaker to close after a successful trial")
+	}
+}
@@ -0,0 +1,84 @@
+package httpx
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrCircuitOpen is wrapped by the UpstreamDownError returned while a
+	// host's circuit breaker is open.
+	ErrCircuitOpen = errors.New("circuit open")
+	// ErrTooLarge is wrapped by the ParseError returned for a response body
+	// over the client's size limit.
+	ErrTooLarge = errors.New("response body too large")
+)
+
+// RateLimitedError is returned when a host still answers 429 Too Many
+// Requests after the last attempt, or asks to wait longer than the client's
+// maximum backoff.
+type RateLimitedError struct {
+	Host       string
+	RetryAfter time.Duration // zero when the host did not say
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s: rate limited, retry after %s", e.Host, e.RetryAfter)
+	}
+	return e.Host + ": rate limited"
+}
+
+// Temporary reports true: the same request succeeds once the limit resets.
+func (e *RateLimitedError) Temporary() bool { return true }
+
+// UpstreamDownError is returned when a host cannot serve the request: the
+// connection failed, an attempt timed out, it answered with a server error,
+// or its circuit breaker is open.
+type UpstreamDownError struct {
+	Host       string
+	StatusCode int   // zero when no response was received
+	Err        error // nil when StatusCode is set
+}
+
+func (e *UpstreamDownError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: upstream down: %v", e.Host, e.Err)
+	}
+	return fmt.Sprintf("%s: upstream down: HTTP %d", e.Host, e.StatusCode)
+}
+
+func (e *UpstreamDownError) Unwrap() error { return e.Err }
+
+// Temporary reports true: outages pass.
+func (e *UpstreamDownError) Temporary() bool { return true }
+
+// StatusError is returned for a client error status other than 408 and 429,
+// e.g. 404 for an unknown symbol. The request is not retried.
+type StatusError struct {
+	Host       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned HTTP %d", e.Host, e.StatusCode)
+}
+
+// Temporary reports false: repeating the request returns the same status.
+func (e *StatusError) Temporary() bool { return false }
+
+// ParseError is returned when a response cannot be read as expected: it is
+// over the size limit or does not decode. The request is not retried since
+// the upstream would likely send the same body again.
+type ParseError struct {
+	Host string
+	Err  error
+}
+
+func (e *ParseError) Error() string { return fmt.Sprintf("%s: parse response: %v", e.Host, e.Err) }
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Temporary reports false.
+func (e *ParseError) Temporary() bool { return false }
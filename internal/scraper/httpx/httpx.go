@@ -0,0 +1,271 @@
+// Package httpx sends the scrapers' upstream requests. Each attempt has its
+// own timeout; outages and rate limits are retried with jittered exponential
+// backoff that honours Retry-After; bodies are read up to a size limit; and a
+// circuit breaker per host stops hammering an upstream that is down. Failures
+// come back as typed errors (RateLimitedError, UpstreamDownError,
+// StatusError, ParseError) whose Temporary method tells the job queue whether
+// to retry.
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultAttempts    = 3
+	defaultBaseDelay   = 500 * time.Millisecond
+	defaultMaxDelay    = 10 * time.Second
+	defaultMaxBodySize = 32 << 20
+)
+
+// Client sends requests with retries on top of an *http.Client.
+type Client struct {
+	client      *http.Client
+	timeout     time.Duration
+	attempts    int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	maxBodySize int64
+	breakers    *Breakers
+}
+
+func New(opts ...Option) *Client {
+	c := &Client{
+		client:      http.DefaultClient,
+		timeout:     defaultTimeout,
+		attempts:    defaultAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+		maxBodySize: defaultMaxBodySize,
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	if c.breakers == nil {
+		c.breakers = NewBreakers(defaultBreakerThreshold, defaultBreakerCooldown)
+	}
+	return c
+}
+
+type Option func(*Client)
+
+// WithHTTPClient sets the client that sends each attempt. Its transport,
+// cookie jar and redirect policy are used as they are.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.client = hc
+		}
+	}
+}
+
+// WithTimeout limits each attempt, including reading the body.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithAttempts sets how many times a request is sent before giving up.
+func WithAttempts(n int) Option {
+	return func(c *Client) { c.attempts = max(1, n) }
+}
+
+// WithBackoff sets the delay before the first retry, which doubles with each
+// further retry up to maxDelay. A Retry-After longer than maxDelay is not
+// waited for; the request fails with a RateLimitedError instead.
+func WithBackoff(base, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.baseDelay = base
+		c.maxDelay = maxDelay
+	}
+}
+
+// WithMaxBodySize sets the largest response body accepted, in bytes.
+func WithMaxBodySize(n int64) Option {
+	return func(c *Client) { c.maxBodySize = n }
+}
+
+// WithBreakers shares circuit breakers between clients. By default every
+// client has its own.
+func WithBreakers(b *Breakers) Option {
+	return func(c *Client) { c.breakers = b }
+}
+
+// Response is a successful (2xx) response with its body read.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	host       string
+}
+
+// JSON decodes the body into v.
+func (r *Response) JSON(v any) error {
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		return &ParseError{Host: r.host, Err: err}
+	}
+	return nil
+}
+
+// XML decodes the body into v.
+func (r *Response) XML(v any) error {
+	if err := xml.Unmarshal(r.Body, v); err != nil {
+		return &ParseError{Host: r.host, Err: err}
+	}
+	return nil
+}
+
+// Do sends req until it succeeds, fails for good or runs out of attempts.
+// A request with a body is only retried if the body can be replayed
+// (req.GetBody is set, as it is for bodies from bytes and strings readers).
+// If req's context is cancelled, its error is returned as is.
+func (c *Client) Do(req *http.Request) (*Response, error) {
+	ctx := req.Context()
+	attempts := c.attempts
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		attempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		res, retryAfter, err := c.attempt(req, attempt)
+		if err == nil {
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt >= attempts || !retryable(err) {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > c.maxDelay {
+			// Also for a server error: the host has said when to come back.
+			return nil, &RateLimitedError{Host: req.URL.Host, RetryAfter: retryAfter}
+		}
+		delay = max(delay, retryAfter)
+
+		slog.Warn("httpx: retrying request", "host", req.URL.Host, "attempt", attempt,
+			"delay", delay, "error", err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends req once and returns the response, or an error with the
+// delay the host asked for in Retry-After.
+func (c *Client) attempt(req *http.Request, n int) (*Response, time.Duration, error) {
+	host := req.URL.Host
+	if !c.breakers.allow(host) {
+		return nil, 0, &UpstreamDownError{Host: host, Err: ErrCircuitOpen}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	defer cancel()
+	r := req.Clone(ctx)
+	if n > 1 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			c.breakers.abort(host)
+			return nil, 0, err
+		}
+		r.Body = body
+	}
+
+	fail := func(err error) (*Response, time.Duration, error) {
+		if req.Context().Err() != nil {
+			c.breakers.abort(host)
+			return nil, 0, req.Context().Err()
+		}
+		c.breakers.record(host, false)
+		return nil, 0, &UpstreamDownError{Host: host, Err: err}
+	}
+
+	res, err := c.client.Do(r) //nolint:gosec // URLs come from scraper config
+	if err != nil {
+		return fail(err)
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, c.maxBodySize+1))
+	if err != nil {
+		return fail(err)
+	}
+
+	switch {
+	case res.StatusCode >= 500, res.StatusCode == http.StatusRequestTimeout:
+		c.breakers.record(host, false)
+		return nil, retryAfter(res.Header), &UpstreamDownError{Host: host, StatusCode: res.StatusCode}
+	case res.StatusCode == http.StatusTooManyRequests:
+		c.breakers.record(host, true)
+		wait := retryAfter(res.Header)
+		return nil, wait, &RateLimitedError{Host: host, RetryAfter: wait}
+	}
+	c.breakers.record(host, true)
+
+	if res.StatusCode >= 300 {
+		return nil, 0, &StatusError{Host: host, StatusCode: res.StatusCode}
+	}
+	if int64(len(body)) > c.maxBodySize {
+		return nil, 0, &ParseError{Host: host, Err: ErrTooLarge}
+	}
+	return &Response{StatusCode: res.StatusCode, Header: res.Header, Body: body, host: host}, 0, nil
+}
+
+// backoff returns the jittered delay before retry n: a random duration
+// between half and all of baseDelay·2ⁿ⁻¹, capped at maxDelay.
+func (c *Client) backoff(n int) time.Duration {
+	d := c.baseDelay << (n - 1)
+	if d <= 0 || d > c.maxDelay {
+		d = c.maxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1) //nolint:gosec // jitter does not need a secure source
+}
+
+// retryable reports whether an attempt that failed with err may succeed if
+// sent again shortly. An open circuit stays open longer than any backoff.
+func retryable(err error) bool {
+	var rl *RateLimitedError
+	var down *UpstreamDownError
+	switch {
+	case errors.As(err, &rl):
+		return true
+	case errors.As(err, &down):
+		return !errors.Is(err, ErrCircuitOpen)
+	default:
+		return false
+	}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns zero when the header is absent or invalid.
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t))
+	}
+	return 0
+}
@@ -0,0 +1,220 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client with millisecond backoffs.
+func newTestClient(opts ...Option) *Client {
+	return New(append([]Option{WithBackoff(time.Millisecond, 5*time.Millisecond)}, opts...)...)
+}
+
+func get(t *testing.T, c *Client, url string) (*Response, error) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c.Do(req)
+}
+
+func TestDo_RetriesServerErrors(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if hits.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	res, err := get(t, newTestClient(), ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var body struct{ OK bool }
+	if err := res.JSON(&body); err != nil || !body.OK {
+		t.Errorf("unexpected body %q (%v)", res.Body, err)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", hits.Load())
+	}
+}
+
+func TestDo_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		header    string
+		body      string
+		wantHits  int64
+		wantTemp  bool
+		checkType func(error) bool
+	}{
+		{"server error", 502, "", "", 3, true, func(err error) bool {
+			var e *UpstreamDownError
+			return errors.As(err, &e) && e.StatusCode == 502
+		}},
+		{"rate limited", 429, "", "", 3, true, func(err error) bool {
+			var e *RateLimitedError
+			return errors.As(err, &e)
+		}},
+		{"retry after beyond max delay", 429, "120", "", 1, true, func(err error) bool {
+			var e *RateLimitedError
+			return errors.As(err, &e) && e.RetryAfter == 2*time.Minute
+		}},
+		{"server error with retry after beyond max delay", 503, "120", "", 1, true, func(err error) bool {
+			var e *RateLimitedError
+			return errors.As(err, &e) && e.RetryAfter == 2*time.Minute
+		}},
+		{"not found", 404, "", "", 1, false, func(err error) bool {
+			var e *StatusError
+			return errors.As(err, &e) && e.StatusCode == 404
+		}},
+		{"too large", 200, "", strings.Repeat("x", 65), 1, false, func(err error) bool {
+			var e *ParseError
+			return errors.As(err, &e) && errors.Is(err, ErrTooLarge)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				hits.Add(1)
+				if tt.header != "" {
+					w.Header().Set("Retry-After", tt.header)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer ts.Close()
+
+			_, err := get(t, newTestClient(WithMaxBodySize(64)), ts.URL)
+			if err == nil || !tt.checkType(err) {
+				t.Fatalf("unexpected error %v (%T)", err, err)
+			}
+			var temp interface{ Temporary() bool }
+			if !errors.As(err, &temp) || temp.Temporary() != tt.wantTemp {
+				t.Errorf("Temporary() = %v, want %v", !tt.wantTemp, tt.wantTemp)
+			}
+			if hits.Load() != tt.wantHits {
+				t.Errorf("expected %d attempts, got %d", tt.wantHits, hits.Load())
+			}
+		})
+	}
+}
+
+func TestDo_ParseError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`<html>`))
+	}))
+	defer ts.Close()
+
+	res, err := get(t, newTestClient(), ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var v map[string]any
+	var pe *ParseError
+	if err := res.JSON(&v); !errors.As(err, &pe) {
+		t.Errorf("expected ParseError, got %v", err)
+	}
+}
+
+func TestDo_AttemptTimeout(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	res, err := get(t, newTestClient(WithTimeout(50*time.Millisecond)), ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(res.Body) != "ok" || hits.Load() != 2 {
+		t.Errorf("expected second attempt to succeed, got %q after %d attempts", res.Body, hits.Load())
+	}
+}
+
+func TestDo_ReplaysBody(t *testing.T) {
+	var bodies []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer ts.Close()
+
+	req, _ := http.NewRequestWithContext(context.Background(), "POST", ts.URL, strings.NewReader("a=1"))
+	if _, err := newTestClient().Do(req); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bodies) != 2 || bodies[0] != "a=1" || bodies[1] != "a=1" {
+		t.Errorf("unexpected request bodies %q", bodies)
+	}
+}
+
+func TestDo_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL, nil)
+	if _, err := newTestClient().Do(req); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestDo_CircuitBreaker(t *testing.T) {
+	var hits atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	b := NewBreakers(3, time.Minute)
+	c := newTestClient(WithBreakers(b), WithAttempts(2))
+
+	// Two requests of two attempts each: the third failure opens the circuit
+	// and the fourth attempt is rejected without reaching the server.
+	for range 2 {
+		_, _ = get(t, c, ts.URL)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected 3 requests before the circuit opened, got %d", hits.Load())
+	}
+	_, err := get(t, c, ts.URL)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	var down *UpstreamDownError
+	if !errors.As(err, &down) || !down.Temporary() {
+		t.Errorf("expected temporary UpstreamDownError, got %v", err)
+	}
+	if hits.Load() != 3 {
+		t.Errorf("open circuit let a request through")
+	}
+}
@@ -0,0 +1,17 @@
+package httpx
+
+import (
+	"slices"
+	"strings"
+)
+
+// SecretParams are lower-case query parameters that carry credentials or
+// session state. Their values are kept out of recorded fixtures and archived
+// responses.
+var SecretParams = []string{"crumb", "token", "access_token", "apikey", "api_key", "key", "password", "session", "sig", "signature"}
+
+// IsSecretParam reports whether the query parameter name is in SecretParams,
+// ignoring case.
+func IsSecretParam(name string) bool {
+	return slices.Contains(SecretParams, strings.ToLower(name))
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 const (
@@ -17,21 +19,35 @@ const (
 	dateFormat      = "20060102150405"
 )
 
+// Column layout of a row in the IndexHistoricalAll "data" array. Only the
+// timestamp and close columns are always present; OHLV columns are appended
+// for instruments that have them.
+const (
+	colTimestamp = iota
+	colClose
+	colOpen
+	colHigh
+	colLow
+	colVolume
+)
+
 type Scraper struct {
 	workers  int
-	client   *http.Client
+	client   *httpx.Client
+	httpOpts []httpx.Option
+	archive  archive.Recorder
 	endpoint string
 }
 
 func New(opts ...Option) *Scraper {
 	s := &Scraper{
 		workers:  5,
-		client:   http.DefaultClient,
 		endpoint: defaultEndpoint,
 	}
 	for _, o := range opts {
 		o(s)
 	}
+	s.client = httpx.New(s.httpOpts...)
 	return s
 }
 
@@ -42,7 +58,18 @@ func WithWorkers(n int) Option {
 }
 
 func WithClient(c *http.Client) Option {
-	return func(s *Scraper) { s.client = c }
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, httpx.WithHTTPClient(c)) }
+}
+
+// WithHTTPOptions configures timeouts, retries and circuit breaking of
+// upstream requests.
+func WithHTTPOptions(opts ...httpx.Option) Option {
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, opts...) }
+}
+
+// WithArchive keeps every raw response with rec.
+func WithArchive(rec archive.Recorder) Option {
+	return func(s *Scraper) { s.archive = rec }
 }
 
 func WithEndpoint(ep string) Option {
@@ -80,54 +107,99 @@ func (s *Scraper) Scrape(ctx context.Context, symbol string, from, to time.Time)
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0")
 
-	res, err := s.client.Do(req) //nolint:gosec // URL built from internal config
+	res, err := s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("isyatirim %s: %w", symbol, err)
 	}
-	defer func() { _ = res.Body.Close() }()
-
-	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("isyatirim returned HTTP %d for %s", res.StatusCode, symbol)
+	archive.Record(ctx, s.archive, &archive.Entry{
+		Source:     s.Source(),
+		Symbol:     symbol,
+		Dataset:    archive.DatasetPrices,
+		Method:     req.Method,
+		URL:        reqURL,
+		StatusCode: res.StatusCode,
+		Body:       res.Body,
+	})
+
+	var response historyResponse
+	if err := res.JSON(&response); err != nil {
+		return nil, fmt.Errorf("isyatirim %s: %w", symbol, err)
 	}
+	prices := response.convert()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
-	}
+	slog.Info("retrieved isyatirim data", "symbol", symbol,
+		"from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"),
+		"count", len(prices))
 
-	var response struct {
-		Data [][]json.Number `json:"data"`
-	}
+	return prices, nil
+}
+
+// Parse implements scraper.Reparser for archived responses.
+func (s *Scraper) Parse(_ string, body []byte) ([]scraper.ScrapedPrice, error) {
+	var response historyResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse isyatirim response: %w", err)
+		return nil, fmt.Errorf("parse isyatirim response: %w", err)
 	}
+	return response.convert(), nil
+}
+
+// historyResponse is the IndexHistoricalAll response.
+type historyResponse struct {
+	Data [][]json.Number `json:"data"`
+}
 
+func (response *historyResponse) convert() []scraper.ScrapedPrice {
 	prices := make([]scraper.ScrapedPrice, 0, len(response.Data))
 	for _, entry := range response.Data {
-		if len(entry) < 2 {
+		if len(entry) <= colClose {
 			continue
 		}
 
-		tsMs, err := entry[0].Int64()
+		tsMs, err := entry[colTimestamp].Int64()
 		if err != nil {
 			continue
 		}
 
-		closePrice, err := entry[1].Float64()
+		closePrice, err := decimal.Parse(entry[colClose].String())
 		if err != nil {
 			continue
 		}
 
-		date := time.Unix(tsMs/1000, (tsMs%1000)*1e6).UTC().Truncate(24 * time.Hour)
-		prices = append(prices, scraper.ScrapedPrice{
-			Date:       date,
-			ClosePrice: closePrice,
-		})
+		exact := &scraper.Exact{ClosePrice: closePrice}
+		sp := scraper.ScrapedPrice{
+			Date:       time.Unix(tsMs/1000, (tsMs%1000)*1e6).UTC().Truncate(24 * time.Hour),
+			ClosePrice: closePrice.Float64(),
+			Exact:      exact,
+		}
+		for _, c := range []struct {
+			field scraper.Field
+			idx   int
+			exact *decimal.Decimal
+		}{
+			{scraper.FieldOpen, colOpen, &exact.Open},
+			{scraper.FieldHigh, colHigh, &exact.High},
+			{scraper.FieldLow, colLow, &exact.Low},
+			{scraper.FieldVolume, colVolume, &exact.Volume},
+		} {
+			if v, ok := column(entry, c.idx); ok {
+				*c.exact = v
+				sp.Set(c.field, v.Float64())
+			}
+		}
+		prices = append(prices, sp)
 	}
+	return prices
+}
 
-	slog.Info("retrieved isyatirim data", "symbol", symbol,
-		"from", from.Format("2006-01-02"), "to", to.Format("2006-01-02"),
-		"count", len(prices))
-
-	return prices, nil
+// column returns the value at idx. It returns false if the row is too short
+// or the value is not numeric.
+func column(entry []json.Number, idx int) (decimal.Decimal, bool) {
+	if idx >= len(entry) {
+		return decimal.Decimal{}, false
+	}
+	v, err := decimal.Parse(entry[idx].String())
+	if err != nil {
+		return decimal.Decimal{}, false
+	}
+	return v, true
 }
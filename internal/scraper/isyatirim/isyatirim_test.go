@@ -3,10 +3,13 @@ package isyatirim
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 func TestScrape(t *testing.T) {
@@ -55,6 +58,36 @@ func TestScrape(t *testing.T) {
 	}
 }
 
+func TestScrape_OHLCVColumns(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := map[string]any{
+			"data": [][]any{
+				{1735772400000.0, 36.65, 36.40, 36.90, 36.30, 125000.0},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	s := New(WithEndpoint(ts.URL), WithClient(ts.Client()))
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	prices, err := s.Scrape(context.Background(), "ALTINS1", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prices) != 1 {
+		t.Fatalf("expected 1 price, got %d", len(prices))
+	}
+
+	p := prices[0]
+	if p.ClosePrice != 36.65 || p.Open != 36.40 || p.High != 36.90 || p.Low != 36.30 || p.Volume != 125000 {
+		t.Errorf("unexpected bar: %+v", p)
+	}
+}
+
 func TestScrape_EmptyData(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		_ = json.NewEncoder(w).Encode(map[string]any{"data": [][]any{}})
@@ -81,14 +114,16 @@ func TestScrape_ErrorResponse(t *testing.T) {
 	}))
 	defer ts.Close()
 
-	s := New(WithEndpoint(ts.URL), WithClient(ts.Client()))
+	s := New(WithEndpoint(ts.URL), WithClient(ts.Client()),
+		WithHTTPOptions(httpx.WithBackoff(time.Millisecond, time.Millisecond)))
 
 	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
 
 	_, err := s.Scrape(context.Background(), "ALTINS1", from, to)
-	if err == nil {
-		t.Fatal("expected error for HTTP 500")
+	var down *httpx.UpstreamDownError
+	if !errors.As(err, &down) || down.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected upstream down error for HTTP 500, got %v", err)
 	}
 }
 
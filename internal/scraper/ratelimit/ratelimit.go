@@ -0,0 +1,105 @@
+// Package ratelimit throttles outgoing requests per host with token buckets,
+// so that every client talking to an upstream shares its request budget no
+// matter how many jobs and chunk workers are running.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limit is the request budget of a host.
+type Limit struct {
+	Rate  float64 // requests per second; zero or less is unlimited
+	Burst int     // requests that may be sent at once after an idle period
+}
+
+// PerMinute returns a limit of n requests per minute that allows one
+// second's worth of requests in a burst.
+func PerMinute(n int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: max(1, n/60)}
+}
+
+// Limiter holds a token bucket per host.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+// Wait blocks until a request to host may be sent under lim, or ctx is done.
+// The bucket of a host is created with the limit of its first request, so
+// clients sharing a host should be given the same limit.
+func (l *Limiter) Wait(ctx context.Context, host string, lim Limit) error {
+	if lim.Rate <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{limit: lim, tokens: float64(max(1, lim.Burst)), last: now}
+		l.buckets[host] = b
+	}
+	// Refill, then take a token. A bucket in debt tells the caller how long
+	// to wait for its token; later callers queue up behind it.
+	b.tokens = min(float64(max(1, b.limit.Burst)), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	b.tokens--
+	wait := time.Duration(0)
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.limit.Rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the token back so a cancelled request does not delay others.
+		l.mu.Lock()
+		b.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Transport returns a RoundTripper that waits for the limiter before sending
+// each request with base (http.DefaultTransport when nil).
+func (l *Limiter) Transport(base http.RoundTripper, lim Limit) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{limiter: l, base: base, limit: lim}
+}
+
+type transport struct {
+	limiter *Limiter
+	base    http.RoundTripper
+	limit   Limit
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context(), req.URL.Host, t.limit); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWait_SpacesRequestsAfterBurst(t *testing.T) {
+	l := New()
+	lim := Limit{Rate: 50, Burst: 2} // one token every 20ms
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 5 {
+		if err := l.Wait(ctx, "example.com", lim); err != nil {
+			t.Fatalf("wait: %v", err)
+		}
+	}
+	// Two requests go out at once, the other three wait 20ms each.
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Errorf("5 requests took %s, expected at least 60ms", elapsed)
+	}
+
+	// Other hosts have their own bucket.
+	start = time.Now()
+	if err := l.Wait(ctx, "other.example.com", lim); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Errorf("first request to another host waited %s", elapsed)
+	}
+}
+
+func TestWait_Cancelled(t *testing.T) {
+	l := New()
+	lim := Limit{Rate: 1, Burst: 1}
+	_ = l.Wait(context.Background(), "example.com", lim)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "example.com", lim); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	var hits atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	l := New()
+	// Two clients of the same host share its bucket.
+	a := &http.Client{Transport: l.Transport(nil, Limit{Rate: 20, Burst: 1})}
+	b := &http.Client{Transport: l.Transport(nil, Limit{Rate: 20, Burst: 1})}
+
+	start := time.Now()
+	for _, c := range []*http.Client{a, b, a} {
+		res, err := c.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		_ = res.Body.Close()
+	}
+	if hits.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", hits.Load())
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %s, expected at least 100ms", elapsed)
+	}
+}
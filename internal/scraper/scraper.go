@@ -5,11 +5,84 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 )
 
+// ScrapedPrice is a single daily bar. Open, High, Low, AdjClose and Volume
+// are optional: Reported lists those the source published, so that a
+// published zero, such as the volume of a day without trades, is kept apart
+// from a missing value, which is left zero.
 type ScrapedPrice struct {
 	Date       time.Time
+	Open       float64
+	High       float64
+	Low        float64
 	ClosePrice float64
+	AdjClose   float64 // split- and dividend-adjusted close
+	Volume     float64
+	Reported   Field
+	Exact      *Exact // the values as published; nil for sources that publish binary floats
+}
+
+// Field is a set of the optional values of a bar.
+type Field uint8
+
+const (
+	FieldOpen Field = 1 << iota
+	FieldHigh
+	FieldLow
+	FieldAdjClose
+	FieldVolume
+)
+
+// Has reports whether f includes g.
+func (f Field) Has(g Field) bool { return f&g == g }
+
+// Set sets an optional value and marks it reported.
+func (p *ScrapedPrice) Set(f Field, v float64) {
+	switch f {
+	case FieldOpen:
+		p.Open = v
+	case FieldHigh:
+		p.High = v
+	case FieldLow:
+		p.Low = v
+	case FieldAdjClose:
+		p.AdjClose = v
+	case FieldVolume:
+		p.Volume = v
+	default:
+		return
+	}
+	p.Reported |= f
+}
+
+// Exact holds a bar's values as the source published them in decimal. The
+// float fields of a ScrapedPrice are rounded from these.
+type Exact struct {
+	Open       decimal.Decimal
+	High       decimal.Decimal
+	Low        decimal.Decimal
+	ClosePrice decimal.Decimal
+	AdjClose   decimal.Decimal
+	Volume     decimal.Decimal
+}
+
+const (
+	ActionDividend = "dividend"
+	ActionSplit    = "split"
+)
+
+// CorporateAction is a dividend or split reported by the upstream. Amount is
+// the cash dividend per share; splits use Numerator:Denominator (4:1 means
+// every share became four).
+type CorporateAction struct {
+	Date        time.Time
+	Type        string
+	Amount      float64
+	Numerator   float64
+	Denominator float64
 }
 
 type Scraper interface {
@@ -18,6 +91,48 @@ type Scraper interface {
 	Scrape(ctx context.Context, symbol string, from, to time.Time) ([]ScrapedPrice, error)
 }
 
+// FundStats is per-day fund metadata published alongside the price.
+type FundStats struct {
+	Date              time.Time
+	Name              string
+	SharesOutstanding float64
+	Investors         int64
+	TotalValue        float64 // portfolio size in native currency
+}
+
+// ActionScraper is implemented by scrapers whose upstream reports dividends
+// and splits in the same response as prices. Callers that want the actions
+// use ScrapeWithActions instead of Scrape.
+type ActionScraper interface {
+	ScrapeWithActions(ctx context.Context, symbol string, from, to time.Time) ([]ScrapedPrice, []CorporateAction, error)
+}
+
+// FundScraper is implemented by scrapers whose upstream reports fund
+// statistics in the same response as prices.
+type FundScraper interface {
+	ScrapeWithStats(ctx context.Context, symbol string, from, to time.Time) ([]ScrapedPrice, []FundStats, error)
+}
+
+// Allocation is a fund's portfolio breakdown on one day. Weights maps the
+// upstream asset class code to its percentage of the portfolio.
+type Allocation struct {
+	Date    time.Time
+	Weights map[string]float64
+}
+
+// AllocationScraper fetches portfolio allocation history, a separate dataset
+// from prices.
+type AllocationScraper interface {
+	ScrapeAllocation(ctx context.Context, symbol string, from, to time.Time) ([]Allocation, error)
+}
+
+// Reparser is implemented by scrapers that archive their raw price
+// responses. Parse rebuilds the prices of symbol from one archived response
+// body, without network access.
+type Reparser interface {
+	Parse(symbol string, body []byte) ([]ScrapedPrice, error)
+}
+
 type Registry struct {
 	mu       sync.RWMutex
 	scrapers map[string]Scraper
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
@@ -14,25 +13,38 @@ import (
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 const (
 	defaultBaseURL         = "https://www.tefas.gov.tr"
 	defaultHistoryEndpoint = "https://www.tefas.gov.tr/api/DB/BindHistoryInfo"
+	defaultAllocEndpoint   = "https://www.tefas.gov.tr/api/DB/BindHistoryAllocation"
 	defaultReferer         = "http://www.tefas.gov.tr/TarihselVeriler.aspx"
 	dateFormat             = "2006-01-02"
 	chunkDays              = 60
 )
 
+const (
+	// DefaultFundType is the type of funds whose symbol has no type prefix,
+	// securities investment funds.
+	DefaultFundType = "YAT"
+	// TypeSeparator separates the fund type prefix from the fund code in a
+	// symbol ("EMK:AEA").
+	TypeSeparator = ":"
+)
+
 type tefasPriceData struct {
-	Timestamp  string  `json:"TARIH"`
-	FundCode   string  `json:"FONKODU"`
-	FundName   string  `json:"FONUNVAN"`
-	Price      float64 `json:"FIYAT"`
-	NumShares  float64 `json:"TEDPAYSAYISI"`
-	NumPeople  float64 `json:"KISISAYISI"`
-	TotalWorth float64 `json:"PORTFOYBUYUKLUK"`
+	Timestamp  string          `json:"TARIH"`
+	FundCode   string          `json:"FONKODU"`
+	FundName   string          `json:"FONUNVAN"`
+	Price      decimal.Decimal `json:"FIYAT"`
+	NumShares  float64         `json:"TEDPAYSAYISI"`
+	NumPeople  float64         `json:"KISISAYISI"`
+	TotalWorth float64         `json:"PORTFOYBUYUKLUK"`
 }
 
 type fundData struct {
@@ -42,10 +54,19 @@ type fundData struct {
 	Data            []tefasPriceData `json:"data"`
 }
 
+// allocationData is the BindHistoryAllocation response. Rows hold TARIH,
+// FONKODU, FONUNVAN and one numeric column per asset class.
+type allocationData struct {
+	Data []map[string]any `json:"data"`
+}
+
 type Scraper struct {
 	workers         int
-	client          *http.Client
+	client          *httpx.Client
+	httpOpts        []httpx.Option
+	archive         archive.Recorder
 	historyEndpoint string
+	allocEndpoint   string
 	baseURL         string
 	referer         string
 }
@@ -53,14 +74,15 @@ type Scraper struct {
 func New(opts ...Option) *Scraper {
 	s := &Scraper{
 		workers:         5,
-		client:          http.DefaultClient,
 		historyEndpoint: defaultHistoryEndpoint,
+		allocEndpoint:   defaultAllocEndpoint,
 		baseURL:         defaultBaseURL,
 		referer:         defaultReferer,
 	}
 	for _, o := range opts {
 		o(s)
 	}
+	s.client = httpx.New(s.httpOpts...)
 	return s
 }
 
@@ -71,13 +93,28 @@ func WithWorkers(n int) Option {
 }
 
 func WithClient(c *http.Client) Option {
-	return func(s *Scraper) { s.client = c }
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, httpx.WithHTTPClient(c)) }
+}
+
+// WithHTTPOptions configures timeouts, retries and circuit breaking of
+// upstream requests.
+func WithHTTPOptions(opts ...httpx.Option) Option {
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, opts...) }
+}
+
+// WithArchive keeps every raw response with rec.
+func WithArchive(rec archive.Recorder) Option {
+	return func(s *Scraper) { s.archive = rec }
 }
 
 func WithHistoryEndpoint(url string) Option {
 	return func(s *Scraper) { s.historyEndpoint = url }
 }
 
+func WithAllocationEndpoint(url string) Option {
+	return func(s *Scraper) { s.allocEndpoint = url }
+}
+
 func WithBaseURL(url string) Option {
 	return func(s *Scraper) { s.baseURL = url }
 }
@@ -91,49 +128,109 @@ func (s *Scraper) Source() string { return "tefas" }
 func (s *Scraper) NativeCurrency(_ string) string { return "TRY" }
 
 func (s *Scraper) Scrape(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, error) {
+	prices, _, err := s.ScrapeWithStats(ctx, symbol, from, to)
+	return prices, err
+}
+
+// ScrapeWithStats returns prices together with the fund name, shares
+// outstanding, investor count and portfolio size TEFAS reports for each day.
+func (s *Scraper) ScrapeWithStats(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, []scraper.FundStats, error) {
 	if symbol == "" {
-		return nil, fmt.Errorf("symbol cannot be empty")
+		return nil, nil, fmt.Errorf("symbol cannot be empty")
 	}
 	if from.IsZero() {
-		return nil, fmt.Errorf("start date cannot be empty")
+		return nil, nil, fmt.Errorf("start date cannot be empty")
 	}
 	if to.IsZero() {
 		to = time.Now()
 	}
 	if from.After(to) {
-		return nil, fmt.Errorf("start date cannot be after end date")
+		return nil, nil, fmt.Errorf("start date cannot be after end date")
 	}
 
 	chunks := scraper.SplitDateRange(from, to, chunkDays)
 
 	type result struct {
 		prices []scraper.ScrapedPrice
+		stats  []scraper.FundStats
 	}
 	results := make([]result, len(chunks))
+	errs := make([]error, len(chunks))
 
-	g, ctx := errgroup.WithContext(ctx)
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(s.workers)
 
 	for i, c := range chunks {
 		g.Go(func() error {
-			fd, err := s.getFundData(ctx, symbol, c.From, c.To)
+			fd, err := s.getFundData(gctx, symbol, c.From, c.To)
 			if err != nil {
 				slog.Error("error retrieving tefas data", "fund", symbol,
 					"startDate", c.From, "endDate", c.To, "error", err)
+				errs[i] = err
 				return nil // continue other chunks
 			}
-			chunk := make([]scraper.ScrapedPrice, 0, len(fd.Data))
-			for _, d := range fd.Data {
-				t := parseTimestamp(d.Timestamp)
-				if t.IsZero() || d.Price < 0 {
-					continue
+			chunk, stats := fd.convert()
+			results[i] = result{prices: chunk, stats: stats}
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, nil, err
+	}
+	err := scraper.ChunkErrors(ctx, chunks, errs)
+	if err != nil && !scraper.IsPartial(err) {
+		return nil, nil, err
+	}
+
+	var all []scraper.ScrapedPrice
+	var stats []scraper.FundStats
+	for _, r := range results {
+		all = append(all, r.prices...)
+		stats = append(stats, r.stats...)
+	}
+	return all, stats, err
+}
+
+// ScrapeAllocation returns the daily portfolio breakdown of a fund. Asset
+// classes with a zero weight are omitted.
+func (s *Scraper) ScrapeAllocation(ctx context.Context, symbol string, from, to time.Time) ([]scraper.Allocation, error) {
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol cannot be empty")
+	}
+	if from.IsZero() {
+		return nil, fmt.Errorf("start date cannot be empty")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.After(to) {
+		return nil, fmt.Errorf("start date cannot be after end date")
+	}
+
+	chunks := scraper.SplitDateRange(from, to, chunkDays)
+	results := make([][]scraper.Allocation, len(chunks))
+	errs := make([]error, len(chunks))
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(s.workers)
+
+	for i, c := range chunks {
+		g.Go(func() error {
+			ad := &allocationData{}
+			if err := s.post(gctx, s.allocEndpoint, archive.DatasetAllocation, symbol, c.From, c.To, ad); err != nil {
+				slog.Error("error retrieving tefas allocation", "fund", symbol,
+					"startDate", c.From, "endDate", c.To, "error", err)
+				errs[i] = err
+				return nil // continue other chunks
+			}
+			chunk := make([]scraper.Allocation, 0, len(ad.Data))
+			for _, row := range ad.Data {
+				if a, ok := parseAllocation(row); ok {
+					chunk = append(chunk, a)
 				}
-				chunk = append(chunk, scraper.ScrapedPrice{
-					Date:       t,
-					ClosePrice: d.Price,
-				})
 			}
-			results[i] = result{prices: chunk}
+			results[i] = chunk
 			return nil
 		})
 	}
@@ -141,24 +238,99 @@ func (s *Scraper) Scrape(ctx context.Context, symbol string, from, to time.Time)
 	if err := g.Wait(); err != nil {
 		return nil, err
 	}
+	err := scraper.ChunkErrors(ctx, chunks, errs)
+	if err != nil && !scraper.IsPartial(err) {
+		return nil, err
+	}
 
-	var all []scraper.ScrapedPrice
+	var all []scraper.Allocation
 	for _, r := range results {
-		all = append(all, r.prices...)
+		all = append(all, r...)
+	}
+	return all, err
+}
+
+// parseAllocation converts one BindHistoryAllocation row. Every numeric
+// column other than the date is treated as an asset class weight.
+func parseAllocation(row map[string]any) (scraper.Allocation, bool) {
+	ts, _ := row["TARIH"].(string)
+	t := parseTimestamp(ts)
+	if t.IsZero() {
+		return scraper.Allocation{}, false
+	}
+
+	weights := make(map[string]float64)
+	for k, v := range row {
+		switch k {
+		case "TARIH", "FONKODU", "FONUNVAN":
+			continue
+		}
+		if w, ok := v.(float64); ok && w != 0 {
+			weights[k] = w
+		}
 	}
-	return all, nil
+	return scraper.Allocation{Date: t, Weights: weights}, true
 }
 
-func (s *Scraper) getFundData(ctx context.Context, fundCode string, startDate, endDate time.Time) (*fundData, error) {
+func (s *Scraper) getFundData(ctx context.Context, symbol string, startDate, endDate time.Time) (*fundData, error) {
+	response := &fundData{}
+	if err := s.post(ctx, s.historyEndpoint, archive.DatasetPrices, symbol, startDate, endDate, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Parse implements scraper.Reparser for archived history responses.
+func (s *Scraper) Parse(_ string, body []byte) ([]scraper.ScrapedPrice, error) {
+	var fd fundData
+	if err := json.Unmarshal(body, &fd); err != nil {
+		return nil, fmt.Errorf("parse tefas response: %w", err)
+	}
+	prices, _ := fd.convert()
+	return prices, nil
+}
+
+// convert returns the prices and fund statistics of a history response.
+func (fd *fundData) convert() ([]scraper.ScrapedPrice, []scraper.FundStats) {
+	prices := make([]scraper.ScrapedPrice, 0, len(fd.Data))
+	stats := make([]scraper.FundStats, 0, len(fd.Data))
+	for _, d := range fd.Data {
+		t := parseTimestamp(d.Timestamp)
+		if t.IsZero() || d.Price.Sign() < 0 {
+			continue
+		}
+		prices = append(prices, scraper.ScrapedPrice{
+			Date:       t,
+			ClosePrice: d.Price.Float64(),
+			Exact:      &scraper.Exact{ClosePrice: d.Price},
+		})
+		stats = append(stats, scraper.FundStats{
+			Date:              t,
+			Name:              d.FundName,
+			SharesOutstanding: d.NumShares,
+			Investors:         int64(d.NumPeople),
+			TotalValue:        d.TotalWorth,
+		})
+	}
+	return prices, stats
+}
+
+// post queries a TEFAS history endpoint for one fund and date range and
+// decodes the JSON response into out. The raw response is archived under
+// dataset.
+func (s *Scraper) post(ctx context.Context, endpoint, dataset, symbol string, startDate, endDate time.Time, out any) error {
+	fundType, fundCode := SplitSymbol(symbol)
+
 	params := url.Values{}
-	params.Add("fontip", "YAT")
+	params.Add("fontip", fundType)
 	params.Add("fonkod", fundCode)
 	params.Add("bastarih", startDate.Format(dateFormat))
 	params.Add("bittarih", endDate.Format(dateFormat))
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.historyEndpoint, strings.NewReader(params.Encode()))
+	form := params.Encode()
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	req.Header.Set("X-Requested-With", "XMLHttpRequest")
@@ -167,24 +339,35 @@ func (s *Scraper) getFundData(ctx context.Context, fundCode string, startDate, e
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Accept", "application/json")
 
-	res, err := s.client.Do(req) //nolint:gosec // URL built from internal config
+	res, err := s.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer func() { _ = res.Body.Close() }()
-
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, err
+	archive.Record(ctx, s.archive, &archive.Entry{
+		Source:      s.Source(),
+		Symbol:      symbol,
+		Dataset:     dataset,
+		Method:      req.Method,
+		URL:         endpoint,
+		RequestBody: []byte(form),
+		StatusCode:  res.StatusCode,
+		Body:        res.Body,
+	})
+	if err := res.JSON(out); err != nil {
+		return err
 	}
 
-	response := &fundData{}
-	if err := json.Unmarshal(body, response); err != nil {
-		return nil, err
-	}
+	slog.Info("retrieved tefas data", "endpoint", endpoint, "fund", fundCode, "type", fundType, "startDate", startDate.Format(dateFormat), "endDate", endDate.Format(dateFormat))
+	return nil
+}
 
-	slog.Info("retrieved tefas data", "fund", fundCode, "startDate", startDate.Format(dateFormat), "endDate", endDate.Format(dateFormat))
-	return response, nil
+// SplitSymbol splits a symbol such as "EMK:AEA" into its fund type and fund
+// code. Symbols without a prefix are of the default type.
+func SplitSymbol(symbol string) (fundType, code string) {
+	if t, c, ok := strings.Cut(symbol, TypeSeparator); ok {
+		return t, c
+	}
+	return DefaultFundType, symbol
 }
 
 func parseTimestamp(timestamp string) time.Time {
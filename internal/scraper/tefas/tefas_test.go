@@ -3,10 +3,17 @@ package tefas
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/cassette"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 func TestScrape(t *testing.T) {
@@ -21,8 +28,8 @@ func TestScrape(t *testing.T) {
 		resp := fundData{
 			RecordsTotal: 2,
 			Data: []tefasPriceData{
-				{Timestamp: "1704067200000", FundCode: "YAC", Price: 1.23},
-				{Timestamp: "1704153600000", FundCode: "YAC", Price: 1.24},
+				{Timestamp: "1704067200000", FundCode: "YAC", Price: decimal.FromFloat(1.23)},
+				{Timestamp: "1704153600000", FundCode: "YAC", Price: decimal.FromFloat(1.24)},
 			},
 		}
 		_ = json.NewEncoder(w).Encode(resp)
@@ -52,6 +59,200 @@ func TestScrape(t *testing.T) {
 	if prices[0].ClosePrice != 1.23 {
 		t.Errorf("expected price 1.23, got %f", prices[0].ClosePrice)
 	}
+	if e := prices[0].Exact; e == nil || e.ClosePrice.String() != "1.23" {
+		t.Errorf("expected exact price 1.23, got %+v", e)
+	}
+}
+
+// TestScrapeWithStats_Replay runs the scraper against its default endpoints
+// with recorded responses from testdata/cassettes.
+func TestScrapeWithStats_Replay(t *testing.T) {
+	s := New(WithClient(&http.Client{Transport: cassette.New(cassette.Replay, "testdata/cassettes", nil)}))
+
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	prices, stats, err := s.ScrapeWithStats(context.Background(), "YAC", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prices) != 3 || len(stats) != 3 {
+		t.Fatalf("expected 3 prices and stats, got %d and %d", len(prices), len(stats))
+	}
+	if p := prices[2]; !p.Date.Equal(from) || p.ClosePrice != 9.704113 {
+		t.Errorf("unexpected price %+v", p)
+	}
+	if st := stats[0]; st.Investors != 6812 || !strings.HasPrefix(st.Name, "YAPI KREDİ") {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestScrape_PartialChunkFailure(t *testing.T) {
+	// The range spans two 60-day chunks; the second one gets a broken response.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("bastarih") != "2024-01-01" {
+			_, _ = w.Write([]byte("<html>blocked</html>"))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(fundData{Data: []tefasPriceData{
+			{Timestamp: "1704067200000", FundCode: "YAC", Price: decimal.FromFloat(1.23)},
+		}})
+	}))
+	defer ts.Close()
+
+	s := New(WithWorkers(1), WithClient(ts.Client()), WithHistoryEndpoint(ts.URL))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	prices, err := s.Scrape(context.Background(), "YAC", from, to)
+	var partial *scraper.PartialError
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected partial error, got %v", err)
+	}
+	if len(partial.Failed) != 1 || !partial.Failed[0].From.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected failed chunks: %+v", partial.Failed)
+	}
+	if len(prices) != 1 {
+		t.Errorf("expected the successful chunk's price, got %d", len(prices))
+	}
+}
+
+func TestScrapeWithStats(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := fundData{
+			RecordsTotal: 1,
+			Data: []tefasPriceData{
+				{
+					Timestamp:  "1704067200000",
+					FundCode:   "YAC",
+					FundName:   "YAPI KREDI PORTFOY KOC HOLDING HISSE SENEDI FONU",
+					Price:      decimal.FromFloat(1.23),
+					NumShares:  150000000,
+					NumPeople:  4210,
+					TotalWorth: 184500000,
+				},
+			},
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	s := New(WithWorkers(1), WithClient(ts.Client()), WithHistoryEndpoint(ts.URL))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	prices, stats, err := s.ScrapeWithStats(context.Background(), "YAC", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prices) != 1 || len(stats) != 1 {
+		t.Fatalf("expected 1 price and 1 stat, got %d and %d", len(prices), len(stats))
+	}
+
+	st := stats[0]
+	if st.Name != "YAPI KREDI PORTFOY KOC HOLDING HISSE SENEDI FONU" {
+		t.Errorf("unexpected name %q", st.Name)
+	}
+	if st.SharesOutstanding != 150000000 || st.Investors != 4210 || st.TotalValue != 184500000 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if !st.Date.Equal(from) {
+		t.Errorf("expected date %v, got %v", from, st.Date)
+	}
+}
+
+func TestScrapeAllocation(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"draw":0,"recordsTotal":1,"data":[
+			{"TARIH":"1704067200000","FONKODU":"YAC","FONUNVAN":"YAPI KREDI","HS":92.5,"R":4.25,"TR":3.25,"DT":0,"KM":null}
+		]}`))
+	}))
+	defer ts.Close()
+
+	s := New(WithWorkers(1), WithClient(ts.Client()), WithAllocationEndpoint(ts.URL))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	allocs, err := s.ScrapeAllocation(context.Background(), "YAC", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(allocs) != 1 {
+		t.Fatalf("expected 1 allocation, got %d", len(allocs))
+	}
+
+	a := allocs[0]
+	if !a.Date.Equal(from) {
+		t.Errorf("expected date %v, got %v", from, a.Date)
+	}
+	want := map[string]float64{"HS": 92.5, "R": 4.25, "TR": 3.25}
+	if len(a.Weights) != len(want) {
+		t.Fatalf("expected weights %v, got %v", want, a.Weights)
+	}
+	for k, v := range want {
+		if a.Weights[k] != v {
+			t.Errorf("weight %s: expected %v, got %v", k, v, a.Weights[k])
+		}
+	}
+}
+
+func TestScrape_FundTypePrefix(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.PostForm.Get("fontip"); got != "EMK" {
+			t.Errorf("expected fontip=EMK, got %s", got)
+		}
+		if got := r.PostForm.Get("fonkod"); got != "AEA" {
+			t.Errorf("expected fonkod=AEA, got %s", got)
+		}
+		_ = json.NewEncoder(w).Encode(fundData{})
+	}))
+	defer ts.Close()
+
+	s := New(WithWorkers(1), WithClient(ts.Client()), WithHistoryEndpoint(ts.URL))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.Scrape(context.Background(), "EMK:AEA", from, from); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestScrape_ErrorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(fundData{})
+	}))
+	defer ts.Close()
+
+	s := New(WithWorkers(1), WithClient(ts.Client()), WithHistoryEndpoint(ts.URL))
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := s.Scrape(context.Background(), "YAC", from, from)
+	var statusErr *httpx.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected HTTP 403 error, got %v", err)
+	}
+}
+
+func TestSplitSymbol(t *testing.T) {
+	tests := []struct {
+		symbol, fundType, code string
+	}{
+		{"YAC", "YAT", "YAC"},
+		{"EMK:AEA", "EMK", "AEA"},
+		{"BYF:GLDTR", "BYF", "GLDTR"},
+	}
+	for _, tt := range tests {
+		fundType, code := SplitSymbol(tt.symbol)
+		if fundType != tt.fundType || code != tt.code {
+			t.Errorf("SplitSymbol(%q) = %q, %q; want %q, %q", tt.symbol, fundType, code, tt.fundType, tt.code)
+		}
+	}
 }
 
 func TestScrape_EmptySymbol(t *testing.T) {
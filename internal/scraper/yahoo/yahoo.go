@@ -6,11 +6,12 @@ package yahoo
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log/slog"
 	"net/http"
 	"net/http/cookiejar"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,7 +19,9 @@ import (
 
 	"golang.org/x/sync/errgroup"
 
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/httpx"
 )
 
 const (
@@ -33,7 +36,9 @@ const (
 // Scraper fetches historical price data from Yahoo Finance.
 type Scraper struct {
 	workers       int
-	client        *http.Client
+	client        *httpx.Client
+	httpOpts      []httpx.Option
+	archive       archive.Recorder
 	chartEndpoint string
 	cookieURL     string
 	crumbURL      string
@@ -47,7 +52,7 @@ func New(opts ...Option) *Scraper {
 	jar, _ := cookiejar.New(nil)
 	s := &Scraper{
 		workers:       5,
-		client:        &http.Client{Jar: jar},
+		httpOpts:      []httpx.Option{httpx.WithHTTPClient(&http.Client{Jar: jar})},
 		chartEndpoint: defaultChartEndpoint,
 		cookieURL:     defaultCookieURL,
 		crumbURL:      defaultCrumbURL,
@@ -55,6 +60,7 @@ func New(opts ...Option) *Scraper {
 	for _, o := range opts {
 		o(s)
 	}
+	s.client = httpx.New(s.httpOpts...)
 	return s
 }
 
@@ -68,7 +74,19 @@ func WithWorkers(n int) Option {
 
 // WithClient sets the HTTP client. The client should have a cookie jar.
 func WithClient(c *http.Client) Option {
-	return func(s *Scraper) { s.client = c }
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, httpx.WithHTTPClient(c)) }
+}
+
+// WithHTTPOptions configures timeouts, retries and circuit breaking of
+// upstream requests.
+func WithHTTPOptions(opts ...httpx.Option) Option {
+	return func(s *Scraper) { s.httpOpts = append(s.httpOpts, opts...) }
+}
+
+// WithArchive keeps every raw chart response with rec. Session cookie and
+// crumb responses are not archived.
+func WithArchive(rec archive.Recorder) Option {
+	return func(s *Scraper) { s.archive = rec }
 }
 
 // WithChartEndpoint overrides the default chart API endpoint.
@@ -101,73 +119,132 @@ func (s *Scraper) NativeCurrency(symbol string) string {
 // chartResponse represents the Yahoo Finance v8 chart API response.
 type chartResponse struct {
 	Chart struct {
-		Result []struct {
-			Timestamp  []int64 `json:"timestamp"`
-			Indicators struct {
-				Quote []struct {
-					Close []any `json:"close"`
-				} `json:"quote"`
-			} `json:"indicators"`
-		} `json:"result"`
-		Error *struct {
-			Code        string `json:"code"`
-			Description string `json:"description"`
-		} `json:"error"`
+		Result []chartResult `json:"result"`
+		Error  *chartError   `json:"error"`
 	} `json:"chart"`
 }
 
-// Scrape fetches daily close prices for the given symbol and date range.
+type chartResult struct {
+	Timestamp  []int64         `json:"timestamp"`
+	Indicators chartIndicators `json:"indicators"`
+	Events     *chartEvents    `json:"events,omitempty"`
+}
+
+type chartIndicators struct {
+	Quote    []chartQuote    `json:"quote"`
+	AdjClose []chartAdjClose `json:"adjclose,omitempty"`
+}
+
+// chartQuote holds the per-timestamp OHLCV series. Values are null for
+// missing data points, hence []any.
+type chartQuote struct {
+	Open   []any `json:"open"`
+	High   []any `json:"high"`
+	Low    []any `json:"low"`
+	Close  []any `json:"close"`
+	Volume []any `json:"volume"`
+}
+
+type chartAdjClose struct {
+	AdjClose []any `json:"adjclose"`
+}
+
+// chartEvents is keyed by the event's unix timestamp as a string.
+type chartEvents struct {
+	Dividends map[string]chartDividend `json:"dividends,omitempty"`
+	Splits    map[string]chartSplit    `json:"splits,omitempty"`
+}
+
+type chartDividend struct {
+	Amount float64 `json:"amount"`
+	Date   int64   `json:"date"`
+}
+
+type chartSplit struct {
+	Date        int64   `json:"date"`
+	Numerator   float64 `json:"numerator"`
+	Denominator float64 `json:"denominator"`
+}
+
+type chartError struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+func (e *chartError) Error() string { return e.Code + ": " + e.Description }
+
+// Temporary reports false: chart errors such as "Not Found" describe the
+// request, so repeating it returns the same error.
+func (e *chartError) Temporary() bool { return false }
+
+// Scrape fetches daily OHLCV bars for the given symbol and date range.
 func (s *Scraper) Scrape(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, error) {
+	prices, _, err := s.ScrapeWithActions(ctx, symbol, from, to)
+	return prices, err
+}
+
+// ScrapeWithActions fetches daily bars together with the dividends and splits
+// Yahoo reports in the chart response's events block.
+func (s *Scraper) ScrapeWithActions(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, []scraper.CorporateAction, error) {
 	if symbol == "" {
-		return nil, fmt.Errorf("symbol cannot be empty")
+		return nil, nil, fmt.Errorf("symbol cannot be empty")
 	}
 	if from.IsZero() {
-		return nil, fmt.Errorf("start date cannot be empty")
+		return nil, nil, fmt.Errorf("start date cannot be empty")
 	}
 	if to.IsZero() {
 		to = time.Now()
 	}
 	if from.After(to) {
-		return nil, fmt.Errorf("start date cannot be after end date")
+		return nil, nil, fmt.Errorf("start date cannot be after end date")
 	}
 
 	// Ensure we have a valid crumb before starting parallel fetches.
 	if err := s.ensureCrumb(ctx); err != nil {
-		return nil, fmt.Errorf("yahoo auth: %w", err)
+		return nil, nil, fmt.Errorf("yahoo auth: %w", err)
 	}
 
 	chunks := scraper.SplitDateRange(from, to, chunkDays)
 
 	type result struct {
-		prices []scraper.ScrapedPrice
+		prices  []scraper.ScrapedPrice
+		actions []scraper.CorporateAction
 	}
 	results := make([]result, len(chunks))
+	errs := make([]error, len(chunks))
 
-	g, ctx := errgroup.WithContext(ctx)
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(s.workers)
 
 	for i, c := range chunks {
 		g.Go(func() error {
-			prices, err := s.fetchChart(ctx, symbol, c.From, c.To)
+			prices, actions, err := s.fetchChart(gctx, symbol, c.From, c.To)
 			if err != nil {
 				slog.Error("error retrieving yahoo data", "symbol", symbol,
 					"startDate", c.From.Format(dateFormat), "endDate", c.To.Format(dateFormat), "error", err)
+				errs[i] = err
 				return nil
 			}
-			results[i] = result{prices: prices}
+			results[i] = result{prices: prices, actions: actions}
 			return nil
 		})
 	}
 
 	if err := g.Wait(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	err := scraper.ChunkErrors(ctx, chunks, errs)
+	if err != nil && !scraper.IsPartial(err) {
+		return nil, nil, err
 	}
 
 	var all []scraper.ScrapedPrice
+	var actions []scraper.CorporateAction
 	for _, r := range results {
 		all = append(all, r.prices...)
+		actions = append(actions, r.actions...)
 	}
-	return all, nil
+	return all, actions, err
 }
 
 // ensureCrumb fetches a session cookie and crumb token if not already cached.
@@ -179,18 +256,18 @@ func (s *Scraper) ensureCrumb(ctx context.Context) error {
 		return nil
 	}
 
-	// Step 1: GET fc.yahoo.com to obtain a session cookie.
+	// Step 1: GET fc.yahoo.com to obtain a session cookie. It sets the
+	// cookie even when it answers with an error status.
 	cookieReq, err := http.NewRequestWithContext(ctx, "GET", s.cookieURL, nil)
 	if err != nil {
 		return fmt.Errorf("build cookie request: %w", err)
 	}
 	cookieReq.Header.Set("User-Agent", userAgent)
 
-	cookieRes, err := s.client.Do(cookieReq) //nolint:gosec // URL from internal config
-	if err != nil {
+	var statusErr *httpx.StatusError
+	if _, err := s.client.Do(cookieReq); err != nil && !errors.As(err, &statusErr) {
 		return fmt.Errorf("fetch cookie: %w", err)
 	}
-	_ = cookieRes.Body.Close()
 
 	// Step 2: GET crumb endpoint (cookie is sent automatically via jar).
 	crumbReq, err := http.NewRequestWithContext(ctx, "GET", s.crumbURL, nil)
@@ -199,22 +276,12 @@ func (s *Scraper) ensureCrumb(ctx context.Context) error {
 	}
 	crumbReq.Header.Set("User-Agent", userAgent)
 
-	crumbRes, err := s.client.Do(crumbReq) //nolint:gosec // URL from internal config
+	crumbRes, err := s.client.Do(crumbReq)
 	if err != nil {
 		return fmt.Errorf("fetch crumb: %w", err)
 	}
-	defer func() { _ = crumbRes.Body.Close() }()
-
-	if crumbRes.StatusCode != http.StatusOK {
-		return fmt.Errorf("crumb endpoint returned HTTP %d", crumbRes.StatusCode)
-	}
 
-	body, err := io.ReadAll(crumbRes.Body)
-	if err != nil {
-		return fmt.Errorf("read crumb: %w", err)
-	}
-
-	crumb := strings.TrimSpace(string(body))
+	crumb := strings.TrimSpace(string(crumbRes.Body))
 	if crumb == "" {
 		return fmt.Errorf("empty crumb received")
 	}
@@ -225,7 +292,7 @@ func (s *Scraper) ensureCrumb(ctx context.Context) error {
 }
 
 // fetchChart fetches chart data for a single date range chunk.
-func (s *Scraper) fetchChart(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, error) {
+func (s *Scraper) fetchChart(ctx context.Context, symbol string, from, to time.Time) ([]scraper.ScrapedPrice, []scraper.CorporateAction, error) {
 	s.mu.Lock()
 	crumb := s.crumb
 	s.mu.Unlock()
@@ -240,69 +307,143 @@ func (s *Scraper) fetchChart(ctx context.Context, symbol string, from, to time.T
 
 	req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	req.Header.Set("User-Agent", userAgent)
 
-	res, err := s.client.Do(req) //nolint:gosec // URL built from internal config
+	res, err := s.client.Do(req)
 	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = res.Body.Close() }()
-
-	if res.StatusCode != http.StatusOK {
 		// Invalidate crumb on auth errors so next Scrape retries auth.
-		if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		var statusErr *httpx.StatusError
+		if errors.As(err, &statusErr) &&
+			(statusErr.StatusCode == http.StatusUnauthorized || statusErr.StatusCode == http.StatusForbidden) {
 			s.mu.Lock()
 			s.crumb = ""
 			s.mu.Unlock()
 		}
-		return nil, fmt.Errorf("yahoo returned HTTP %d for %s", res.StatusCode, symbol)
+		return nil, nil, fmt.Errorf("yahoo %s: %w", symbol, err)
 	}
+	archive.Record(ctx, s.archive, &archive.Entry{
+		Source:     s.Source(),
+		Symbol:     symbol,
+		Dataset:    archive.DatasetPrices,
+		Method:     req.Method,
+		URL:        reqURL,
+		StatusCode: res.StatusCode,
+		Body:       res.Body,
+	})
 
-	body, err := io.ReadAll(res.Body)
+	var resp chartResponse
+	if err := res.JSON(&resp); err != nil {
+		return nil, nil, fmt.Errorf("yahoo %s: %w", symbol, err)
+	}
+	prices, actions, err := resp.convert()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
+	slog.Info("retrieved yahoo data", "symbol", symbol,
+		"from", from.Format(dateFormat), "to", to.Format(dateFormat),
+		"count", len(prices), "actions", len(actions))
+
+	return prices, actions, nil
+}
+
+// Parse implements scraper.Reparser for archived chart responses.
+func (s *Scraper) Parse(_ string, body []byte) ([]scraper.ScrapedPrice, error) {
 	var resp chartResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, fmt.Errorf("parse yahoo response: %w", err)
 	}
+	prices, _, err := resp.convert()
+	return prices, err
+}
 
+// convert returns the daily bars and corporate actions of a chart response.
+func (resp *chartResponse) convert() ([]scraper.ScrapedPrice, []scraper.CorporateAction, error) {
 	if resp.Chart.Error != nil {
-		return nil, fmt.Errorf("yahoo chart error: %s: %s", resp.Chart.Error.Code, resp.Chart.Error.Description)
+		return nil, nil, fmt.Errorf("yahoo chart error: %w", resp.Chart.Error)
 	}
 
 	if len(resp.Chart.Result) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	result := resp.Chart.Result[0]
 	if len(result.Indicators.Quote) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 
-	closes := result.Indicators.Quote[0].Close
-	n := min(len(result.Timestamp), len(closes))
+	quote := result.Indicators.Quote[0]
+	var adjCloses []any
+	if len(result.Indicators.AdjClose) > 0 {
+		adjCloses = result.Indicators.AdjClose[0].AdjClose
+	}
+	n := min(len(result.Timestamp), len(quote.Close))
 	prices := make([]scraper.ScrapedPrice, 0, n)
 	for i := range n {
-		closeVal, ok := toFloat64(closes[i])
+		closeVal, ok := toFloat64(quote.Close[i])
 		if !ok {
 			continue
 		}
-		date := time.Unix(result.Timestamp[i], 0).UTC().Truncate(24 * time.Hour)
-		prices = append(prices, scraper.ScrapedPrice{
-			Date:       date,
+		sp := scraper.ScrapedPrice{
+			Date:       time.Unix(result.Timestamp[i], 0).UTC().Truncate(24 * time.Hour),
 			ClosePrice: closeVal,
-		})
+		}
+		for _, c := range []struct {
+			field scraper.Field
+			vals  []any
+		}{
+			{scraper.FieldOpen, quote.Open},
+			{scraper.FieldHigh, quote.High},
+			{scraper.FieldLow, quote.Low},
+			{scraper.FieldAdjClose, adjCloses},
+			{scraper.FieldVolume, quote.Volume},
+		} {
+			if v, ok := valueAt(c.vals, i); ok {
+				sp.Set(c.field, v)
+			}
+		}
+		prices = append(prices, sp)
 	}
 
-	slog.Info("retrieved yahoo data", "symbol", symbol,
-		"from", from.Format(dateFormat), "to", to.Format(dateFormat),
-		"count", len(prices))
+	return prices, parseEvents(result.Events), nil
+}
+
+// parseEvents flattens the dividends and splits maps into corporate actions,
+// ordered by date.
+func parseEvents(ev *chartEvents) []scraper.CorporateAction {
+	if ev == nil {
+		return nil
+	}
+
+	actions := make([]scraper.CorporateAction, 0, len(ev.Dividends)+len(ev.Splits))
+	for _, d := range ev.Dividends {
+		actions = append(actions, scraper.CorporateAction{
+			Date:   time.Unix(d.Date, 0).UTC().Truncate(24 * time.Hour),
+			Type:   scraper.ActionDividend,
+			Amount: d.Amount,
+		})
+	}
+	for _, sp := range ev.Splits {
+		if sp.Numerator <= 0 || sp.Denominator <= 0 {
+			continue
+		}
+		actions = append(actions, scraper.CorporateAction{
+			Date:        time.Unix(sp.Date, 0).UTC().Truncate(24 * time.Hour),
+			Type:        scraper.ActionSplit,
+			Numerator:   sp.Numerator,
+			Denominator: sp.Denominator,
+		})
+	}
 
-	return prices, nil
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].Date.Equal(actions[j].Date) {
+			return actions[i].Type < actions[j].Type
+		}
+		return actions[i].Date.Before(actions[j].Date)
+	})
+	return actions
 }
 
 // toFloat64 converts a JSON number (which may be float64 or json.Number) to float64.
@@ -318,3 +459,12 @@ func toFloat64(v any) (float64, bool) {
 		return 0, false
 	}
 }
+
+// valueAt returns vals[i] as float64. It returns false when the series is
+// shorter than expected or the point is null.
+func valueAt(vals []any, i int) (float64, bool) {
+	if i >= len(vals) {
+		return 0, false
+	}
+	return toFloat64(vals[i])
+}
@@ -5,8 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/archive"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper/cassette"
 )
 
 // newTestServer returns a mock Yahoo Finance server that serves cookie, crumb,
@@ -54,25 +59,18 @@ func newTestServer(t *testing.T, chartData chartResponse) (*httptest.Server, *Sc
 
 func TestScrape(t *testing.T) {
 	resp := chartResponse{}
-	resp.Chart.Result = []struct {
-		Timestamp  []int64 `json:"timestamp"`
-		Indicators struct {
-			Quote []struct {
-				Close []any `json:"close"`
-			} `json:"quote"`
-		} `json:"indicators"`
-	}{
+	resp.Chart.Result = []chartResult{
 		{
 			Timestamp: []int64{1704153600, 1704240000},
-			Indicators: struct {
-				Quote []struct {
-					Close []any `json:"close"`
-				} `json:"quote"`
-			}{
-				Quote: []struct {
-					Close []any `json:"close"`
-				}{
-					{Close: []any{185.01, 184.25}},
+			Indicators: chartIndicators{
+				Quote: []chartQuote{
+					{
+						Open:   []any{187.15, 184.22},
+						High:   []any{188.44, 185.88},
+						Low:    []any{183.89, 183.43},
+						Close:  []any{185.01, 184.25},
+						Volume: []any{82488700.0, 58414500.0},
+					},
 				},
 			},
 		},
@@ -99,28 +97,122 @@ func TestScrape(t *testing.T) {
 	if prices[1].ClosePrice != 184.25 {
 		t.Errorf("expected close 184.25, got %f", prices[1].ClosePrice)
 	}
+	if prices[0].Open != 187.15 || prices[0].High != 188.44 || prices[0].Low != 183.89 {
+		t.Errorf("unexpected OHL: %f/%f/%f", prices[0].Open, prices[0].High, prices[0].Low)
+	}
+	if prices[0].Volume != 82488700 {
+		t.Errorf("expected volume 82488700, got %f", prices[0].Volume)
+	}
+}
+
+type entries []archive.Entry
+
+func (e *entries) Save(_ context.Context, entry *archive.Entry) error {
+	*e = append(*e, *entry)
+	return nil
+}
+
+func TestScrape_ArchiveRedactsCrumb(t *testing.T) {
+	resp := chartResponse{}
+	resp.Chart.Result = []chartResult{{
+		Timestamp:  []int64{1704153600},
+		Indicators: chartIndicators{Quote: []chartQuote{{Close: []any{185.01}}}},
+	}}
+	ts, s := newTestServer(t, resp)
+	defer ts.Close()
+	var archived entries
+	WithArchive(&archived)(s)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := s.Scrape(context.Background(), "AAPL", from, from.AddDate(0, 0, 5)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(archived) != 1 {
+		t.Fatalf("expected 1 archived response, got %d", len(archived))
+	}
+	if u := archived[0].URL; strings.Contains(u, "crumb") || !strings.Contains(u, "interval=1d") {
+		t.Errorf("unexpected archived URL %q", u)
+	}
+}
+
+// TestScrape_Replay runs the scraper against its default endpoints with
+// recorded responses from testdata/cassettes.
+func TestScrape_Replay(t *testing.T) {
+	s := New(WithClient(&http.Client{Transport: cassette.New(cassette.Replay, "testdata/cassettes", nil)}))
+
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)
+	prices, err := s.Scrape(context.Background(), "AAPL", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prices) != 3 {
+		t.Fatalf("expected 3 prices, got %d", len(prices))
+	}
+	if p := prices[0]; !p.Date.Equal(from) || p.ClosePrice != 185.63999938964844 || p.Volume != 82488700 {
+		t.Errorf("unexpected first bar %+v", p)
+	}
+}
+
+func TestScrapeWithActions(t *testing.T) {
+	resp := chartResponse{}
+	resp.Chart.Result = []chartResult{
+		{
+			Timestamp: []int64{1704153600, 1704240000},
+			Indicators: chartIndicators{
+				Quote:    []chartQuote{{Close: []any{100.0, 51.0}}},
+				AdjClose: []chartAdjClose{{AdjClose: []any{49.5, 51.0}}},
+			},
+			Events: &chartEvents{
+				Dividends: map[string]chartDividend{
+					"1704153600": {Amount: 0.24, Date: 1704153600},
+				},
+				Splits: map[string]chartSplit{
+					"1704240000": {Date: 1704240000, Numerator: 2, Denominator: 1},
+				},
+			},
+		},
+	}
+
+	ts, s := newTestServer(t, resp)
+	defer ts.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	prices, actions, err := s.ScrapeWithActions(context.Background(), "THYAO.IS", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(prices) != 2 {
+		t.Fatalf("expected 2 prices, got %d", len(prices))
+	}
+	if prices[0].AdjClose != 49.5 {
+		t.Errorf("expected adj close 49.5, got %f", prices[0].AdjClose)
+	}
+
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+	if actions[0].Type != scraper.ActionDividend || actions[0].Amount != 0.24 {
+		t.Errorf("unexpected dividend: %+v", actions[0])
+	}
+	if actions[1].Type != scraper.ActionSplit || actions[1].Numerator != 2 || actions[1].Denominator != 1 {
+		t.Errorf("unexpected split: %+v", actions[1])
+	}
+	if !actions[1].Date.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected split date: %v", actions[1].Date)
+	}
 }
 
 func TestScrape_NullCloseValues(t *testing.T) {
 	resp := chartResponse{}
-	resp.Chart.Result = []struct {
-		Timestamp  []int64 `json:"timestamp"`
-		Indicators struct {
-			Quote []struct {
-				Close []any `json:"close"`
-			} `json:"quote"`
-		} `json:"indicators"`
-	}{
+	resp.Chart.Result = []chartResult{
 		{
 			Timestamp: []int64{1704153600, 1704240000, 1704326400},
-			Indicators: struct {
-				Quote []struct {
-					Close []any `json:"close"`
-				} `json:"quote"`
-			}{
-				Quote: []struct {
-					Close []any `json:"close"`
-				}{
+			Indicators: chartIndicators{
+				Quote: []chartQuote{
 					{Close: []any{185.01, nil, 184.25}},
 				},
 			},
@@ -143,6 +235,38 @@ func TestScrape_NullCloseValues(t *testing.T) {
 	}
 }
 
+func TestScrape_NullOptionalValues(t *testing.T) {
+	resp := chartResponse{}
+	resp.Chart.Result = []chartResult{
+		{
+			Timestamp: []int64{1704153600},
+			Indicators: chartIndicators{
+				Quote: []chartQuote{
+					{Open: []any{nil}, Close: []any{185.01}, Volume: []any{0.0}},
+				},
+			},
+		},
+	}
+
+	ts, s := newTestServer(t, resp)
+	defer ts.Close()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	prices, err := s.Scrape(context.Background(), "AAPL", from, to)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(prices) != 1 {
+		t.Fatalf("expected 1 price, got %d", len(prices))
+	}
+	// A zero volume is reported; a null open and absent high and low are not.
+	if got := prices[0].Reported; got != scraper.FieldVolume {
+		t.Errorf("expected only volume reported, got %b", got)
+	}
+}
+
 func TestScrape_EmptyResult(t *testing.T) {
 	resp := chartResponse{}
 	ts, s := newTestServer(t, resp)
@@ -162,10 +286,7 @@ func TestScrape_EmptyResult(t *testing.T) {
 
 func TestScrape_ChartError(t *testing.T) {
 	resp := chartResponse{}
-	resp.Chart.Error = &struct {
-		Code        string `json:"code"`
-		Description string `json:"description"`
-	}{Code: "Not Found", Description: "No data found"}
+	resp.Chart.Error = &chartError{Code: "Not Found", Description: "No data found"}
 
 	ts, s := newTestServer(t, resp)
 	defer ts.Close()
@@ -173,11 +294,13 @@ func TestScrape_ChartError(t *testing.T) {
 	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
 	to := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
 
-	// Chart errors are logged but not propagated (consistent with chunk error
-	// handling — partial failures don't fail the entire scrape).
+	// The only chunk failed, so the scrape fails as a whole.
 	prices, err := s.Scrape(context.Background(), "INVALID", from, to)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if err == nil || !strings.Contains(err.Error(), "Not Found") {
+		t.Fatalf("expected chart error, got %v", err)
+	}
+	if scraper.IsPartial(err) {
+		t.Error("expected a plain error when every chunk failed")
 	}
 	if len(prices) != 0 {
 		t.Errorf("expected 0 prices for chart error, got %d", len(prices))
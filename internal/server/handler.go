@@ -1,14 +1,23 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	"github.com/ahmethakanbesel/finance-api/internal/watchlist"
 )
 
 const dateFormat = "2006-01-02"
@@ -16,6 +25,11 @@ const dateFormat = "2006-01-02"
 type handler struct {
 	priceSvc *price.Service
 	jobSvc   *job.Service
+	fundSvc  *fund.Service
+	rateSvc  *rate.Service
+
+	watchlistSvc *watchlist.Service
+	qualitySvc   *quality.Service
 }
 
 func (h *handler) health(w http.ResponseWriter, _ *http.Request) {
@@ -63,6 +77,16 @@ func (h *handler) getPrices(w http.ResponseWriter, r *http.Request) {
 
 	format := r.URL.Query().Get("format")
 
+	var adjusted bool
+	switch r.URL.Query().Get("adjusted") {
+	case "", "false":
+	case "true":
+		adjusted = true
+	default:
+		writeError(w, http.StatusBadRequest, "adjusted must be true or false")
+		return
+	}
+
 	req := price.GetPricesRequest{
 		Source:    source,
 		Symbol:    symbol,
@@ -70,6 +94,11 @@ func (h *handler) getPrices(w http.ResponseWriter, r *http.Request) {
 		StartDate: startDate,
 		EndDate:   endDate,
 		Format:    format,
+		Adjusted:  adjusted,
+		FundType:  strings.ToUpper(r.URL.Query().Get("fundType")),
+		Precision: r.URL.Query().Get("precision"),
+
+		RateSource: strings.ToLower(r.URL.Query().Get("rateSource")),
 	}
 
 	if appErr := req.Validate(); appErr != nil {
@@ -95,6 +124,339 @@ func (h *handler) getPrices(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
+func (h *handler) getPriceRevisions(w http.ResponseWriter, r *http.Request) {
+	source := price.Source(r.URL.Query().Get("source"))
+	if source == "" {
+		writeError(w, http.StatusBadRequest, "source query parameter is required")
+		return
+	}
+	startDate, endDate, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	req := price.GetRevisionsRequest{
+		Source:    source,
+		Symbol:    strings.ToUpper(r.PathValue("symbol")),
+		FundType:  strings.ToUpper(r.URL.Query().Get("fundType")),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	resp, err := h.priceSvc.GetRevisions(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getQuality(w http.ResponseWriter, r *http.Request) {
+	var startDate, endDate time.Time
+	for _, p := range []struct {
+		name string
+		date *time.Time
+	}{{"startDate", &startDate}, {"endDate", &endDate}} {
+		v := r.URL.Query().Get(p.name)
+		if v == "" {
+			continue
+		}
+		d, err := time.Parse(dateFormat, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid "+p.name+" format, expected YYYY-MM-DD")
+			return
+		}
+		*p.date = d
+	}
+
+	req := quality.GetReportRequest{
+		Source:    strings.ToLower(r.PathValue("source")),
+		Symbol:    strings.ToUpper(r.PathValue("symbol")),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	resp, err := h.qualitySvc.GetReport(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// batchPricesBody is the JSON body of POST /api/v1/prices:batch.
+type batchPricesBody struct {
+	Items      []price.BatchItem `json:"items"`
+	StartDate  string            `json:"startDate"`
+	EndDate    string            `json:"endDate"`
+	Currency   string            `json:"currency"`
+	Format     string            `json:"format"`
+	Adjusted   bool              `json:"adjusted"`
+	Precision  string            `json:"precision"`
+	RateSource string            `json:"rateSource"`
+	Layout     string            `json:"layout"`
+	Fill       string            `json:"fill"`
+}
+
+const maxBatchBodyBytes = 1 << 20
+
+func (h *handler) getPricesBatch(w http.ResponseWriter, r *http.Request) {
+	var body batchPricesBody
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	startDate, endDate, ok := parseBodyDates(w, body.StartDate, body.EndDate)
+	if !ok {
+		return
+	}
+
+	currency := price.Currency(strings.ToUpper(body.Currency))
+	if currency == "" {
+		currency = price.CurrencyTRY
+	}
+
+	req := price.BatchPricesRequest{
+		Items:      body.Items,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Currency:   currency,
+		Format:     body.Format,
+		Adjusted:   body.Adjusted,
+		Precision:  body.Precision,
+		RateSource: strings.ToLower(body.RateSource),
+		Layout:     body.Layout,
+		Fill:       body.Fill,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	if req.Layout == price.LayoutWide {
+		h.getPricesWide(w, r, req)
+		return
+	}
+
+	resp, err := h.priceSvc.GetPricesBatch(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if req.Format == "csv" {
+		symbols := slices.Sorted(maps.Keys(resp.Symbols))
+		var points []price.PricePoint
+		for _, sym := range symbols {
+			points = append(points, resp.Symbols[sym].Prices...)
+		}
+		writeCSV(w, points)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getPricesWide(w http.ResponseWriter, r *http.Request, req price.BatchPricesRequest) {
+	resp, err := h.priceSvc.GetPricesWide(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if req.Format == "csv" {
+		writeWideCSV(w, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getRates(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	req := rate.GetRatesRequest{
+		Pair:      strings.ToUpper(r.PathValue("pair")),
+		StartDate: startDate,
+		EndDate:   endDate,
+		Format:    r.URL.Query().Get("format"),
+		Fill:      r.URL.Query().Get("fill"),
+
+		RateSource: strings.ToLower(r.URL.Query().Get("rateSource")),
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	resp, err := h.rateSvc.ListRates(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if req.Format == "csv" {
+		writeRatesCSV(w, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) listRates(w http.ResponseWriter, r *http.Request) {
+	pairs, err := h.rateSvc.ListPairs(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, pairs)
+}
+
+func (h *handler) listCalendars(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, calendar.Names())
+}
+
+func (h *handler) getTradingDays(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	resp, appErr := calendar.GetDays(calendar.GetDaysRequest{
+		Calendar:  r.PathValue("name"),
+		StartDate: startDate,
+		EndDate:   endDate,
+	})
+	if appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) listWatchlist(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.watchlistSvc.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []watchlist.Entry{}
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// watchlistBody is the JSON body of POST /api/v1/watchlist.
+type watchlistBody struct {
+	Source   string `json:"source"`
+	Symbol   string `json:"symbol"`
+	FundType string `json:"fundType"`
+}
+
+func (h *handler) addToWatchlist(w http.ResponseWriter, r *http.Request) {
+	var body watchlistBody
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	req := watchlist.AddRequest{
+		Source: body.Source,
+		Symbol: strings.ToUpper(body.Symbol),
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+	if !slices.Contains(h.priceSvc.ListSources(), req.Source) {
+		writeError(w, http.StatusBadRequest, "unknown source: "+req.Source)
+		return
+	}
+	switch {
+	case req.Source == string(price.SourceTefas):
+		symbol, err := fund.SeriesSymbol(strings.ToUpper(body.FundType), req.Symbol)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Symbol = symbol
+	case body.FundType != "":
+		writeError(w, http.StatusBadRequest, "fundType is only supported for source tefas")
+		return
+	}
+
+	e, created, err := h.watchlistSvc.Add(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, e)
+}
+
+func (h *handler) removeFromWatchlist(w http.ResponseWriter, r *http.Request) {
+	err := h.watchlistSvc.Remove(r.Context(), watchlist.RemoveRequest{
+		Source: r.PathValue("source"),
+		Symbol: strings.ToUpper(r.PathValue("symbol")),
+	})
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *handler) getJob(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	id, err := strconv.ParseInt(idStr, 10, 64)
@@ -122,6 +484,109 @@ func (h *handler) getJob(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, j)
 }
 
+// createJobBody is the JSON body of POST /api/v1/jobs.
+type createJobBody struct {
+	Kind      string `json:"kind"`
+	Source    string `json:"source"`
+	Symbol    string `json:"symbol"`
+	FundType  string `json:"fundType"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+func (h *handler) createJob(w http.ResponseWriter, r *http.Request) {
+	var body createJobBody
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	startDate, endDate, ok := parseBodyDates(w, body.StartDate, body.EndDate)
+	if !ok {
+		return
+	}
+
+	req := job.CreateJobRequest{
+		Kind:      job.Kind(body.Kind),
+		Source:    body.Source,
+		Symbol:    strings.ToUpper(body.Symbol),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+	if !slices.Contains(h.priceSvc.ListSources(), req.Source) {
+		writeError(w, http.StatusBadRequest, "unknown source: "+req.Source)
+		return
+	}
+	if req.Kind == job.KindAllocation && req.Source != string(price.SourceTefas) {
+		writeError(w, http.StatusBadRequest, "allocation jobs are only supported for source tefas")
+		return
+	}
+	switch {
+	case req.Source == string(price.SourceTefas):
+		symbol, err := fund.SeriesSymbol(strings.ToUpper(body.FundType), req.Symbol)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.Symbol = symbol
+	case body.FundType != "":
+		writeError(w, http.StatusBadRequest, "fundType is only supported for source tefas")
+		return
+	}
+
+	j, created, err := h.jobSvc.Create(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, j)
+}
+
+func (h *handler) cancelJob(w http.ResponseWriter, r *http.Request) {
+	h.changeJob(w, r, h.jobSvc.Cancel)
+}
+
+func (h *handler) retryJob(w http.ResponseWriter, r *http.Request) {
+	h.changeJob(w, r, h.jobSvc.Retry)
+}
+
+// changeJob runs a job action on the job named in the path and writes the
+// updated job.
+func (h *handler) changeJob(w http.ResponseWriter, r *http.Request, action func(context.Context, job.GetJobRequest) (*job.Job, error)) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid job id")
+		return
+	}
+
+	j, err := action(r.Context(), job.GetJobRequest{ID: id})
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, j)
+}
+
 func (h *handler) listJobs(w http.ResponseWriter, r *http.Request) {
 	req := job.ListJobsRequest{
 		Source: r.URL.Query().Get("source"),
@@ -136,3 +601,110 @@ func (h *handler) listJobs(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, jobs)
 }
+
+func (h *handler) getFundStats(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	req := fund.GetStatsRequest{
+		Code:      strings.ToUpper(r.PathValue("code")),
+		FundType:  strings.ToUpper(r.URL.Query().Get("fundType")),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	resp, err := h.fundSvc.GetStats(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *handler) getFundAllocation(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, ok := parseDateRange(w, r)
+	if !ok {
+		return
+	}
+
+	req := fund.GetAllocationRequest{
+		Code:      strings.ToUpper(r.PathValue("code")),
+		FundType:  strings.ToUpper(r.URL.Query().Get("fundType")),
+		StartDate: startDate,
+		EndDate:   endDate,
+	}
+	if appErr := req.Validate(); appErr != nil {
+		writeError(w, appErr.HTTPStatus(), appErr.Message())
+		return
+	}
+
+	resp, err := h.fundSvc.GetAllocation(r.Context(), req)
+	if err != nil {
+		if ae, ok := err.(*apperror.AppError); ok {
+			writeError(w, ae.HTTPStatus(), ae.Message())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseBodyDates parses the startDate/endDate fields of a JSON body, writing
+// a 400 and returning false when they are invalid.
+func parseBodyDates(w http.ResponseWriter, startStr, endStr string) (start, end time.Time, ok bool) {
+	if startStr == "" {
+		writeError(w, http.StatusBadRequest, "startDate is required")
+		return start, end, false
+	}
+	start, err := time.Parse(dateFormat, startStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid startDate format, expected YYYY-MM-DD")
+		return start, end, false
+	}
+
+	if endStr != "" {
+		end, err = time.Parse(dateFormat, endStr)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid endDate format, expected YYYY-MM-DD")
+			return start, end, false
+		}
+	}
+	return start, end, true
+}
+
+// parseDateRange reads the startDate/endDate query parameters, writing a 400
+// and returning false when they are invalid.
+func parseDateRange(w http.ResponseWriter, r *http.Request) (start, end time.Time, ok bool) {
+	startDateStr := r.URL.Query().Get("startDate")
+	if startDateStr == "" {
+		writeError(w, http.StatusBadRequest, "startDate is required")
+		return start, end, false
+	}
+	start, err := time.Parse(dateFormat, startDateStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid startDate format, expected YYYY-MM-DD")
+		return start, end, false
+	}
+
+	if v := r.URL.Query().Get("endDate"); v != "" {
+		end, err = time.Parse(dateFormat, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid endDate format, expected YYYY-MM-DD")
+			return start, end, false
+		}
+	}
+	return start, end, true
+}
@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/decimal"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 )
 
 type APIResponse[T any] struct {
@@ -37,17 +41,101 @@ func writeCSV(w http.ResponseWriter, prices []price.PricePoint) {
 	w.Header().Set("Content-Disposition", "attachment; filename=prices.csv")
 	w.WriteHeader(http.StatusOK)
 
-	_, _ = fmt.Fprintln(w, "Symbol,Date,Currency,Source,Close,NativePrice,NativeCurrency,Rate")
+	_, _ = fmt.Fprintln(w, "Symbol,Date,Currency,Source,Open,High,Low,Close,AdjClose,Volume,NativePrice,NativeCurrency,Rate,RatePath")
 	for _, p := range prices {
-		_, _ = fmt.Fprintf(w, "%s,%s,%s,%s,%.6f,%.6f,%s,%.6f\n", //nolint:gosec // CSV output from internal domain types, not user input
+		// Exact decimals when requested; otherwise the shortest form that
+		// round-trips the float, so low-priced funds keep every digit.
+		e := p.Exact
+		if e == nil {
+			e = &price.ExactPrice{
+				Open:        decimal.FromFloat(p.Open),
+				High:        decimal.FromFloat(p.High),
+				Low:         decimal.FromFloat(p.Low),
+				ClosePrice:  decimal.FromFloat(p.ClosePrice),
+				AdjClose:    decimal.FromFloat(p.AdjClose),
+				NativePrice: decimal.FromFloat(p.NativePrice),
+				Rate:        decimal.FromFloat(p.Rate),
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s,%s\n", //nolint:gosec // CSV output from internal domain types, not user input
 			p.Symbol,
 			p.Date.Format(time.DateOnly),
 			p.Currency,
 			p.Source,
-			p.ClosePrice,
-			p.NativePrice,
+			reportedCSV(p, scraper.FieldOpen, e.Open),
+			reportedCSV(p, scraper.FieldHigh, e.High),
+			reportedCSV(p, scraper.FieldLow, e.Low),
+			e.ClosePrice,
+			reportedCSV(p, scraper.FieldAdjClose, e.AdjClose),
+			reportedCSV(p, scraper.FieldVolume, decimal.FromFloat(p.Volume)),
+			e.NativePrice,
 			p.NativeCurrency,
-			p.Rate,
+			e.Rate,
+			p.RatePath,
+		)
+	}
+}
+
+// writeWideCSV writes one row per date and one column per symbol. Days a
+// symbol has no price are left empty.
+func writeWideCSV(w http.ResponseWriter, resp *price.WidePricesResponse) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=prices.csv")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = fmt.Fprintln(w, "Date,"+strings.Join(resp.Symbols, ","))
+	cells := make([]string, len(resp.Symbols)+1)
+	for _, row := range resp.Rows {
+		cells[0] = row.Date.Format(time.DateOnly)
+		for i, v := range row.Values {
+			switch {
+			case row.Exact != nil && row.Exact[i] != nil:
+				cells[i+1] = row.Exact[i].String()
+			case v != nil:
+				cells[i+1] = decimal.FromFloat(*v).String()
+			default:
+				cells[i+1] = ""
+			}
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(cells, ",")) //nolint:gosec // CSV output from internal domain types, not user input
+	}
+}
+
+func writeRatesCSV(w http.ResponseWriter, resp *rate.GetRatesResponse) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", "attachment; filename=rates.csv")
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = fmt.Fprintln(w, "Pair,Date,Rate,Bid,Ask,Source,Path,Filled")
+	for _, p := range resp.Rates {
+		_, _ = fmt.Fprintf(w, "%s,%s,%s,%s,%s,%s,%s,%t\n", //nolint:gosec // CSV output from internal domain types, not user input
+			resp.Pair,
+			p.Date.Format(time.DateOnly),
+			decimal.FromFloat(p.Rate),
+			optionalCSV(decimal.FromFloat(p.Bid)),
+			optionalCSV(decimal.FromFloat(p.Ask)),
+			resp.Source,
+			resp.Path,
+			p.Filled,
 		)
 	}
 }
+
+// reportedCSV leaves the cell empty when the source did not report the
+// value, and writes it, zero included, when it did.
+func reportedCSV(p price.PricePoint, f scraper.Field, d decimal.Decimal) string {
+	if !p.Reported.Has(f) {
+		return ""
+	}
+	return d.String()
+}
+
+// optionalCSV leaves the cell empty for a zero, which rates use for a value
+// the source did not report.
+func optionalCSV(d decimal.Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.String()
+}
@@ -3,20 +3,28 @@ package server
 import (
 	"net/http"
 
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	"github.com/ahmethakanbesel/finance-api/internal/watchlist"
 )
 
 // NewHandler creates the full HTTP handler with routes and middleware.
 // Exported for use in tests (e.g., httptest.NewServer).
-func NewHandler(priceSvc *price.Service, jobSvc *job.Service) http.Handler {
-	return newMux(priceSvc, jobSvc)
+func NewHandler(priceSvc *price.Service, jobSvc *job.Service, fundSvc *fund.Service, rateSvc *rate.Service, watchlistSvc *watchlist.Service, qualitySvc *quality.Service) http.Handler {
+	return newMux(priceSvc, jobSvc, fundSvc, rateSvc, watchlistSvc, qualitySvc)
 }
 
-func newMux(priceSvc *price.Service, jobSvc *job.Service) http.Handler {
+func newMux(priceSvc *price.Service, jobSvc *job.Service, fundSvc *fund.Service, rateSvc *rate.Service, watchlistSvc *watchlist.Service, qualitySvc *quality.Service) http.Handler {
 	h := &handler{
-		priceSvc: priceSvc,
-		jobSvc:   jobSvc,
+		priceSvc:     priceSvc,
+		jobSvc:       jobSvc,
+		fundSvc:      fundSvc,
+		rateSvc:      rateSvc,
+		watchlistSvc: watchlistSvc,
+		qualitySvc:   qualitySvc,
 	}
 
 	mux := http.NewServeMux()
@@ -24,8 +32,23 @@ func newMux(priceSvc *price.Service, jobSvc *job.Service) http.Handler {
 	mux.HandleFunc("GET /health", h.health)
 	mux.HandleFunc("GET /api/v1/sources", h.listSources)
 	mux.HandleFunc("GET /api/v1/prices/{symbol}", h.getPrices)
+	mux.HandleFunc("GET /api/v1/prices/{symbol}/revisions", h.getPriceRevisions)
+	mux.HandleFunc("POST /api/v1/prices:batch", h.getPricesBatch)
+	mux.HandleFunc("GET /api/v1/rates", h.listRates)
+	mux.HandleFunc("GET /api/v1/rates/{pair}", h.getRates)
+	mux.HandleFunc("GET /api/v1/quality/{source}/{symbol}", h.getQuality)
+	mux.HandleFunc("GET /api/v1/calendars", h.listCalendars)
+	mux.HandleFunc("GET /api/v1/calendars/{name}/days", h.getTradingDays)
+	mux.HandleFunc("GET /api/v1/watchlist", h.listWatchlist)
+	mux.HandleFunc("POST /api/v1/watchlist", h.addToWatchlist)
+	mux.HandleFunc("DELETE /api/v1/watchlist/{source}/{symbol}", h.removeFromWatchlist)
 	mux.HandleFunc("GET /api/v1/jobs", h.listJobs)
+	mux.HandleFunc("POST /api/v1/jobs", h.createJob)
 	mux.HandleFunc("GET /api/v1/jobs/{id}", h.getJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/cancel", h.cancelJob)
+	mux.HandleFunc("POST /api/v1/jobs/{id}/retry", h.retryJob)
+	mux.HandleFunc("GET /api/v1/funds/{code}/stats", h.getFundStats)
+	mux.HandleFunc("GET /api/v1/funds/{code}/allocation", h.getFundAllocation)
 
 	// Apply middleware stack: recovery -> requestID -> logging
 	var handler http.Handler = mux
@@ -8,8 +8,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
+	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	"github.com/ahmethakanbesel/finance-api/internal/watchlist"
 )
 
 type Server struct {
@@ -19,11 +23,11 @@ type Server struct {
 // New creates a server. The baseCtx is used as the base context for all
 // incoming requests (via BaseContext). Cancelling it causes in-flight scraper
 // workers to stop promptly during graceful shutdown.
-func New(baseCtx context.Context, port string, priceSvc *price.Service, jobSvc *job.Service) *Server {
+func New(baseCtx context.Context, port string, priceSvc *price.Service, jobSvc *job.Service, fundSvc *fund.Service, rateSvc *rate.Service, watchlistSvc *watchlist.Service, qualitySvc *quality.Service) *Server {
 	return &Server{
 		srv: &http.Server{
 			Addr:    fmt.Sprintf(":%s", port),
-			Handler: newMux(priceSvc, jobSvc),
+			Handler: newMux(priceSvc, jobSvc, fundSvc, rateSvc, watchlistSvc, qualitySvc),
 			BaseContext: func(_ net.Listener) context.Context {
 				return baseCtx
 			},
@@ -0,0 +1,16 @@
+package watchlist
+
+import (
+	"context"
+	"time"
+)
+
+type Repository interface {
+	// Add stores the entry and fills in its ID and creation time. An entry
+	// for the same source and symbol is loaded instead; created reports
+	// which happened.
+	Add(ctx context.Context, e *Entry) (created bool, err error)
+	List(ctx context.Context) ([]Entry, error)
+	Remove(ctx context.Context, source, symbol string) (bool, error)
+	MarkRefreshed(ctx context.Context, id int64, at time.Time) error
+}
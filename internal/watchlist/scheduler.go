@@ -0,0 +1,125 @@
+package watchlist
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/job"
+	"github.com/ahmethakanbesel/finance-api/internal/schedule"
+)
+
+const defaultLookback = 14
+
+// Refresher queues jobs for the missing part of a range, normally
+// price.Service.RefreshCoverage.
+type Refresher interface {
+	RefreshCoverage(ctx context.Context, source, symbol string, from, to time.Time) ([]*job.Job, error)
+}
+
+// Scheduler refreshes the watchlist of each source on the source's schedule,
+// which should fire after the source publishes the day's data. Every run
+// queues jobs for whatever is missing from the recent days, so a missed run
+// is caught up by the next one.
+type Scheduler struct {
+	repo      Repository
+	refresher Refresher
+	schedules map[string]*schedule.Schedule
+	lookback  int // days refreshed before today
+	now       func() time.Time
+}
+
+// NewScheduler creates a scheduler. Sources without a schedule are not
+// refreshed.
+func NewScheduler(repo Repository, refresher Refresher, schedules map[string]*schedule.Schedule, opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		repo:      repo,
+		refresher: refresher,
+		schedules: schedules,
+		lookback:  defaultLookback,
+		now:       time.Now,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	return s
+}
+
+type SchedulerOption func(*Scheduler)
+
+// WithLookback sets how many days before today each run covers.
+func WithLookback(days int) SchedulerOption {
+	return func(s *Scheduler) { s.lookback = days }
+}
+
+// Run refreshes each scheduled source at its times and blocks until ctx is
+// cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for source, sched := range s.schedules {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, source, sched)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, source string, sched *schedule.Schedule) {
+	for {
+		next := sched.Next(s.now().UTC())
+		if next.IsZero() {
+			slog.Warn("scheduler: schedule never runs", "source", source, "schedule", sched)
+			return
+		}
+		slog.Info("scheduler: next refresh", "source", source, "at", next)
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if _, err := s.Refresh(ctx, source); err != nil {
+			slog.Error("scheduler: refresh failed", "source", source, "error", err)
+		}
+	}
+}
+
+// Refresh queues jobs for the recent data missing for the source's watched
+// symbols and returns them. A symbol that fails is logged and skipped.
+func (s *Scheduler) Refresh(ctx context.Context, source string) ([]*job.Job, error) {
+	entries, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	to := now.Truncate(24 * time.Hour)
+	from := to.AddDate(0, 0, -s.lookback)
+
+	var queued []*job.Job
+	symbols := 0
+	for _, e := range entries {
+		if e.Source != source {
+			continue
+		}
+		symbols++
+		jobs, err := s.refresher.RefreshCoverage(ctx, e.Source, e.Symbol, from, to)
+		if err != nil {
+			slog.Error("scheduler: refresh symbol", "source", source, "symbol", e.Symbol, "error", err)
+			continue
+		}
+		queued = append(queued, jobs...)
+		if err := s.repo.MarkRefreshed(ctx, e.ID, now); err != nil {
+			slog.Error("scheduler: mark refreshed", "source", source, "symbol", e.Symbol, "error", err)
+		}
+	}
+
+	slog.Info("scheduler: refreshed watchlist", "source", source, "symbols", symbols, "jobs", len(queued))
+	return queued, nil
+}
@@ -0,0 +1,89 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ahmethakanbesel/finance-api/internal/job"
+)
+
+type mockRepo struct {
+	entries   []Entry
+	refreshed map[int64]time.Time
+}
+
+func (m *mockRepo) Add(_ context.Context, e *Entry) (bool, error) {
+	e.ID = int64(len(m.entries) + 1)
+	m.entries = append(m.entries, *e)
+	return true, nil
+}
+
+func (m *mockRepo) List(_ context.Context) ([]Entry, error) { return m.entries, nil }
+
+func (m *mockRepo) Remove(_ context.Context, _, _ string) (bool, error) { return false, nil }
+
+func (m *mockRepo) MarkRefreshed(_ context.Context, id int64, at time.Time) error {
+	if m.refreshed == nil {
+		m.refreshed = make(map[int64]time.Time)
+	}
+	m.refreshed[id] = at
+	return nil
+}
+
+type call struct {
+	source, symbol string
+	from, to       time.Time
+}
+
+type mockRefresher struct {
+	calls []call
+	fail  string // symbol to fail
+}
+
+func (m *mockRefresher) RefreshCoverage(_ context.Context, source, symbol string, from, to time.Time) ([]*job.Job, error) {
+	m.calls = append(m.calls, call{source, symbol, from, to})
+	if symbol == m.fail {
+		return nil, errors.New("boom")
+	}
+	return []*job.Job{{Source: source, Symbol: symbol, StartDate: to, EndDate: to}}, nil
+}
+
+func TestScheduler_Refresh(t *testing.T) {
+	repo := &mockRepo{}
+	for _, e := range []Entry{
+		{Source: "tefas", Symbol: "YAC"},
+		{Source: "yahoo", Symbol: "AAPL"},
+		{Source: "tefas", Symbol: "AFT"},
+		{Source: "tefas", Symbol: "TTE"},
+	} {
+		_, _ = repo.Add(context.Background(), &e)
+	}
+	refresher := &mockRefresher{fail: "AFT"}
+	now := time.Date(2024, 1, 5, 17, 0, 0, 0, time.UTC)
+
+	s := NewScheduler(repo, refresher, nil, WithLookback(7))
+	s.now = func() time.Time { return now }
+
+	jobs, err := s.Refresh(context.Background(), "tefas")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if len(refresher.calls) != 3 {
+		t.Fatalf("expected 3 tefas symbols refreshed, got %+v", refresher.calls)
+	}
+	today := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	for _, c := range refresher.calls {
+		if c.source != "tefas" || !c.to.Equal(today) || !c.from.Equal(today.AddDate(0, 0, -7)) {
+			t.Errorf("unexpected refresh %+v", c)
+		}
+	}
+	if len(jobs) != 2 {
+		t.Errorf("expected 2 jobs, the failed symbol skipped, got %d", len(jobs))
+	}
+	if len(repo.refreshed) != 2 || !repo.refreshed[1].Equal(now) || !repo.refreshed[4].Equal(now) {
+		t.Errorf("expected YAC and TTE marked refreshed, got %v", repo.refreshed)
+	}
+}
@@ -0,0 +1,47 @@
+package watchlist
+
+import (
+	"context"
+
+	"github.com/ahmethakanbesel/finance-api/internal/apperror"
+)
+
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Add tracks a symbol. Adding a tracked symbol returns its entry with
+// created false.
+func (s *Service) Add(ctx context.Context, req AddRequest) (e *Entry, created bool, err error) {
+	if err := req.Validate(); err != nil {
+		return nil, false, err
+	}
+	e = &Entry{Source: req.Source, Symbol: req.Symbol}
+	created, err = s.repo.Add(ctx, e)
+	if err != nil {
+		return nil, false, err
+	}
+	return e, created, nil
+}
+
+func (s *Service) List(ctx context.Context) ([]Entry, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *Service) Remove(ctx context.Context, req RemoveRequest) error {
+	if err := req.Validate(); err != nil {
+		return err
+	}
+	ok, err := s.repo.Remove(ctx, req.Source, req.Symbol)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return apperror.New(apperror.NotFound, "symbol is not on the watchlist")
+	}
+	return nil
+}
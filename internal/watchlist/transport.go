@@ -0,0 +1,30 @@
+package watchlist
+
+import "github.com/ahmethakanbesel/finance-api/internal/apperror"
+
+type AddRequest struct {
+	Source string
+	Symbol string
+}
+
+func (r AddRequest) Validate() *apperror.AppError {
+	if r.Source == "" {
+		return apperror.New(apperror.BadRequest, "source is required")
+	}
+	if len(r.Symbol) < 2 {
+		return apperror.New(apperror.BadRequest, "symbol must be at least 2 characters")
+	}
+	return nil
+}
+
+type RemoveRequest struct {
+	Source string
+	Symbol string
+}
+
+func (r RemoveRequest) Validate() *apperror.AppError {
+	if r.Source == "" || r.Symbol == "" {
+		return apperror.New(apperror.BadRequest, "source and symbol are required")
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+// Package watchlist keeps the symbols whose data is refreshed on a schedule
+// rather than only when a client asks for it.
+package watchlist
+
+import "time"
+
+// Entry is a tracked symbol of a data source.
+type Entry struct {
+	ID          int64      `json:"id"`
+	Source      string     `json:"source"`
+	Symbol      string     `json:"symbol"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RefreshedAt *time.Time `json:"refreshedAt,omitempty"` // last scheduled refresh
+}
@@ -4,23 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/ahmethakanbesel/finance-api/internal/calendar"
+	"github.com/ahmethakanbesel/finance-api/internal/fund"
 	"github.com/ahmethakanbesel/finance-api/internal/job"
 	"github.com/ahmethakanbesel/finance-api/internal/platform/sqlite"
 	"github.com/ahmethakanbesel/finance-api/internal/price"
+	"github.com/ahmethakanbesel/finance-api/internal/quality"
 	"github.com/ahmethakanbesel/finance-api/internal/rate"
+	fundrepo "github.com/ahmethakanbesel/finance-api/internal/repository/fund"
 	jobrepo "github.com/ahmethakanbesel/finance-api/internal/repository/job"
 	pricerepo "github.com/ahmethakanbesel/finance-api/internal/repository/price"
+	qualityrepo "github.com/ahmethakanbesel/finance-api/internal/repository/quality"
 	raterepo "github.com/ahmethakanbesel/finance-api/internal/repository/rate"
+	watchlistrepo "github.com/ahmethakanbesel/finance-api/internal/repository/watchlist"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/isyatirim"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/tefas"
 	"github.com/ahmethakanbesel/finance-api/internal/scraper/yahoo"
 	"github.com/ahmethakanbesel/finance-api/internal/server"
+	"github.com/ahmethakanbesel/finance-api/internal/watchlist"
 )
 
 func setupE2E(t *testing.T, tefasURL, isyatirimURL string) *httptest.Server {
@@ -35,14 +45,18 @@ func setupE2E(t *testing.T, tefasURL, isyatirimURL string) *httptest.Server {
 	priceRepo := pricerepo.NewRepository(db.DB)
 	jobRepo := jobrepo.NewRepository(db.DB)
 	rateRepo := raterepo.NewRepository(db.DB)
+	fundRepo := fundrepo.NewRepository(db.DB)
+	qualityRepo := qualityrepo.NewRepository(db.DB)
 
-	registry := scraper.NewRegistry()
-	registry.Register(tefas.New(
+	tefasScraper := tefas.New(
 		tefas.WithWorkers(1),
 		tefas.WithHistoryEndpoint(tefasURL),
+		tefas.WithAllocationEndpoint(tefasURL),
 		tefas.WithBaseURL(tefasURL),
 		tefas.WithReferer(tefasURL),
-	))
+	)
+	registry := scraper.NewRegistry()
+	registry.Register(tefasScraper)
 	registry.Register(yahoo.New(
 		yahoo.WithWorkers(1),
 	))
@@ -60,12 +74,32 @@ func setupE2E(t *testing.T, tefasURL, isyatirimURL string) *httptest.Server {
 
 	rateSvc := rate.NewService(rateRepo)
 	jobSvc := job.NewService(jobRepo)
-	priceSvc := price.NewService(priceRepo, jobRepo, registry, rateSvc)
+	checker := quality.NewChecker(quality.Rules{
+		RejectInvalid: true,
+		RejectFuture:  true,
+		MaxMove:       map[quality.Class]float64{quality.ClassFund: 0.2},
+		StaleRun:      10,
+	})
+	priceSvc := price.NewService(priceRepo, jobRepo, registry, rateSvc,
+		price.WithFundRepository(fundRepo),
+		price.WithQuality(checker, qualityRepo),
+	)
+	fundSvc := fund.NewService(fundRepo, jobRepo, priceSvc, tefasScraper)
+	watchlistSvc := watchlist.NewService(watchlistrepo.NewRepository(db.DB))
+	qualitySvc := quality.NewService(qualityRepo, checker)
 
 	// Start worker pool for background job processing
+	router := job.NewRouter(jobRepo)
+	router.Handle(job.KindPrices, priceSvc)
+	router.Handle(job.KindRefresh, priceSvc)
+	router.Handle(job.KindAllocation, fundSvc)
 	poolCtx, poolCancel := context.WithCancel(context.Background())
-	pool := job.NewWorkerPool(jobRepo, priceSvc, 2)
+	pool := job.NewWorkerPool(jobRepo, router, 2)
 	priceSvc.SetNotify(pool.Notify)
+	fundSvc.SetNotify(pool.Notify)
+	jobSvc.SetNotify(pool.Notify)
+	jobSvc.SetCancel(pool.Cancel)
+	jobSvc.SetRunning(pool.Running)
 	poolDone := make(chan struct{})
 	go func() {
 		pool.Run(poolCtx)
@@ -77,7 +111,7 @@ func setupE2E(t *testing.T, tefasURL, isyatirimURL string) *httptest.Server {
 		<-poolDone
 	})
 
-	return httptest.NewServer(server.NewHandler(priceSvc, jobSvc))
+	return httptest.NewServer(server.NewHandler(priceSvc, jobSvc, fundSvc, rateSvc, watchlistSvc, qualitySvc))
 }
 
 // waitForJob polls the job endpoint until the job reaches a terminal status.
@@ -106,7 +140,7 @@ func waitForJob(t *testing.T, baseURL string, jobID int64) *job.Job {
 			t.Fatalf("decode: %v", err)
 		}
 
-		if result.Data.Status == job.StatusCompleted || result.Data.Status == job.StatusFailed {
+		if !result.Data.Status.Active() {
 			return &result.Data
 		}
 
@@ -144,6 +178,40 @@ func TestE2E_ListSources(t *testing.T) {
 	}
 }
 
+func TestE2E_CalendarDays(t *testing.T) {
+	ts := setupE2E(t, "", "")
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/v1/calendars/tefas/days?startDate=2024-04-08&endDate=2024-04-15") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var result struct {
+		Data calendar.GetDaysResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// Ramazan Bayramı: the eve on 9 April and 10-12 April are closed.
+	if len(result.Data.Days) != 2 {
+		t.Errorf("expected 2 trading days, got %v", result.Data.Days)
+	}
+	if len(result.Data.Holidays) != 4 {
+		t.Errorf("expected 4 holidays, got %v", result.Data.Holidays)
+	}
+
+	resp, _ = http.Get(ts.URL + "/api/v1/calendars/lse/days?startDate=2024-04-08") //nolint:gosec // test URL
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown calendar, got %d", resp.StatusCode)
+	}
+}
+
 func TestE2E_GetPrices_TEFAS(t *testing.T) {
 	// Mock TEFAS server
 	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -498,3 +566,576 @@ func TestE2E_GetPrices_Isyatirim(t *testing.T) {
 		}
 	}
 }
+
+func TestE2E_GetPrices_ReportedZeroVolume(t *testing.T) {
+	mockIsyatirim := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"data": [][]any{
+				{1735772400000.0, 36.65, 36.5, 36.9, 36.4, 0.0}, // a day without trades
+				{1735858800000.0, 36.74},                        // close only
+			},
+		})
+	}))
+	defer mockIsyatirim.Close()
+
+	ts := setupE2E(t, "", mockIsyatirim.URL)
+	defer ts.Close()
+
+	url := fmt.Sprintf("%s/api/v1/prices/ALTINS1?source=isyatirim&startDate=2025-01-01&endDate=2025-01-02", ts.URL)
+	get := func(query string) []byte {
+		t.Helper()
+		resp, err := http.Get(url + query) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return body
+	}
+
+	var first struct {
+		Data struct {
+			Job *job.Job `json:"job"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(get(""), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first.Data.Job == nil {
+		t.Fatal("expected job in first request")
+	}
+	waitForJob(t, ts.URL, first.Data.Job.ID)
+
+	for _, query := range []string{"", "&precision=exact"} {
+		var result struct {
+			Data struct {
+				Prices []map[string]any `json:"prices"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(get(query), &result); err != nil {
+			t.Fatal(err)
+		}
+		prices := result.Data.Prices
+		if len(prices) != 2 {
+			t.Fatalf("%q: expected 2 prices, got %+v", query, prices)
+		}
+		if v, ok := prices[0]["volume"]; !ok || v != 0.0 {
+			t.Errorf("%q: expected a reported zero volume, got %+v", query, prices[0])
+		}
+		for _, field := range []string{"open", "high", "low", "volume"} {
+			if _, ok := prices[1][field]; ok {
+				t.Errorf("%q: expected no %s for a close-only bar, got %+v", query, field, prices[1])
+			}
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(get("&format=csv"))), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %q", lines)
+	}
+	// Open, High, Low, Close, AdjClose and Volume.
+	for i, want := range []string{"36.5,36.9,36.4,36.65,,0", ",,,36.74,,"} {
+		if got := strings.Join(strings.Split(lines[i+1], ",")[4:10], ","); got != want {
+			t.Errorf("row %d: got %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestE2E_FundStats(t *testing.T) {
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 2,
+			"data": []map[string]any{
+				{"TARIH": "1704067200000", "FONKODU": "YAC", "FONUNVAN": "YAPI KREDI FONU", "FIYAT": 1.23,
+					"TEDPAYSAYISI": 1000000.0, "KISISAYISI": 250.0, "PORTFOYBUYUKLUK": 1230000.0},
+				{"TARIH": "1704153600000", "FONKODU": "YAC", "FONUNVAN": "YAPI KREDI FONU", "FIYAT": 1.24,
+					"TEDPAYSAYISI": 1100000.0, "KISISAYISI": 260.0, "PORTFOYBUYUKLUK": 1364000.0},
+			},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	url := fmt.Sprintf("%s/api/v1/funds/YAC/stats?startDate=2024-01-01&endDate=2024-01-31", ts.URL)
+
+	type statsResult struct {
+		Data fund.GetStatsResponse `json:"data"`
+	}
+
+	// First request: queues a TEFAS job
+	resp, err := http.Get(url) //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var first statsResult
+	err = json.NewDecoder(resp.Body).Decode(&first)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Data.Job == nil {
+		t.Fatal("expected job in first request")
+	}
+	waitForJob(t, ts.URL, first.Data.Job.ID)
+
+	// Second request: stats are stored
+	resp, err = http.Get(url) //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var second statsResult
+	if err := json.NewDecoder(resp.Body).Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if second.Data.Name != "YAPI KREDI FONU" {
+		t.Errorf("expected fund name, got %q", second.Data.Name)
+	}
+	if len(second.Data.Stats) != 2 {
+		t.Fatalf("expected 2 stats, got %d", len(second.Data.Stats))
+	}
+	if second.Data.Stats[1].Investors != 260 || second.Data.Stats[1].TotalValue != 1364000 {
+		t.Errorf("unexpected stats: %+v", second.Data.Stats[1])
+	}
+}
+
+func TestE2E_FundAllocation(t *testing.T) {
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 2,
+			"data": []map[string]any{
+				{"TARIH": "1704067200000", "FONKODU": "YAC", "HS": 90.0, "R": 10.0},
+				{"TARIH": "1704153600000", "FONKODU": "YAC", "HS": 85.0, "R": 10.0, "TR": 5.0},
+			},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	url := fmt.Sprintf("%s/api/v1/funds/YAC/allocation?startDate=2024-01-01&endDate=2024-01-31", ts.URL)
+
+	type allocResult struct {
+		Data fund.GetAllocationResponse `json:"data"`
+	}
+
+	// First request: queues an allocation job
+	resp, err := http.Get(url) //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var first allocResult
+	err = json.NewDecoder(resp.Body).Decode(&first)
+	_ = resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Data.Job == nil {
+		t.Fatal("expected job in first request")
+	}
+	if first.Data.Job.Kind != job.KindAllocation {
+		t.Errorf("expected allocation job, got %q", first.Data.Job.Kind)
+	}
+	if j := waitForJob(t, ts.URL, first.Data.Job.ID); j.Status != job.StatusCompleted {
+		t.Fatalf("expected completed job, got %s: %s", j.Status, j.Error)
+	}
+
+	// Second request: allocations are stored
+	resp, err = http.Get(url) //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var second allocResult
+	if err := json.NewDecoder(resp.Body).Decode(&second); err != nil {
+		t.Fatal(err)
+	}
+	if len(second.Data.Allocations) != 2 {
+		t.Fatalf("expected 2 allocations, got %d", len(second.Data.Allocations))
+	}
+	if w := second.Data.Allocations[1].Weights; w["HS"] != 85 || w["TR"] != 5 {
+		t.Errorf("unexpected weights: %v", w)
+	}
+}
+
+func TestE2E_GetPricesBatch(t *testing.T) {
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 2,
+			"data": []map[string]any{
+				{"TARIH": "1704067200000", "FONKODU": r.Form.Get("fonkod"), "FIYAT": 1.23},
+				{"TARIH": "1704153600000", "FONKODU": r.Form.Get("fonkod"), "FIYAT": 1.24},
+			},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	body := `{"items":[{"source":"tefas","symbol":"YAC"},{"source":"tefas","symbol":"AFT"}],
+		"startDate":"2024-01-01","endDate":"2024-01-31","currency":"TRY"}`
+
+	type batchResult struct {
+		Message string                    `json:"message"`
+		Data    price.BatchPricesResponse `json:"data"`
+	}
+	post := func() batchResult {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/v1/prices:batch", "application/json", strings.NewReader(body)) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200, got %d", resp.StatusCode)
+		}
+		var result batchResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// First request queues a job per symbol
+	first := post()
+	for _, sym := range []string{"YAC", "AFT"} {
+		sp := first.Data.Symbols[sym]
+		if sp == nil || sp.Job == nil {
+			t.Fatalf("expected a job for %s, got %+v", sym, sp)
+		}
+		if j := waitForJob(t, ts.URL, sp.Job.ID); j.Status != job.StatusCompleted {
+			t.Errorf("%s: expected completed, got %s (error: %s)", sym, j.Status, j.Error)
+		}
+	}
+
+	// Second request returns the stored prices
+	second := post()
+	for _, sym := range []string{"YAC", "AFT"} {
+		sp := second.Data.Symbols[sym]
+		if sp == nil || len(sp.Prices) != 2 {
+			t.Errorf("%s: expected 2 cached prices, got %+v", sym, sp)
+			continue
+		}
+		if sp.Prices[0].Symbol != sym {
+			t.Errorf("expected prices of %s, got %s", sym, sp.Prices[0].Symbol)
+		}
+	}
+
+	// Wide layout: one row per date, one column per symbol in request order
+	wide := strings.Replace(body, `"currency":"TRY"`, `"currency":"TRY","layout":"wide","format":"csv"`, 1)
+	resp, err := http.Post(ts.URL+"/api/v1/prices:batch", "application/json", strings.NewReader(wide)) //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	csv, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	want := "Date,YAC,AFT\n2024-01-01,1.23,1.23\n2024-01-02,1.24,1.24\n"
+	if string(csv) != want {
+		t.Errorf("unexpected wide CSV:\n%s\nwant:\n%s", csv, want)
+	}
+
+	// Invalid items are rejected as a whole
+	resp, err = http.Post(ts.URL+"/api/v1/prices:batch", "application/json", //nolint:gosec // test URL
+		strings.NewReader(`{"items":[{"source":"tefas","symbol":"YAC"},{"symbol":"AFT"}],"startDate":"2024-01-01"}`))
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestE2E_JobLifecycle(t *testing.T) {
+	// TEFAS hangs until released or the request is cancelled
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 1,
+			"data":         []map[string]any{{"TARIH": "1704067200000", "FONKODU": "YAC", "FIYAT": 1.23}},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	post := func(path, body string) (int, *job.Job) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body)) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var result struct {
+			Data *job.Job `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Data
+	}
+
+	body := `{"source":"tefas","symbol":"YAC","startDate":"2024-01-01","endDate":"2024-01-31"}`
+	status, j := post("/api/v1/jobs", body)
+	if status != http.StatusCreated || j == nil || j.Kind != job.KindPrices {
+		t.Fatalf("expected 201 with a prices job, got %d %+v", status, j)
+	}
+
+	// Submitting the same range again returns the active job
+	if status, dup := post("/api/v1/jobs", body); status != http.StatusOK || dup.ID != j.ID {
+		t.Errorf("expected 200 with job %d, got %d %+v", j.ID, status, dup)
+	}
+
+	// Cancel while the scrape is in flight
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for scrape to start")
+	}
+	if status, _ := post(fmt.Sprintf("/api/v1/jobs/%d/cancel", j.ID), ""); status != http.StatusOK {
+		t.Fatalf("cancel: expected 200, got %d", status)
+	}
+	if got := waitForJob(t, ts.URL, j.ID); got.Status != job.StatusCancelled {
+		t.Fatalf("expected cancelled, got %s (error: %s)", got.Status, got.Error)
+	}
+	if status, _ := post(fmt.Sprintf("/api/v1/jobs/%d/cancel", j.ID), ""); status != http.StatusConflict {
+		t.Errorf("cancelling a cancelled job: expected 409, got %d", status)
+	}
+
+	// Retry runs it to completion
+	close(release)
+	status, retried := post(fmt.Sprintf("/api/v1/jobs/%d/retry", j.ID), "")
+	if status != http.StatusOK || retried.Status != job.StatusPending {
+		t.Fatalf("retry: expected 200 with a pending job, got %d %+v", status, retried)
+	}
+	if got := waitForJob(t, ts.URL, j.ID); got.Status != job.StatusCompleted {
+		t.Errorf("expected completed, got %s (error: %s)", got.Status, got.Error)
+	}
+	if status, _ := post(fmt.Sprintf("/api/v1/jobs/%d/retry", j.ID), ""); status != http.StatusConflict {
+		t.Errorf("retrying a completed job: expected 409, got %d", status)
+	}
+
+	if status, _ := post("/api/v1/jobs", `{"source":"nasdaq","symbol":"AAPL","startDate":"2024-01-01"}`); status != http.StatusBadRequest {
+		t.Errorf("unknown source: expected 400, got %d", status)
+	}
+}
+
+func TestE2E_PriceRevisions(t *testing.T) {
+	// TEFAS corrects the price after the first fetch
+	var fetches atomic.Int32
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		closePrice := 1.23
+		if fetches.Add(1) > 1 {
+			closePrice = 1.25
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 1,
+			"data":         []map[string]any{{"TARIH": "1704153600000", "FONKODU": "YAC", "FIYAT": closePrice}},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	for _, kind := range []string{"prices", "refresh"} {
+		body := fmt.Sprintf(`{"kind":%q,"source":"tefas","symbol":"YAC","startDate":"2024-01-02","endDate":"2024-01-02"}`, kind)
+		resp, err := http.Post(ts.URL+"/api/v1/jobs", "application/json", strings.NewReader(body)) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		var created struct {
+			Data *job.Job `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&created)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d", kind, resp.StatusCode)
+		}
+		if got := waitForJob(t, ts.URL, created.Data.ID); got.Status != job.StatusCompleted || got.RecordsCount != 1 {
+			t.Fatalf("%s: expected completed with 1 record, got %s %d (error: %s)", kind, got.Status, got.RecordsCount, got.Error)
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/prices/YAC/revisions?source=tefas&startDate=2024-01-01&endDate=2024-01-31") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var result struct {
+		Data price.GetRevisionsResponse `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	revs := result.Data.Revisions
+	if len(revs) != 1 || revs[0].Field != "closePrice" || revs[0].OldValue.String() != "1.23" || revs[0].NewValue.String() != "1.25" || revs[0].JobID != 2 {
+		t.Errorf("unexpected revisions %+v", revs)
+	}
+
+	resp2, err := http.Get(ts.URL + "/api/v1/prices/YAC/revisions?startDate=2024-01-01") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	_ = resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing source: expected 400, got %d", resp2.StatusCode)
+	}
+}
+
+func TestE2E_Quality(t *testing.T) {
+	// Jan 3 is a tenfold spike and Jan 4 a zero; both are quarantined.
+	mockTefas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"recordsTotal": 4,
+			"data": []map[string]any{
+				{"TARIH": "1704153600000", "FONKODU": "YAC", "FIYAT": 1.23},
+				{"TARIH": "1704240000000", "FONKODU": "YAC", "FIYAT": 12.3},
+				{"TARIH": "1704326400000", "FONKODU": "YAC", "FIYAT": 0},
+				{"TARIH": "1704412800000", "FONKODU": "YAC", "FIYAT": 1.24},
+			},
+		})
+	}))
+	defer mockTefas.Close()
+
+	ts := setupE2E(t, mockTefas.URL, "")
+	defer ts.Close()
+
+	type pricesResult struct {
+		Data price.GetPricesResponse `json:"data"`
+	}
+	getPrices := func() pricesResult {
+		t.Helper()
+		resp, err := http.Get(ts.URL + "/api/v1/prices/YAC?source=tefas&startDate=2024-01-02&endDate=2024-01-05") //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var result pricesResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return result
+	}
+
+	first := getPrices()
+	if first.Data.Job == nil {
+		t.Fatal("expected a job")
+	}
+	if got := waitForJob(t, ts.URL, first.Data.Job.ID); got.Status != job.StatusCompleted || got.RecordsCount != 2 {
+		t.Fatalf("expected completed with 2 records, got %s %d (error: %s)", got.Status, got.RecordsCount, got.Error)
+	}
+
+	second := getPrices()
+	if second.Data.Job != nil || len(second.Data.Prices) != 2 || len(second.Data.Quarantined) != 2 {
+		t.Fatalf("expected 2 prices, 2 quarantined and no job, got %+v", second.Data)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/quality/tefas/YAC") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var report struct {
+		Data quality.Report `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	r := report.Data
+	if r.Class != quality.ClassFund || r.Rules.MaxMove != 0.2 || len(r.Points) != 2 ||
+		r.Counts[quality.RuleMove] != 1 || r.Counts[quality.RuleInvalid] != 1 {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	resp2, err := http.Get(ts.URL + "/api/v1/quality/nasdaq/AAPL") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	_ = resp2.Body.Close()
+	if resp2.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown source: expected 400, got %d", resp2.StatusCode)
+	}
+}
+
+func TestE2E_Watchlist(t *testing.T) {
+	ts := setupE2E(t, "", "")
+	defer ts.Close()
+
+	add := func(body string) (int, *watchlist.Entry) {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/v1/watchlist", "application/json", strings.NewReader(body)) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		var result struct {
+			Data *watchlist.Entry `json:"data"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result.Data
+	}
+	remove := func(path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL+path, nil)
+		resp, err := http.DefaultClient.Do(req) //nolint:gosec // test URL
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	status, e := add(`{"source":"tefas","symbol":"aea","fundType":"EMK"}`)
+	if status != http.StatusCreated || e == nil || e.Symbol != "EMK:AEA" {
+		t.Fatalf("add: expected 201 for EMK:AEA, got %d %+v", status, e)
+	}
+	if status, again := add(`{"source":"tefas","symbol":"EMK:AEA"}`); status != http.StatusOK || again.ID != e.ID {
+		t.Errorf("add again: expected 200 with id %d, got %d %+v", e.ID, status, again)
+	}
+	if status, _ := add(`{"source":"nasdaq","symbol":"AAPL"}`); status != http.StatusBadRequest {
+		t.Errorf("unknown source: expected 400, got %d", status)
+	}
+
+	resp, err := http.Get(ts.URL + "/api/v1/watchlist") //nolint:gosec // test URL
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	var list struct {
+		Data []watchlist.Entry `json:"data"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&list)
+	_ = resp.Body.Close()
+	if len(list.Data) != 1 {
+		t.Fatalf("expected 1 entry, got %+v", list.Data)
+	}
+
+	if status := remove("/api/v1/watchlist/tefas/EMK:AEA"); status != http.StatusNoContent {
+		t.Errorf("remove: expected 204, got %d", status)
+	}
+	if status := remove("/api/v1/watchlist/tefas/EMK:AEA"); status != http.StatusNotFound {
+		t.Errorf("remove again: expected 404, got %d", status)
+	}
+}